@@ -0,0 +1,229 @@
+package arc53
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/kylebeee/arc53-watcher-go/errors"
+)
+
+// multicodec codes for the content types we can verify
+const (
+	CodecRaw   uint64 = 0x55
+	CodecDagPB uint64 = 0x70
+)
+
+// multihash function codes
+const (
+	hashIdentity uint64 = 0x00
+	hashSha256   uint64 = 0x12
+)
+
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+var base32Lower = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// CID is a decoded IPFS content identifier
+type CID struct {
+	Version  uint64
+	Codec    uint64
+	HashCode uint64
+	Digest   []byte
+	raw      string
+}
+
+func (c *CID) String() string {
+	return c.raw
+}
+
+// ParseCID decodes a CIDv0 (base58btc Qm...) or a multibase encoded CIDv1
+func ParseCID(s string) (*CID, error) {
+	const op errors.Op = "ParseCID"
+
+	if len(s) == 46 && strings.HasPrefix(s, "Qm") {
+		mh, err := decodeBase58(s)
+		if err != nil {
+			return nil, errors.E(pkg, op, errors.Type, err)
+		}
+
+		c := &CID{Version: 0, Codec: CodecDagPB, raw: s}
+		rest, err := c.readMultihash(mh)
+		if err != nil || len(rest) != 0 {
+			return nil, errors.E(pkg, op, errors.Type, fmt.Errorf("invalid CIDv0: %s", s))
+		}
+		return c, nil
+	}
+
+	if len(s) < 2 {
+		return nil, errors.E(pkg, op, errors.Type, fmt.Errorf("invalid CID: %s", s))
+	}
+
+	var (
+		data []byte
+		err  error
+	)
+	switch s[0] {
+	case 'b', 'B':
+		data, err = base32Lower.DecodeString(strings.ToUpper(s[1:]))
+	case 'z':
+		data, err = decodeBase58(s[1:])
+	case 'f', 'F':
+		data, err = hex.DecodeString(s[1:])
+	default:
+		err = fmt.Errorf("unsupported multibase prefix %q", s[0])
+	}
+	if err != nil {
+		return nil, errors.E(pkg, op, errors.Type, err)
+	}
+
+	c, err := parseCIDBytes(data)
+	if err != nil {
+		return nil, errors.E(pkg, op, errors.Type, err)
+	}
+	c.raw = s
+
+	return c, nil
+}
+
+// parseCIDBytes decodes the binary form of a CID as found in dag-pb links,
+// CIDv0 links are a bare sha2-256 multihash
+func parseCIDBytes(data []byte) (*CID, error) {
+	if len(data) == 34 && data[0] == byte(hashSha256) && data[1] == 32 {
+		c := &CID{Version: 0, Codec: CodecDagPB}
+		_, err := c.readMultihash(data)
+		if err != nil {
+			return nil, err
+		}
+		c.raw = encodeBase58(data)
+		return c, nil
+	}
+
+	version, n := binary.Uvarint(data)
+	if n <= 0 || version != 1 {
+		return nil, fmt.Errorf("unsupported CID version")
+	}
+	data = data[n:]
+
+	codec, n := binary.Uvarint(data)
+	if n <= 0 {
+		return nil, fmt.Errorf("invalid CID codec")
+	}
+	data = data[n:]
+
+	c := &CID{Version: 1, Codec: codec}
+	rest, err := c.readMultihash(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, fmt.Errorf("trailing bytes after CID multihash")
+	}
+
+	if c.raw == "" {
+		var buf []byte
+		buf = binary.AppendUvarint(buf, c.Version)
+		buf = binary.AppendUvarint(buf, c.Codec)
+		buf = binary.AppendUvarint(buf, c.HashCode)
+		buf = binary.AppendUvarint(buf, uint64(len(c.Digest)))
+		buf = append(buf, c.Digest...)
+		c.raw = "b" + strings.ToLower(base32Lower.EncodeToString(buf))
+	}
+
+	return c, nil
+}
+
+func (c *CID) readMultihash(data []byte) ([]byte, error) {
+	code, n := binary.Uvarint(data)
+	if n <= 0 {
+		return nil, fmt.Errorf("invalid multihash code")
+	}
+	data = data[n:]
+
+	length, n := binary.Uvarint(data)
+	if n <= 0 || uint64(len(data)-n) < length {
+		return nil, fmt.Errorf("invalid multihash length")
+	}
+	data = data[n:]
+
+	c.HashCode = code
+	c.Digest = data[:length]
+
+	return data[length:], nil
+}
+
+// Verify checks that block hashes to the digest of the CID
+func (c *CID) Verify(block []byte) error {
+	const op errors.Op = "CID.Verify"
+
+	var digest []byte
+	switch c.HashCode {
+	case hashSha256:
+		sum := sha256.Sum256(block)
+		digest = sum[:]
+	case hashIdentity:
+		digest = block
+	default:
+		return errors.E(pkg, op, errors.Integrity, fmt.Errorf("unsupported multihash function 0x%x for %s", c.HashCode, c))
+	}
+
+	if !bytes.Equal(digest, c.Digest) {
+		return errors.E(pkg, op, errors.Integrity, fmt.Errorf("content does not match %s", c))
+	}
+
+	return nil
+}
+
+func decodeBase58(s string) ([]byte, error) {
+	result := big.NewInt(0)
+	radix := big.NewInt(58)
+
+	for _, r := range s {
+		idx := strings.IndexRune(base58Alphabet, r)
+		if idx < 0 {
+			return nil, fmt.Errorf("invalid base58 character %q", r)
+		}
+		result.Mul(result, radix)
+		result.Add(result, big.NewInt(int64(idx)))
+	}
+
+	decoded := result.Bytes()
+
+	// leading '1's encode leading zero bytes
+	zeros := 0
+	for zeros < len(s) && s[zeros] == '1' {
+		zeros++
+	}
+
+	return append(make([]byte, zeros), decoded...), nil
+}
+
+func encodeBase58(data []byte) string {
+	num := new(big.Int).SetBytes(data)
+	radix := big.NewInt(58)
+	mod := new(big.Int)
+
+	var out []byte
+	for num.Sign() > 0 {
+		num.DivMod(num, radix, mod)
+		out = append(out, base58Alphabet[mod.Int64()])
+	}
+
+	for _, b := range data {
+		if b != 0 {
+			break
+		}
+		out = append(out, '1')
+	}
+
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+
+	return string(out)
+}
@@ -0,0 +1,226 @@
+package arc53
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"net/http"
+	"testing"
+
+	"github.com/kylebeee/arc53-watcher-go/errors"
+)
+
+// helloWorldPB is the dag-pb block `ipfs add` makes for "hello world\n", QmT78zSuBmuS4z925WZfrqQ1qHaJ56DQaTfyMUF7F8ff5o
+var helloWorldPB = mustHex("0a120802120c68656c6c6f20776f726c640a180c")
+
+func mustHex(s string) []byte {
+	data, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
+
+func TestParseCID(t *testing.T) {
+	tests := []struct {
+		name    string
+		cid     string
+		version uint64
+		codec   uint64
+		digest  string
+		block   []byte
+	}{
+		{
+			name:    "CIDv0",
+			cid:     "QmT78zSuBmuS4z925WZfrqQ1qHaJ56DQaTfyMUF7F8ff5o",
+			version: 0,
+			codec:   CodecDagPB,
+			digest:  "46d44814b9c5af141c3aaab7c05dc5e844ead5f91f12858b021eba45768b4c0e",
+			block:   helloWorldPB,
+		},
+		{
+			name:    "CIDv1 raw",
+			cid:     "bafkreifzjut3te2nhyekklss27nh3k72ysco7y32koao5eei66wof36n5e",
+			version: 1,
+			codec:   CodecRaw,
+			digest:  "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9",
+			block:   []byte("hello world"),
+		},
+		{
+			name:    "CIDv1 dag-pb",
+			cid:     "bafybeicg2rebjoofv4kbyovkw7af3rpiitvnl6i7ckcywaq6xjcxnc2mby",
+			version: 1,
+			codec:   CodecDagPB,
+			digest:  "46d44814b9c5af141c3aaab7c05dc5e844ead5f91f12858b021eba45768b4c0e",
+			block:   helloWorldPB,
+		},
+		{
+			name:    "CIDv1 raw in base16",
+			cid:     "f01551220b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9",
+			version: 1,
+			codec:   CodecRaw,
+			digest:  "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9",
+			block:   []byte("hello world"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := ParseCID(tt.cid)
+			if err != nil {
+				t.Fatalf("ParseCID: %v", err)
+			}
+
+			if c.Version != tt.version || c.Codec != tt.codec || c.HashCode != hashSha256 {
+				t.Fatalf("ParseCID = v%d codec 0x%x hash 0x%x, want v%d codec 0x%x hash 0x%x", c.Version, c.Codec, c.HashCode, tt.version, tt.codec, hashSha256)
+			}
+			if hex.EncodeToString(c.Digest) != tt.digest {
+				t.Fatalf("digest = %x, want %s", c.Digest, tt.digest)
+			}
+			if c.String() != tt.cid {
+				t.Fatalf("String = %s, want %s", c, tt.cid)
+			}
+
+			err = c.Verify(tt.block)
+			if err != nil {
+				t.Fatalf("Verify: %v", err)
+			}
+		})
+	}
+}
+
+func TestParseCIDInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		cid  string
+	}{
+		{name: "empty", cid: ""},
+		{name: "CIDv0 with a bad character", cid: "QmT78zSuBmuS4z925WZfrqQ1qHaJ56DQaTfyMUF7F8ff5l"},
+		{name: "unknown multibase", cid: "mAXASIA"},
+		{name: "truncated digest", cid: "f01551220b94d27b9934d3e08a52e"},
+		{name: "trailing bytes", cid: "f01551220b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde900"},
+		{name: "CIDv2", cid: "f02551220b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseCID(tt.cid)
+			if err == nil {
+				t.Fatalf("ParseCID(%q) succeeded", tt.cid)
+			}
+		})
+	}
+}
+
+func TestCIDVerifyMismatch(t *testing.T) {
+	tests := []struct {
+		name  string
+		cid   string
+		block []byte
+	}{
+		{name: "other content", cid: "bafkreifzjut3te2nhyekklss27nh3k72ysco7y32koao5eei66wof36n5e", block: []byte("hello world\n")},
+		{name: "truncated raw block", cid: "bafkreifzjut3te2nhyekklss27nh3k72ysco7y32koao5eei66wof36n5e", block: []byte("hello worl")},
+		{name: "truncated dag-pb block", cid: "QmT78zSuBmuS4z925WZfrqQ1qHaJ56DQaTfyMUF7F8ff5o", block: helloWorldPB[:len(helloWorldPB)-4]},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := ParseCID(tt.cid)
+			if err != nil {
+				t.Fatalf("ParseCID: %v", err)
+			}
+
+			err = c.Verify(tt.block)
+			if err == nil || !errors.HasKind(err, errors.Integrity) {
+				t.Fatalf("Verify error %v, want an integrity error", err)
+			}
+		})
+	}
+}
+
+func TestDecodePBNode(t *testing.T) {
+	node, err := decodePBNode(helloWorldPB)
+	if err != nil {
+		t.Fatalf("decodePBNode: %v", err)
+	}
+	if len(node.Links) != 0 {
+		t.Fatalf("decodePBNode found %d links, want none", len(node.Links))
+	}
+
+	fs, err := decodeUnixFS(node.Data)
+	if err != nil {
+		t.Fatalf("decodeUnixFS: %v", err)
+	}
+	if fs.Type != unixfsFile || string(fs.Data) != "hello world\n" {
+		t.Fatalf("decodeUnixFS = type %d %q, want a file holding %q", fs.Type, fs.Data, "hello world\n")
+	}
+
+	// cutting the block inside the data field leaves a length longer than what's left
+	_, err = decodePBNode(helloWorldPB[:10])
+	if err == nil {
+		t.Fatalf("decodePBNode of a truncated block succeeded")
+	}
+}
+
+// pbBytes encodes a protobuf length delimited field
+func pbBytes(num uint64, data []byte) []byte {
+	out := []byte{byte(num<<3 | 2), byte(len(data))}
+	return append(out, data...)
+}
+
+func TestGatewayFetcherResolvesDagPB(t *testing.T) {
+	metadata := []byte(`{"version":"0.0.1"}`)
+	leaf, err := ParseCID(rawCID(t, metadata))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a directory holding the metadata as a raw leaf, links are written before the data
+	leafBytes := mustHex("01551220")
+	leafBytes = append(leafBytes, leaf.Digest...)
+	link := append(pbBytes(1, leafBytes), pbBytes(2, []byte("metadata.json"))...)
+	dir := append(pbBytes(2, link), pbBytes(1, []byte{0x08, 0x01})...)
+
+	dirCID := dagPBCIDv0(t, dir)
+	blocks := map[string][]byte{
+		"QmT78zSuBmuS4z925WZfrqQ1qHaJ56DQaTfyMUF7F8ff5o": helloWorldPB,
+		leaf.String(): metadata,
+		dirCID:        dir,
+	}
+
+	g := newGateway(t, func(w http.ResponseWriter, r *http.Request) {
+		block, ok := blocks[r.URL.Path[len("/ipfs/"):]]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(block)
+	})
+	f := NewGatewayFetcher(FetcherConfig{Gateways: []string{g.base()}})
+
+	tests := []struct {
+		name string
+		url  string
+		want []byte
+	}{
+		{name: "unixfs file", url: "ipfs://QmT78zSuBmuS4z925WZfrqQ1qHaJ56DQaTfyMUF7F8ff5o", want: []byte("hello world\n")},
+		{name: "path into a directory", url: "ipfs://" + dirCID + "/metadata.json", want: metadata},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := f.Fetch(context.Background(), tt.url)
+			if err != nil {
+				t.Fatalf("Fetch: %v", err)
+			}
+			if !bytes.Equal(data, tt.want) {
+				t.Fatalf("Fetch = %q, want %q", data, tt.want)
+			}
+		})
+	}
+
+	_, err = f.Fetch(context.Background(), "ipfs://"+dirCID+"/missing.json")
+	if err == nil {
+		t.Fatalf("Fetch of a missing path succeeded")
+	}
+}
@@ -0,0 +1,134 @@
+package arc53
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// unixfs node types
+const (
+	unixfsRaw       uint64 = 0
+	unixfsDirectory uint64 = 1
+	unixfsFile      uint64 = 2
+	unixfsHAMTShard uint64 = 5
+)
+
+type pbLink struct {
+	Hash []byte
+	Name string
+}
+
+type pbNode struct {
+	Links []pbLink
+	Data  []byte
+}
+
+type unixfsData struct {
+	Type uint64
+	Data []byte
+}
+
+// pbField is a single decoded protobuf field, only the wire types used by dag-pb & unixfs are kept
+type pbField struct {
+	Num    uint64
+	Varint uint64
+	Bytes  []byte
+}
+
+func readPBFields(data []byte) ([]pbField, error) {
+	fields := []pbField{}
+
+	for len(data) > 0 {
+		key, n := binary.Uvarint(data)
+		if n <= 0 {
+			return nil, fmt.Errorf("invalid protobuf key")
+		}
+		data = data[n:]
+
+		field := pbField{Num: key >> 3}
+		switch key & 7 {
+		case 0: // varint
+			field.Varint, n = binary.Uvarint(data)
+			if n <= 0 {
+				return nil, fmt.Errorf("invalid protobuf varint")
+			}
+			data = data[n:]
+		case 1: // fixed64
+			if len(data) < 8 {
+				return nil, fmt.Errorf("invalid protobuf fixed64")
+			}
+			data = data[8:]
+		case 2: // length delimited
+			length, n := binary.Uvarint(data)
+			if n <= 0 || uint64(len(data)-n) < length {
+				return nil, fmt.Errorf("invalid protobuf length")
+			}
+			field.Bytes = data[n : n+int(length)]
+			data = data[n+int(length):]
+		case 5: // fixed32
+			if len(data) < 4 {
+				return nil, fmt.Errorf("invalid protobuf fixed32")
+			}
+			data = data[4:]
+		default:
+			return nil, fmt.Errorf("unsupported protobuf wire type %d", key&7)
+		}
+
+		fields = append(fields, field)
+	}
+
+	return fields, nil
+}
+
+func decodePBNode(block []byte) (*pbNode, error) {
+	fields, err := readPBFields(block)
+	if err != nil {
+		return nil, err
+	}
+
+	// PBNode is Data = 1 & Links = 2, links are written first in the canonical encoding
+	node := &pbNode{}
+	for _, field := range fields {
+		switch field.Num {
+		case 1:
+			node.Data = field.Bytes
+		case 2:
+			linkFields, err := readPBFields(field.Bytes)
+			if err != nil {
+				return nil, err
+			}
+
+			link := pbLink{}
+			for _, lf := range linkFields {
+				switch lf.Num {
+				case 1:
+					link.Hash = lf.Bytes
+				case 2:
+					link.Name = string(lf.Bytes)
+				}
+			}
+			node.Links = append(node.Links, link)
+		}
+	}
+
+	return node, nil
+}
+
+func decodeUnixFS(data []byte) (*unixfsData, error) {
+	fields, err := readPBFields(data)
+	if err != nil {
+		return nil, err
+	}
+
+	fs := &unixfsData{}
+	for _, field := range fields {
+		switch field.Num {
+		case 1:
+			fs.Type = field.Varint
+		case 2:
+			fs.Data = field.Bytes
+		}
+	}
+
+	return fs, nil
+}
@@ -0,0 +1,358 @@
+package arc53
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kylebeee/arc53-watcher-go/errors"
+)
+
+const pkg errors.Pkg = "arc53"
+
+// DefaultGateways are the IPFS gateways tried, in order, when none are configured
+var DefaultGateways = []string{
+	"https://ipfs.algonode.xyz/ipfs/",
+	"https://ipfs.io/ipfs/",
+	"https://dweb.link/ipfs/",
+}
+
+const defaultTimeoutMs int64 = 10000
+const defaultMaxBodySize int64 = 4 << 20
+
+// maxDagDepth bounds how far a path or chunked file is followed through linked blocks
+const maxDagDepth = 32
+
+// rawBlockMimeType asks a trustless gateway for the raw block rather than the decoded content
+const rawBlockMimeType = "application/vnd.ipld.raw"
+
+// MetadataFetcher retrieves the ARC53 metadata document a provider points at
+type MetadataFetcher interface {
+	Fetch(ctx context.Context, url string) ([]byte, error)
+}
+
+type FetcherConfig struct {
+	// Gateways are IPFS gateway base urls ending in /ipfs/, tried in order until one succeeds
+	Gateways []string `json:"gateways"`
+	// TimeoutMs is the timeout for each request made to a gateway or url
+	TimeoutMs int64 `json:"timeout_ms"`
+	// MaxBodySize is the largest document in bytes that will be read
+	MaxBodySize int64 `json:"max_body_size"`
+	// CacheDir enables an on-disk cache of IPFS content when set
+	CacheDir string `json:"cache_dir"`
+}
+
+// GatewayFetcher fetches ipfs:// urls through a list of gateways with failover
+// and plain https:// urls directly
+type GatewayFetcher struct {
+	gateways    []string
+	client      *http.Client
+	maxBodySize int64
+	cacheDir    string
+}
+
+func NewGatewayFetcher(cfg FetcherConfig) *GatewayFetcher {
+	f := &GatewayFetcher{
+		gateways:    cfg.Gateways,
+		maxBodySize: cfg.MaxBodySize,
+		cacheDir:    cfg.CacheDir,
+	}
+
+	if len(f.gateways) == 0 {
+		f.gateways = DefaultGateways
+	}
+
+	if f.maxBodySize <= 0 {
+		f.maxBodySize = defaultMaxBodySize
+	}
+
+	timeout := cfg.TimeoutMs
+	if timeout <= 0 {
+		timeout = defaultTimeoutMs
+	}
+	f.client = &http.Client{Timeout: time.Duration(timeout) * time.Millisecond}
+
+	return f
+}
+
+func (f *GatewayFetcher) Fetch(ctx context.Context, url string) ([]byte, error) {
+	const op errors.Op = "GatewayFetcher.Fetch"
+
+	switch {
+	case strings.HasPrefix(url, "ipfs://"):
+		data, err := f.fetchIPFS(ctx, strings.TrimPrefix(url, "ipfs://"))
+		if err != nil {
+			return nil, errors.E(pkg, op, err)
+		}
+		return data, nil
+	case strings.HasPrefix(url, "https://"):
+		data, err := f.get(ctx, url, "")
+		if err != nil {
+			return nil, errors.E(pkg, op, err)
+		}
+		return data, nil
+	default:
+		return nil, errors.E(pkg, op, errors.Type, fmt.Errorf("unsupported metadata url: %s", url))
+	}
+}
+
+// fetchIPFS resolves an ipfs path block by block, verifying every block
+// against its CID so a gateway can't substitute content
+func (f *GatewayFetcher) fetchIPFS(ctx context.Context, path string) ([]byte, error) {
+	const op errors.Op = "GatewayFetcher.fetchIPFS"
+
+	path = strings.Trim(path, "/")
+	cidStr, rest, _ := strings.Cut(path, "/")
+	if cidStr == "" || !isAlphanumeric(cidStr) {
+		return nil, errors.E(pkg, op, errors.Type, fmt.Errorf("invalid ipfs path: %s", path))
+	}
+
+	root, err := ParseCID(cidStr)
+	if err != nil {
+		return nil, errors.E(pkg, op, err)
+	}
+
+	segments := []string{}
+	if rest != "" {
+		segments = strings.Split(rest, "/")
+	}
+
+	data, err := f.resolve(ctx, root, segments, 0)
+	if err != nil {
+		return nil, errors.E(pkg, op, err)
+	}
+
+	return data, nil
+}
+
+func (f *GatewayFetcher) resolve(ctx context.Context, c *CID, segments []string, depth int) ([]byte, error) {
+	const op errors.Op = "GatewayFetcher.resolve"
+
+	if depth > maxDagDepth {
+		return nil, errors.E(pkg, op, errors.Type, fmt.Errorf("dag deeper than %d levels", maxDagDepth))
+	}
+
+	block, err := f.fetchBlock(ctx, c)
+	if err != nil {
+		return nil, errors.E(pkg, op, err)
+	}
+
+	switch c.Codec {
+	case CodecRaw:
+		if len(segments) > 0 {
+			return nil, errors.E(pkg, op, errors.Type, fmt.Errorf("can't resolve a path inside raw block %s", c))
+		}
+		return block, nil
+	case CodecDagPB:
+		node, err := decodePBNode(block)
+		if err != nil {
+			return nil, errors.E(pkg, op, errors.Type, err)
+		}
+
+		fs, err := decodeUnixFS(node.Data)
+		if err != nil {
+			return nil, errors.E(pkg, op, errors.Type, err)
+		}
+
+		if len(segments) > 0 {
+			if fs.Type != unixfsDirectory {
+				return nil, errors.E(pkg, op, errors.Type, fmt.Errorf("%s is not a plain unixfs directory", c))
+			}
+
+			for _, link := range node.Links {
+				if link.Name != segments[0] {
+					continue
+				}
+
+				child, err := parseCIDBytes(link.Hash)
+				if err != nil {
+					return nil, errors.E(pkg, op, errors.Type, err)
+				}
+				return f.resolve(ctx, child, segments[1:], depth+1)
+			}
+
+			return nil, errors.E(pkg, op, errors.Type, fmt.Errorf("%s not found in %s", segments[0], c))
+		}
+
+		if fs.Type != unixfsFile && fs.Type != unixfsRaw {
+			return nil, errors.E(pkg, op, errors.Type, fmt.Errorf("%s is not a unixfs file", c))
+		}
+
+		// large files are chunked, their content is the node data followed by each child in order
+		data := append([]byte{}, fs.Data...)
+		for _, link := range node.Links {
+			child, err := parseCIDBytes(link.Hash)
+			if err != nil {
+				return nil, errors.E(pkg, op, errors.Type, err)
+			}
+
+			childData, err := f.resolve(ctx, child, nil, depth+1)
+			if err != nil {
+				return nil, errors.E(pkg, op, err)
+			}
+
+			data = append(data, childData...)
+			if int64(len(data)) > f.maxBodySize {
+				return nil, errors.E(pkg, op, fmt.Errorf("%s is larger than %d bytes", c, f.maxBodySize))
+			}
+		}
+
+		return data, nil
+	default:
+		return nil, errors.E(pkg, op, errors.Type, fmt.Errorf("unsupported codec 0x%x for %s", c.Codec, c))
+	}
+}
+
+// fetchBlock fetches the raw block for a CID, failing over to the next gateway when
+// one errors or serves a block that doesn't hash to the CID
+func (f *GatewayFetcher) fetchBlock(ctx context.Context, c *CID) ([]byte, error) {
+	const op errors.Op = "GatewayFetcher.fetchBlock"
+	var lastErr, mismatch error
+
+	block, ok := f.readCache(c)
+	if ok {
+		return block, nil
+	}
+
+	for _, gateway := range f.gateways {
+		block, err := f.get(ctx, gateway+c.String()+"?format=raw", rawBlockMimeType)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		err = c.Verify(block)
+		if err != nil {
+			fmt.Printf("[WARN][ARC53] gateway %s served a block that does not match %s\n", gateway, c)
+			mismatch = err
+			continue
+		}
+
+		f.writeCache(c, block)
+		return block, nil
+	}
+
+	// a gateway serving the wrong content is reported over any plain network failures
+	if mismatch != nil {
+		return nil, errors.E(pkg, op, errors.Integrity, mismatch, "content failed verification")
+	}
+
+	return nil, errors.E(pkg, op, lastErr, "all gateways failed")
+}
+
+func (f *GatewayFetcher) get(ctx context.Context, url string, accept string) ([]byte, error) {
+	const op errors.Op = "GatewayFetcher.get"
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, errors.E(pkg, op, err)
+	}
+
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, errors.E(pkg, op, errors.Network, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.E(pkg, op, errors.Network, fmt.Errorf("request to %s failed: %s", url, resp.Status))
+	}
+
+	if resp.ContentLength > f.maxBodySize {
+		return nil, errors.E(pkg, op, fmt.Errorf("response from %s is larger than %d bytes", url, f.maxBodySize))
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, f.maxBodySize+1))
+	if err != nil {
+		return nil, errors.E(pkg, op, errors.Network, err)
+	}
+
+	if int64(len(body)) > f.maxBodySize {
+		return nil, errors.E(pkg, op, fmt.Errorf("response from %s is larger than %d bytes", url, f.maxBodySize))
+	}
+
+	if len(body) == 0 {
+		return nil, errors.E(pkg, op, errors.Network, fmt.Errorf("empty response from %s", url))
+	}
+
+	return body, nil
+}
+
+// cacheFile is where the block of a CID is cached, blocks rather than resolved paths are
+// cached so whatever is read back can be checked against the CID it's keyed by
+func (f *GatewayFetcher) cacheFile(c *CID) string {
+	return filepath.Join(f.cacheDir, c.String())
+}
+
+// readCache returns the cached block of a CID, a block that no longer hashes to its CID
+// is removed & fetched again
+func (f *GatewayFetcher) readCache(c *CID) ([]byte, bool) {
+	if f.cacheDir == "" {
+		return nil, false
+	}
+
+	block, err := os.ReadFile(f.cacheFile(c))
+	if err != nil || len(block) == 0 {
+		return nil, false
+	}
+
+	err = c.Verify(block)
+	if err != nil {
+		fmt.Printf("[WARN][ARC53] cached block for %s failed verification, refetching\n", c)
+		os.Remove(f.cacheFile(c))
+		return nil, false
+	}
+
+	return block, true
+}
+
+// writeCache caches a block that was verified against its CID
+func (f *GatewayFetcher) writeCache(c *CID, block []byte) {
+	if f.cacheDir == "" {
+		return
+	}
+
+	err := os.MkdirAll(f.cacheDir, 0o755)
+	if err != nil {
+		fmt.Printf("[WARN][ARC53] unable to create metadata cache: %s\n", err)
+		return
+	}
+
+	// write then rename so readers never see a partial file
+	tmp, err := os.CreateTemp(f.cacheDir, ".tmp-*")
+	if err != nil {
+		fmt.Printf("[WARN][ARC53] unable to write metadata cache: %s\n", err)
+		return
+	}
+
+	_, err = tmp.Write(block)
+	closeErr := tmp.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), f.cacheFile(c))
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		fmt.Printf("[WARN][ARC53] unable to write metadata cache: %s\n", err)
+	}
+}
+
+func isAlphanumeric(s string) bool {
+	for _, r := range s {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,285 @@
+package arc53
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kylebeee/arc53-watcher-go/errors"
+)
+
+// rawCID returns the CIDv1 raw CID of a block
+func rawCID(t *testing.T, block []byte) string {
+	t.Helper()
+
+	sum := sha256.Sum256(block)
+	var buf []byte
+	buf = binary.AppendUvarint(buf, 1)
+	buf = binary.AppendUvarint(buf, CodecRaw)
+	buf = binary.AppendUvarint(buf, hashSha256)
+	buf = binary.AppendUvarint(buf, uint64(len(sum)))
+	buf = append(buf, sum[:]...)
+
+	c, err := parseCIDBytes(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c.String()
+}
+
+// dagPBCIDv0 returns the CIDv0 of a dag-pb block
+func dagPBCIDv0(t *testing.T, block []byte) string {
+	t.Helper()
+
+	sum := sha256.Sum256(block)
+	return encodeBase58(append([]byte{byte(hashSha256), 32}, sum[:]...))
+}
+
+// gateway serves blocks by CID & counts the requests it gets
+type gateway struct {
+	*httptest.Server
+	requests int
+}
+
+func newGateway(t *testing.T, handler func(w http.ResponseWriter, r *http.Request)) *gateway {
+	t.Helper()
+
+	g := &gateway{}
+	g.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		g.requests++
+		handler(w, r)
+	}))
+	t.Cleanup(g.Close)
+
+	return g
+}
+
+func (g *gateway) base() string {
+	return g.URL + "/ipfs/"
+}
+
+// serving is a gateway handler that serves block for any CID
+func serving(block []byte) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept") != rawBlockMimeType || r.URL.Query().Get("format") != "raw" {
+			http.Error(w, "raw blocks only", http.StatusBadRequest)
+			return
+		}
+		w.Write(block)
+	}
+}
+
+func failing(w http.ResponseWriter, r *http.Request) {
+	http.Error(w, "gateway down", http.StatusBadGateway)
+}
+
+func TestGatewayFetcherFailover(t *testing.T) {
+	block := []byte(`{"version":"0.0.1"}`)
+	cid := rawCID(t, block)
+
+	tests := []struct {
+		name     string
+		handlers []func(w http.ResponseWriter, r *http.Request)
+		// requests is the requests each gateway should see
+		requests []int
+		kind     errors.Kind
+	}{
+		{
+			name:     "first gateway serves",
+			handlers: []func(w http.ResponseWriter, r *http.Request){serving(block), serving(block)},
+			requests: []int{1, 0},
+		},
+		{
+			name:     "fails over past an erroring gateway",
+			handlers: []func(w http.ResponseWriter, r *http.Request){failing, serving(block)},
+			requests: []int{1, 1},
+		},
+		{
+			name:     "fails over past a gateway serving other content",
+			handlers: []func(w http.ResponseWriter, r *http.Request){serving([]byte(`{"version":"evil"}`)), serving(block)},
+			requests: []int{1, 1},
+		},
+		{
+			name:     "every gateway failing is a network error",
+			handlers: []func(w http.ResponseWriter, r *http.Request){failing, failing},
+			requests: []int{1, 1},
+			kind:     errors.Network,
+		},
+		{
+			name:     "a mismatch wins over network failures",
+			handlers: []func(w http.ResponseWriter, r *http.Request){serving([]byte(`{"version":"evil"}`)), failing},
+			requests: []int{1, 1},
+			kind:     errors.Integrity,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gateways := []*gateway{}
+			bases := []string{}
+			for _, handler := range tt.handlers {
+				g := newGateway(t, handler)
+				gateways = append(gateways, g)
+				bases = append(bases, g.base())
+			}
+
+			f := NewGatewayFetcher(FetcherConfig{Gateways: bases})
+			data, err := f.Fetch(context.Background(), "ipfs://"+cid)
+
+			if tt.kind == "" {
+				if err != nil {
+					t.Fatalf("Fetch: %v", err)
+				}
+				if !bytes.Equal(data, block) {
+					t.Fatalf("Fetch = %q, want %q", data, block)
+				}
+			} else if err == nil {
+				t.Fatalf("Fetch succeeded, want a %s error", tt.kind)
+			} else if !errors.HasKind(err, tt.kind) {
+				t.Fatalf("Fetch error kinds %v, want %s", errors.Kinds(err), tt.kind)
+			}
+
+			for i, g := range gateways {
+				if g.requests != tt.requests[i] {
+					t.Errorf("gateway %d got %d requests, want %d", i, g.requests, tt.requests[i])
+				}
+			}
+		})
+	}
+}
+
+func TestGatewayFetcherBodySizeCap(t *testing.T) {
+	small := []byte(`{"version":"0.0.1"}`)
+	large := bytes.Repeat([]byte("a"), 1024)
+
+	tests := []struct {
+		name  string
+		block []byte
+		// chunked leaves out the content length so the cap is hit while reading
+		chunked bool
+		ok      bool
+	}{
+		{name: "under the cap", block: small, ok: true},
+		{name: "over the cap by content length", block: large},
+		{name: "over the cap while reading", block: large, chunked: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newGateway(t, func(w http.ResponseWriter, r *http.Request) {
+				if tt.chunked {
+					w.Write(tt.block[:1])
+					w.(http.Flusher).Flush()
+					w.Write(tt.block[1:])
+					return
+				}
+				serving(tt.block)(w, r)
+			})
+
+			f := NewGatewayFetcher(FetcherConfig{Gateways: []string{g.base()}, MaxBodySize: 512})
+			_, err := f.Fetch(context.Background(), "ipfs://"+rawCID(t, tt.block))
+
+			if tt.ok && err != nil {
+				t.Fatalf("Fetch: %v", err)
+			}
+			if !tt.ok && (err == nil || !strings.Contains(err.Error(), "larger than 512 bytes")) {
+				t.Fatalf("Fetch error %v, want the body size cap", err)
+			}
+		})
+	}
+}
+
+func TestGatewayFetcherTimeout(t *testing.T) {
+	block := []byte(`{"version":"0.0.1"}`)
+	cid := rawCID(t, block)
+
+	release := make(chan struct{})
+	defer close(release)
+
+	slow := newGateway(t, func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	})
+	fast := newGateway(t, serving(block))
+
+	t.Run("a slow gateway is skipped", func(t *testing.T) {
+		f := NewGatewayFetcher(FetcherConfig{Gateways: []string{slow.base(), fast.base()}, TimeoutMs: 100})
+
+		start := time.Now()
+		data, err := f.Fetch(context.Background(), "ipfs://"+cid)
+		if err != nil {
+			t.Fatalf("Fetch: %v", err)
+		}
+		if !bytes.Equal(data, block) {
+			t.Fatalf("Fetch = %q, want %q", data, block)
+		}
+		if elapsed := time.Since(start); elapsed > 2*time.Second {
+			t.Fatalf("Fetch took %s, the slow gateway wasn't timed out", elapsed)
+		}
+	})
+
+	t.Run("only slow gateways time out as a network error", func(t *testing.T) {
+		f := NewGatewayFetcher(FetcherConfig{Gateways: []string{slow.base()}, TimeoutMs: 100})
+
+		_, err := f.Fetch(context.Background(), "ipfs://"+cid)
+		if err == nil || !errors.HasKind(err, errors.Network) {
+			t.Fatalf("Fetch error %v, want a network error", err)
+		}
+	})
+}
+
+func TestGatewayFetcherCache(t *testing.T) {
+	block := []byte(`{"version":"0.0.1"}`)
+	cid := rawCID(t, block)
+	c, err := ParseCID(cid)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	g := newGateway(t, serving(block))
+	f := NewGatewayFetcher(FetcherConfig{Gateways: []string{g.base()}, CacheDir: t.TempDir()})
+
+	for i := 0; i < 2; i++ {
+		data, err := f.Fetch(context.Background(), "ipfs://"+cid)
+		if err != nil {
+			t.Fatalf("Fetch: %v", err)
+		}
+		if !bytes.Equal(data, block) {
+			t.Fatalf("Fetch = %q, want %q", data, block)
+		}
+	}
+	if g.requests != 1 {
+		t.Fatalf("gateway got %d requests, want the second fetch served from the cache", g.requests)
+	}
+
+	// a cached block that no longer matches its CID is dropped & fetched again
+	err = os.WriteFile(f.cacheFile(c), []byte(`{"version":"evil"}`), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := f.Fetch(context.Background(), "ipfs://"+cid)
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	if !bytes.Equal(data, block) {
+		t.Fatalf("Fetch = %q, want %q", data, block)
+	}
+	if g.requests != 2 {
+		t.Fatalf("gateway got %d requests, want the tampered block refetched", g.requests)
+	}
+
+	cached, err := os.ReadFile(f.cacheFile(c))
+	if err != nil || !bytes.Equal(cached, block) {
+		t.Fatalf("cache holds %q, want the verified block", cached)
+	}
+}
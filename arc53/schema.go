@@ -0,0 +1,378 @@
+package arc53
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/algorand/go-algorand-sdk/v2/types"
+	"github.com/kylebeee/arc53-watcher-go/errors"
+)
+
+//go:embed schema.json
+var communitySchemaJSON []byte
+
+// ValidationError describes a single way a document fails the ARC53 schema
+type ValidationError struct {
+	// Path is a JSON pointer to the offending value, empty for the document root
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+func (v ValidationError) String() string {
+	if v.Path == "" {
+		return v.Message
+	}
+	return v.Path + ": " + v.Message
+}
+
+// schema is the subset of JSON Schema the ARC53 document schema is written in
+type schema struct {
+	Ref                  string             `json:"$ref"`
+	Type                 schemaTypes        `json:"type"`
+	Enum                 []json.RawMessage  `json:"enum"`
+	Required             []string           `json:"required"`
+	Properties           map[string]*schema `json:"properties"`
+	AdditionalProperties *schemaOrBool      `json:"additionalProperties"`
+	PropertyNames        *schema            `json:"propertyNames"`
+	Items                *schema            `json:"items"`
+	MinItems             *int               `json:"minItems"`
+	MaxItems             *int               `json:"maxItems"`
+	MinLength            *int               `json:"minLength"`
+	MaxLength            *int               `json:"maxLength"`
+	MaxBytes             *int               `json:"maxBytes"`
+	Pattern              string             `json:"pattern"`
+	Format               string             `json:"format"`
+	Minimum              *json.Number       `json:"minimum"`
+	Maximum              *json.Number       `json:"maximum"`
+	Definitions          map[string]*schema `json:"definitions"`
+
+	pattern *regexp.Regexp
+}
+
+// schemaTypes accepts both "type": "string" & "type": ["string", "null"]
+type schemaTypes []string
+
+func (t *schemaTypes) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*t = schemaTypes{single}
+		return nil
+	}
+
+	var multiple []string
+	err := json.Unmarshal(data, &multiple)
+	if err != nil {
+		return err
+	}
+	*t = multiple
+	return nil
+}
+
+// schemaOrBool is a subschema that may be written as true or false
+type schemaOrBool struct {
+	allowed bool
+	schema  *schema
+}
+
+func (s *schemaOrBool) UnmarshalJSON(data []byte) error {
+	var allowed bool
+	if err := json.Unmarshal(data, &allowed); err == nil {
+		s.allowed = allowed
+		return nil
+	}
+
+	s.allowed = true
+	return json.Unmarshal(data, &s.schema)
+}
+
+// Validator checks documents against a JSON schema
+type Validator struct {
+	root *schema
+}
+
+// NewValidator compiles a JSON schema document
+func NewValidator(schemaJSON []byte) (*Validator, error) {
+	const op errors.Op = "NewValidator"
+
+	var root schema
+	err := json.Unmarshal(schemaJSON, &root)
+	if err != nil {
+		return nil, errors.E(pkg, op, errors.Type, err, "Invalid Schema")
+	}
+
+	err = root.compile()
+	if err != nil {
+		return nil, errors.E(pkg, op, errors.Type, err, "Invalid Schema")
+	}
+
+	return &Validator{root: &root}, nil
+}
+
+var (
+	communityValidator     *Validator
+	communityValidatorOnce sync.Once
+)
+
+// CommunityValidator returns the validator for the embedded ARC53 community schema
+func CommunityValidator() *Validator {
+	communityValidatorOnce.Do(func() {
+		v, err := NewValidator(communitySchemaJSON)
+		if err != nil {
+			// the schema is embedded at build time, failing to compile it is a programming error
+			panic(err)
+		}
+		communityValidator = v
+	})
+	return communityValidator
+}
+
+// ValidateCommunity checks an ARC53 community document against the embedded schema
+func ValidateCommunity(data []byte) []ValidationError {
+	return CommunityValidator().Validate(data)
+}
+
+func (s *schema) compile() error {
+	if s == nil {
+		return nil
+	}
+
+	if s.Pattern != "" {
+		re, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return err
+		}
+		s.pattern = re
+	}
+
+	children := []*schema{s.PropertyNames, s.Items}
+	if s.AdditionalProperties != nil {
+		children = append(children, s.AdditionalProperties.schema)
+	}
+	for _, child := range s.Properties {
+		children = append(children, child)
+	}
+	for _, child := range s.Definitions {
+		children = append(children, child)
+	}
+
+	for _, child := range children {
+		err := child.compile()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Validate checks data against the schema & returns every violation found,
+// a document that isn't JSON at all is reported as a single root error
+func (v *Validator) Validate(data []byte) []ValidationError {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var doc interface{}
+	err := dec.Decode(&doc)
+	if err == nil && dec.More() {
+		err = fmt.Errorf("unexpected data after the top level value")
+	}
+	if err != nil {
+		return []ValidationError{{Message: fmt.Sprintf("invalid JSON: %s", err)}}
+	}
+
+	var errs []ValidationError
+	v.validate(v.root, doc, "", &errs)
+	return errs
+}
+
+func (v *Validator) resolve(s *schema) *schema {
+	for s != nil && s.Ref != "" {
+		name, ok := strings.CutPrefix(s.Ref, "#/definitions/")
+		if !ok {
+			return nil
+		}
+		s = v.root.Definitions[name]
+	}
+	return s
+}
+
+func (v *Validator) validate(s *schema, value interface{}, path string, errs *[]ValidationError) {
+	s = v.resolve(s)
+	if s == nil {
+		return
+	}
+
+	report := func(format string, args ...interface{}) {
+		*errs = append(*errs, ValidationError{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if len(s.Type) > 0 && !typeMatches(s.Type, value) {
+		report("expected %s, got %s", strings.Join(s.Type, " or "), jsonType(value))
+		return
+	}
+
+	if len(s.Enum) > 0 {
+		encoded, _ := json.Marshal(value)
+		found := false
+		for _, option := range s.Enum {
+			if bytes.Equal(bytes.TrimSpace(option), encoded) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			options := make([]string, len(s.Enum))
+			for i, option := range s.Enum {
+				options[i] = string(option)
+			}
+			report("must be one of %s", strings.Join(options, ", "))
+		}
+	}
+
+	switch value := value.(type) {
+	case string:
+		v.validateString(s, value, report)
+	case json.Number:
+		validateNumber(s, value, report)
+	case []interface{}:
+		if s.MinItems != nil && len(value) < *s.MinItems {
+			report("must have at least %d items", *s.MinItems)
+		}
+		if s.MaxItems != nil && len(value) > *s.MaxItems {
+			report("must have at most %d items", *s.MaxItems)
+		}
+		for i, item := range value {
+			v.validate(s.Items, item, fmt.Sprintf("%s/%d", path, i), errs)
+		}
+	case map[string]interface{}:
+		for _, key := range s.Required {
+			if _, ok := value[key]; !ok {
+				report("missing required property %q", key)
+			}
+		}
+
+		keys := make([]string, 0, len(value))
+		for key := range value {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			keyPath := path + "/" + escapePointer(key)
+
+			if s.PropertyNames != nil {
+				v.validate(s.PropertyNames, key, keyPath, errs)
+			}
+
+			if child, ok := s.Properties[key]; ok {
+				v.validate(child, value[key], keyPath, errs)
+				continue
+			}
+
+			if s.AdditionalProperties != nil {
+				if !s.AdditionalProperties.allowed {
+					*errs = append(*errs, ValidationError{Path: keyPath, Message: "property is not allowed"})
+					continue
+				}
+				v.validate(s.AdditionalProperties.schema, value[key], keyPath, errs)
+			}
+		}
+	}
+}
+
+func (v *Validator) validateString(s *schema, value string, report func(string, ...interface{})) {
+	length := utf8.RuneCountInString(value)
+	if s.MinLength != nil && length < *s.MinLength {
+		report("must be at least %d characters", *s.MinLength)
+	}
+	if s.MaxLength != nil && length > *s.MaxLength {
+		report("must be at most %d characters", *s.MaxLength)
+	}
+	// maxBytes isn't JSON Schema, TEXT columns are limited in bytes rather than characters
+	if s.MaxBytes != nil && len(value) > *s.MaxBytes {
+		report("must be at most %d bytes", *s.MaxBytes)
+	}
+	if s.pattern != nil && !s.pattern.MatchString(value) {
+		report("must match %s", s.Pattern)
+	}
+
+	switch s.Format {
+	case "algorand-address":
+		_, err := types.DecodeAddress(value)
+		if err != nil {
+			report("must be a valid algorand address")
+		}
+	}
+}
+
+func validateNumber(s *schema, value json.Number, report func(string, ...interface{})) {
+	n, ok := new(big.Rat).SetString(value.String())
+	if !ok {
+		report("invalid number %s", value)
+		return
+	}
+
+	if s.Minimum != nil {
+		min, ok := new(big.Rat).SetString(s.Minimum.String())
+		if ok && n.Cmp(min) < 0 {
+			report("must be at least %s", s.Minimum.String())
+		}
+	}
+	if s.Maximum != nil {
+		max, ok := new(big.Rat).SetString(s.Maximum.String())
+		if ok && n.Cmp(max) > 0 {
+			report("must be at most %s", s.Maximum.String())
+		}
+	}
+}
+
+func typeMatches(allowed schemaTypes, value interface{}) bool {
+	actual := jsonType(value)
+	for _, t := range allowed {
+		if t == actual {
+			return true
+		}
+
+		// every integer is also a number
+		if t == "number" && actual == "integer" {
+			return true
+		}
+	}
+	return false
+}
+
+func jsonType(value interface{}) string {
+	switch value := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case json.Number:
+		n, ok := new(big.Rat).SetString(value.String())
+		if ok && n.IsInt() {
+			return "integer"
+		}
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return "unknown"
+}
+
+// escapePointer escapes a key for use as a JSON pointer segment
+func escapePointer(key string) string {
+	key = strings.ReplaceAll(key, "~", "~0")
+	return strings.ReplaceAll(key, "/", "~1")
+}
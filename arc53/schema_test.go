@@ -0,0 +1,114 @@
+package arc53
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// validAddress is the zero address, a well formed algorand address
+const validAddress = "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAY5HFKQ"
+
+func TestValidateCommunity(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+		want []ValidationError
+	}{
+		{
+			name: "valid",
+			doc: fmt.Sprintf(`{"version": "1.0", "tokens": [{"asset_id": 1}], "associates": [{"address": %q, "role": "artist"}],
+				"faq": [{"q": "when?", "a": "soon", "ordering": 0}], "collections": [{"name": "art", "extras": {"k": "v"}}]}`, validAddress),
+		},
+		{
+			name: "not JSON",
+			doc:  `{"version": `,
+			want: []ValidationError{{Message: "invalid JSON: unexpected EOF"}},
+		},
+		{
+			name: "missing required properties",
+			doc:  `{"faq": [{"q": "when?"}]}`,
+			want: []ValidationError{
+				{Message: `missing required property "version"`},
+				{Path: "/faq/0", Message: `missing required property "a"`},
+			},
+		},
+		{
+			name: "wrong types",
+			doc:  `{"version": 1, "tokens": {}, "collections": [{"name": "art", "explicit": "yes"}]}`,
+			want: []ValidationError{
+				{Path: "/collections/0/explicit", Message: "expected boolean, got string"},
+				{Path: "/tokens", Message: "expected array, got object"},
+				{Path: "/version", Message: "expected string, got integer"},
+			},
+		},
+		{
+			name: "integer given a fraction",
+			doc:  `{"version": "1.0", "faq": [{"q": "when?", "a": "soon", "ordering": 1.5}]}`,
+			want: []ValidationError{{Path: "/faq/0/ordering", Message: "expected integer, got number"}},
+		},
+		{
+			name: "invalid addresses",
+			doc:  fmt.Sprintf(`{"version": "1.0", "collections": [{"name": "art", "artists": [%q, "nope"]}]}`, validAddress[:57]+"A"),
+			want: []ValidationError{
+				{Path: "/collections/0/artists/0", Message: "must be a valid algorand address"},
+				{Path: "/collections/0/artists/1", Message: "must be a valid algorand address"},
+			},
+		},
+		{
+			name: "ASA IDs out of range",
+			doc:  `{"version": "1.0", "tokens": [{"asset_id": 0}, {"asset_id": 18446744073709551615}, {"asset_id": 18446744073709551616}]}`,
+			want: []ValidationError{
+				{Path: "/tokens/0/asset_id", Message: "must be at least 1"},
+				{Path: "/tokens/2/asset_id", Message: "must be at most 18446744073709551615"},
+			},
+		},
+		{
+			name: "not one of the enum",
+			doc:  `{"version": "1.0", "settings": {"default_tab": "home"}}`,
+			want: []ValidationError{{Path: "/settings/default_tab", Message: `must be one of "activity", "about", "collections", "staking", "subscriptions", "shuffles"`}},
+		},
+		{
+			name: "too short & too long",
+			doc:  fmt.Sprintf(`{"version": "", "associates": [{"address": %q, "role": %q}]}`, validAddress, strings.Repeat("a", 65)),
+			want: []ValidationError{
+				{Path: "/associates/0/role", Message: "must be at most 64 characters"},
+				{Path: "/version", Message: "must be at least 1 characters"},
+			},
+		},
+		{
+			name: "multibyte characters count once against maxLength",
+			doc:  fmt.Sprintf(`{"version": "1.0", "associates": [{"address": %q, "role": %q}]}`, validAddress, strings.Repeat("é", 64)),
+		},
+		{
+			name: "TEXT fields at their limit in bytes",
+			doc:  fmt.Sprintf(`{"version": "1.0", "faq": [{"q": "when?", "a": %q}]}`, strings.Repeat("a", 65535)),
+		},
+		{
+			name: "TEXT fields over their limit in bytes",
+			// 32768 two byte characters fit in maxLength but not in a TEXT column
+			doc: fmt.Sprintf(`{"version": "1.0", "faq": [{"q": "when?", "a": %q}], "extras": [{"key": "k", "value": %q}], "collections": [{"name": "art", "description": %q, "extras": {"k": %q}}]}`,
+				strings.Repeat("é", 32768), strings.Repeat("é", 32768), strings.Repeat("é", 32768), strings.Repeat("é", 32768)),
+			want: []ValidationError{
+				{Path: "/collections/0/description", Message: "must be at most 65535 bytes"},
+				{Path: "/collections/0/extras/k", Message: "must be at most 65535 bytes"},
+				{Path: "/extras/0/value", Message: "must be at most 65535 bytes"},
+				{Path: "/faq/0/a", Message: "must be at most 65535 bytes"},
+			},
+		},
+		{
+			name: "extras keys too long",
+			doc:  fmt.Sprintf(`{"version": "1.0", "collections": [{"name": "art", "extras": {%q: "v"}}]}`, strings.Repeat("k", 129)),
+			want: []ValidationError{{Path: "/collections/0/extras/" + strings.Repeat("k", 129), Message: "must be at most 128 characters"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ValidateCommunity([]byte(tt.doc))
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
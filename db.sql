@@ -1,6 +1,6 @@
 CREATE TABLE "provider" (
   "id" bigint unsigned NOT NULL,
-  "type" enum('nfd') NOT NULL,
+  "type" enum('nfd','app') NOT NULL,
   "round" bigint unsigned NOT NULL,
   PRIMARY KEY ("id"),
   INDEX "type" ("type"),
@@ -10,6 +10,7 @@ CREATE TABLE "provider" (
 CREATE TABLE "provider_address" (
   "id" bigint unsigned NOT NULL,
   "address" varchar(58) NOT NULL,
+  "verified" tinyint(1) NOT NULL DEFAULT '0',
   PRIMARY KEY ("id", "address")
 );
 
@@ -23,6 +24,8 @@ CREATE TABLE "community_json" (
   "id" bigint unsigned NOT NULL,
   "data" json NOT NULL,
   "malformed" tinyint(1) NOT NULL DEFAULT '0',
+  "cid_mismatch" tinyint(1) NOT NULL DEFAULT '0',
+  "validation_errors" json DEFAULT NULL,
   PRIMARY KEY ("id")
 );
 
@@ -151,4 +154,108 @@ CREATE TABLE "community_extras" (
   "mvalue" text NOT NULL,
   PRIMARY KEY ("id","mkey"),
   KEY "key" ("mkey")
-);
\ No newline at end of file
+);
+
+CREATE TABLE "cursor" (
+  "id" varchar(32) NOT NULL,
+  "round" bigint unsigned NOT NULL,
+  PRIMARY KEY ("id")
+);
+
+CREATE TABLE "catchup_checkpoint" (
+  "provider_type" varchar(32) NOT NULL,
+  "min_round" bigint unsigned NOT NULL,
+  "next_token" varchar(256) NOT NULL DEFAULT '',
+  "round" bigint unsigned NOT NULL,
+  PRIMARY KEY ("provider_type")
+);
+
+CREATE TABLE "sync_failure" (
+  "id" bigint unsigned NOT NULL,
+  "provider_type" varchar(32) NOT NULL,
+  "error" varchar(1024) NOT NULL,
+  "attempts" int unsigned NOT NULL DEFAULT '1',
+  "last_attempt" bigint NOT NULL,
+  PRIMARY KEY ("provider_type", "id")
+);
+
+CREATE TABLE "dead_letter" (
+  "id" bigint unsigned NOT NULL AUTO_INCREMENT,
+  "kind" enum('sync','decode') NOT NULL,
+  "dedupe_key" varchar(128) NOT NULL,
+  "provider_type" varchar(32) NOT NULL DEFAULT '',
+  "app_id" bigint unsigned NOT NULL DEFAULT '0',
+  "round" bigint unsigned NOT NULL,
+  "txn_index" bigint unsigned NOT NULL DEFAULT '0',
+  "error_kind" varchar(64) NOT NULL DEFAULT '',
+  "error" varchar(1024) NOT NULL,
+  "attempts" int unsigned NOT NULL DEFAULT '1',
+  "next_attempt" bigint NOT NULL,
+  "last_attempt" bigint NOT NULL,
+  "created" bigint NOT NULL,
+  PRIMARY KEY ("id"),
+  UNIQUE KEY "dedupe_key" ("dedupe_key"),
+  KEY "next_attempt" ("next_attempt")
+);
+
+CREATE TABLE "sync_job" (
+  "id" varchar(24) NOT NULL,
+  "provider_type" varchar(32) NOT NULL,
+  "app_id" bigint unsigned NOT NULL,
+  "status" enum('queued','running','succeeded','failed') NOT NULL,
+  "error" varchar(1024) NOT NULL DEFAULT '',
+  "changes" json DEFAULT NULL,
+  "created" bigint NOT NULL,
+  "started" bigint NOT NULL DEFAULT '0',
+  "finished" bigint NOT NULL DEFAULT '0',
+  PRIMARY KEY ("id"),
+  KEY "app_id" ("provider_type","app_id"),
+  KEY "status" ("status")
+);
+
+CREATE TABLE "event" (
+  "id" bigint unsigned NOT NULL AUTO_INCREMENT,
+  "provider_id" bigint unsigned NOT NULL,
+  "type" varchar(32) NOT NULL,
+  "subject" varchar(128) NOT NULL DEFAULT '',
+  "round" bigint unsigned NOT NULL,
+  "created" bigint NOT NULL,
+  PRIMARY KEY ("id"),
+  KEY "provider_id" ("provider_id")
+);
+
+CREATE TABLE "event_sequence" (
+  "id" tinyint unsigned NOT NULL,
+  "last_id" bigint unsigned NOT NULL,
+  PRIMARY KEY ("id")
+);
+
+INSERT INTO "event_sequence" ("id", "last_id") SELECT 1, COALESCE(MAX("id"), 0) FROM "event";
+
+CREATE TABLE "webhook" (
+  "id" varchar(24) NOT NULL,
+  "url" varchar(2048) NOT NULL,
+  "secret" varchar(128) NOT NULL,
+  "provider_ids" varchar(1024) NOT NULL DEFAULT '',
+  "types" varchar(512) NOT NULL DEFAULT '',
+  "last_event_id" bigint unsigned NOT NULL DEFAULT '0',
+  "created" bigint NOT NULL,
+  PRIMARY KEY ("id")
+);
+
+CREATE TABLE "webhook_delivery" (
+  "id" bigint unsigned NOT NULL AUTO_INCREMENT,
+  "webhook_id" varchar(24) NOT NULL,
+  "event_id" bigint unsigned NOT NULL,
+  "status" enum('pending','delivered','failed') NOT NULL,
+  "attempts" int unsigned NOT NULL DEFAULT '0',
+  "response_code" int NOT NULL DEFAULT '0',
+  "error" varchar(1024) NOT NULL DEFAULT '',
+  "next_attempt" bigint NOT NULL,
+  "last_attempt" bigint NOT NULL DEFAULT '0',
+  "created" bigint NOT NULL,
+  PRIMARY KEY ("id"),
+  UNIQUE KEY "webhook_event" ("webhook_id","event_id"),
+  KEY "due" ("status","next_attempt"),
+  KEY "webhook_due" ("webhook_id","status","next_attempt")
+);
@@ -0,0 +1,94 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/kylebeee/arc53-watcher-go/errors"
+)
+
+// CatchupCheckpoint is how far a provider type's catch up has got, it's kept
+// while a catch up is in progress so an interrupted one can be resumed
+type CatchupCheckpoint struct {
+	ProviderType string `structs:"provider_type,omitempty" db:"provider_type" json:"provider_type,omitempty"`
+	// MinRound is the round the catch up was started from
+	MinRound uint64 `structs:"min_round,omitempty" db:"min_round" json:"min_round"`
+	// NextToken is the indexer token of the next page to process
+	NextToken string `structs:"next_token,omitempty" db:"next_token" json:"next_token,omitempty"`
+	// Round is the round the catch up is syncing apps up to
+	Round uint64 `structs:"round,omitempty" db:"round" json:"round"`
+}
+
+func CatchupCheckpointTableKeys() []string {
+	return []string{"provider_type", "min_round", "next_token", "round"}
+}
+
+func GetCatchupCheckpoint[H Handle](h H, providerType string) (*CatchupCheckpoint, error) {
+	const op errors.Op = "GetCatchupCheckpoint"
+	query := fmt.Sprintf("select %s from catchup_checkpoint where provider_type = ?", strings.Join(CatchupCheckpointTableKeys(), ","))
+
+	var checkpoint CatchupCheckpoint
+	err := h.Get(&checkpoint, query, providerType)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.E(pkg, op, errors.DatabaseResultNotFound, err, "Catchup Checkpoint Not Found")
+		}
+		return nil, errors.E(pkg, op, err)
+	}
+
+	return &checkpoint, nil
+}
+
+// SetCatchupCheckpoint creates or moves a provider type's checkpoint in a single statement
+func SetCatchupCheckpoint[H Handle](h H, checkpoint *CatchupCheckpoint) error {
+	const op errors.Op = "SetCatchupCheckpoint"
+	query := "insert into catchup_checkpoint (provider_type, min_round, next_token, round) values (?, ?, ?, ?) on duplicate key update min_round = values(min_round), next_token = values(next_token), round = values(round)"
+	args := []interface{}{checkpoint.ProviderType, checkpoint.MinRound, checkpoint.NextToken, checkpoint.Round}
+
+	switch h := any(h).(type) {
+	case *sqlx.Tx:
+		stmt, err := h.Prepare(query)
+		if err != nil {
+			return errors.E(pkg, op, errors.Database, err, "Failed to Prepare Query")
+		}
+
+		_, err = stmt.Exec(args...)
+		if err != nil {
+			return errors.E(pkg, op, errors.Database, err, "Failed to Execute Query")
+		}
+	case *sqlx.DB:
+		_, err := h.Exec(query, args...)
+		if err != nil {
+			return errors.E(pkg, op, errors.Database, err, "Failed to Execute Query")
+		}
+	}
+
+	return nil
+}
+
+func DeleteCatchupCheckpoint[H Handle](h H, providerType string) error {
+	const op errors.Op = "DeleteCatchupCheckpoint"
+	query := "delete from catchup_checkpoint where provider_type = ?"
+
+	switch h := any(h).(type) {
+	case *sqlx.Tx:
+		stmt, err := h.Prepare(query)
+		if err != nil {
+			return errors.E(pkg, op, errors.Database, err, "Failed to Prepare Query")
+		}
+
+		_, err = stmt.Exec(providerType)
+		if err != nil {
+			return errors.E(pkg, op, errors.Database, err, "Failed to Execute Query")
+		}
+	case *sqlx.DB:
+		_, err := h.Exec(query, providerType)
+		if err != nil {
+			return errors.E(pkg, op, errors.Database, err, "Failed to Execute Query")
+		}
+	}
+
+	return nil
+}
@@ -36,7 +36,7 @@ func CollectionTableKeys() []string {
 
 func GetCollection[H Handle](h H, id string) (*Collection, error) {
 	const op errors.Op = "GetCollection"
-	query := fmt.Sprintf("select %s from %s.collection where id = ?", strings.Join(CollectionTableKeys(), ","), arc53Database())
+	query := fmt.Sprintf("select %s from collection where id = ?", strings.Join(CollectionTableKeys(), ","))
 
 	var c Collection
 	err := h.Get(&c, query, id)
@@ -51,7 +51,7 @@ func GetCollection[H Handle](h H, id string) (*Collection, error) {
 
 func GetCollectionsByProviderID[H Handle](h H, providerID uint64) (*[]Collection, error) {
 	const op errors.Op = "GetCollectionsPaginated"
-	query := fmt.Sprintf("select %s from %s.collection where provider_id = ?", strings.Join(CollectionTableKeys(), ","), arc53Database())
+	query := fmt.Sprintf("select %s from collection where provider_id = ?", strings.Join(CollectionTableKeys(), ","))
 
 	var collections []Collection
 	err := h.Select(&collections, query, providerID)
@@ -67,7 +67,7 @@ func GetCollectionsByProviderID[H Handle](h H, providerID uint64) (*[]Collection
 
 func GetCollectionCreatorWallets[H Handle](h H, id string) (*[]ProviderAddress, error) {
 	const op errors.Op = "GetCollectionCreatorWallets"
-	query := fmt.Sprintf("select %s from %s.provider_address where id = (select provider_id from %s.collection where id = ?) and verified = 1 order by deposit desc", strings.Join(ProviderAddressTableKeys(), ","), arc53Database(), arc53Database())
+	query := fmt.Sprintf("select %s from provider_address where id = (select provider_id from collection where id = ?) and verified = 1 order by deposit desc", strings.Join(ProviderAddressTableKeys(), ","))
 
 	var wallets []ProviderAddress
 	err := h.Select(&wallets, query, id)
@@ -84,7 +84,7 @@ func GetCollectionCreatorWallets[H Handle](h H, id string) (*[]ProviderAddress,
 // GetCollectionByAssetID is a query that returns a collection by asset ID, this is used for reverse lookup when someone is viewing a specific asset
 func GetCollectionByAssetID[H Handle](h H, assetID uint64, creator string, unitName string) (*Collection, error) {
 	const op errors.Op = "GetCollectionByAssetID"
-	query := fmt.Sprintf("select %s from %s.collection where provider_id in(select id from %s.provider_address where address = ?) and ((exists(select id from %s.collection_prefix where %s.collection.id = id and left(?, char_length(prefix)) = prefix) and not exists(select id from %s.collection_excluded_asset where %s.collection.id = id and asa_id = ?)) or (exists(select id from %s.collection_asset where %s.collection.id = id and asa_id = ?)))", strings.Join(CollectionTableKeys(), ","), arc53Database(), arc53Database(), arc53Database(), arc53Database(), arc53Database(), arc53Database(), arc53Database(), arc53Database())
+	query := fmt.Sprintf("select %s from collection where provider_id in(select id from provider_address where address = ? and verified = 1) and ((exists(select id from collection_prefix where collection.id = id and left(?, char_length(prefix)) = prefix) and not exists(select id from collection_excluded_asset where collection.id = id and asa_id = ?)) or (exists(select id from collection_asset where collection.id = id and asa_id = ?)))", strings.Join(CollectionTableKeys(), ","))
 	var c Collection
 
 	err := h.Get(&c, query, creator, unitName, assetID, assetID)
@@ -100,7 +100,7 @@ func GetCollectionByAssetID[H Handle](h H, assetID uint64, creator string, unitN
 
 func GetCollectionsPaginated[H Handle](h H, start, limit uint64) (*[]Collection, error) {
 	const op errors.Op = "GetCollectionsPaginated"
-	query := fmt.Sprintf("select %s from %s.collection limit ?, ?", strings.Join(CollectionTableKeys(), ","), arc53Database())
+	query := fmt.Sprintf("select %s from collection limit ?, ?", strings.Join(CollectionTableKeys(), ","))
 
 	var collections []Collection
 	err := h.Select(&collections, query, start, limit)
@@ -116,7 +116,7 @@ func GetCollectionsPaginated[H Handle](h H, start, limit uint64) (*[]Collection,
 
 func DeleteCollectionsByProviderID[H Handle](h H, providerID uint64) error {
 	const op errors.Op = "DeleteCollectionsByProviderID"
-	query := fmt.Sprintf("delete from %s.collection where provider_id = ?", arc53Database())
+	query := "delete from collection where provider_id = ?"
 
 	switch h := any(h).(type) {
 	case *sqlx.Tx:
@@ -144,7 +144,7 @@ func DeleteCollectionNotIn[H Handle](h H, providerID uint64, ids ...string) erro
 	const op errors.Op = "DeleteCollectionNotIn"
 	var err error
 	data := append([]interface{}{providerID}, misc.ToInterfaceSlice(ids)...)
-	query := fmt.Sprintf("delete from %s.collection where provider_id = ?", arc53Database())
+	query := "delete from collection where provider_id = ?"
 
 	if len(ids) > 0 {
 		qMarks := []rune(strings.Repeat("?, ", len(ids)))
@@ -171,3 +171,26 @@ func DeleteCollectionNotIn[H Handle](h H, providerID uint64, ids ...string) erro
 
 	return nil
 }
+
+// GetCollectionsByProviderIDs is GetCollectionsByProviderID for several providers in one query
+func GetCollectionsByProviderIDs[H Handle](h H, providerIDs ...uint64) (*[]Collection, error) {
+	const op errors.Op = "GetCollectionsByProviderIDs"
+
+	collections := []Collection{}
+	if len(providerIDs) == 0 {
+		return &collections, nil
+	}
+
+	qMarks := []rune(strings.Repeat("?, ", len(providerIDs)))
+	query := fmt.Sprintf("select %s from collection where provider_id in (%s)", strings.Join(CollectionTableKeys(), ","), string(qMarks[0:len(qMarks)-2]))
+
+	err := h.Select(&collections, query, misc.ToInterfaceSlice(providerIDs)...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.E(pkg, op, errors.DatabaseResultNotFound, err, "Collections Not Found")
+		}
+		return nil, errors.E(pkg, op, err)
+	}
+
+	return &collections, nil
+}
@@ -21,7 +21,7 @@ func CollectionAddressTableKeys() []string {
 
 func GetAllCollectionAddress[H Handle](h H) (*[]CollectionAddress, error) {
 	const op errors.Op = "GetCollectionAddress"
-	query := fmt.Sprintf("select %s from %s.collection_address", strings.Join(CollectionAddressTableKeys(), ","), arc53Database())
+	query := fmt.Sprintf("select %s from collection_address", strings.Join(CollectionAddressTableKeys(), ","))
 
 	var ccs []CollectionAddress
 	err := h.Select(&ccs, query)
@@ -36,7 +36,7 @@ func GetAllCollectionAddress[H Handle](h H) (*[]CollectionAddress, error) {
 
 func GetCollectionAddresses[H Handle](h H, id string) (*[]CollectionAddress, error) {
 	const op errors.Op = "GetCollectionAddresses"
-	query := fmt.Sprintf("select %s from %s.collection_address where id = ?", strings.Join(CollectionAddressTableKeys(), ","), arc53Database())
+	query := fmt.Sprintf("select %s from collection_address where id = ?", strings.Join(CollectionAddressTableKeys(), ","))
 
 	var ccs []CollectionAddress
 	err := h.Select(&ccs, query, id)
@@ -51,7 +51,7 @@ func GetCollectionAddresses[H Handle](h H, id string) (*[]CollectionAddress, err
 
 func DeleteCollectionAddresses[H Handle](h H, id string) error {
 	const op errors.Op = "DeleteCollectionAddresses"
-	query := fmt.Sprintf("delete from %s.collection_address where id = ?", arc53Database())
+	query := "delete from collection_address where id = ?"
 
 	switch h := any(h).(type) {
 	case *sqlx.Tx:
@@ -79,7 +79,7 @@ func DeleteCollectionAddressesNotIn[H Handle](h H, id string, addresses ...strin
 	const op errors.Op = "DeleteCollectionAddressesNotIn"
 	var err error
 	data := append([]interface{}{id}, misc.ToInterfaceSlice(addresses)...)
-	query := fmt.Sprintf("delete from %s.collection_address where id = ?", arc53Database())
+	query := "delete from collection_address where id = ?"
 
 	if len(addresses) > 0 {
 		qMarks := []rune(strings.Repeat("?, ", len(addresses)))
@@ -106,3 +106,26 @@ func DeleteCollectionAddressesNotIn[H Handle](h H, id string, addresses ...strin
 
 	return nil
 }
+
+// GetCollectionAddressesByIDs is GetCollectionAddresses for several collections in one query
+func GetCollectionAddressesByIDs[H Handle](h H, ids ...string) (*[]CollectionAddress, error) {
+	const op errors.Op = "GetCollectionAddressesByIDs"
+
+	addresses := []CollectionAddress{}
+	if len(ids) == 0 {
+		return &addresses, nil
+	}
+
+	qMarks := []rune(strings.Repeat("?, ", len(ids)))
+	query := fmt.Sprintf("select %s from collection_address where id in (%s)", strings.Join(CollectionAddressTableKeys(), ","), string(qMarks[0:len(qMarks)-2]))
+
+	err := h.Select(&addresses, query, misc.ToInterfaceSlice(ids)...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.E(pkg, op, errors.DatabaseResultNotFound, err, "CollectionAddress Not Found")
+		}
+		return nil, errors.E(pkg, op, err)
+	}
+
+	return &addresses, nil
+}
@@ -21,7 +21,7 @@ func CollectionArtistTableKeys() []string {
 
 func GetCollectionArtist[H Handle](h H) (*[]CollectionArtist, error) {
 	const op errors.Op = "GetCollectionArtist"
-	query := fmt.Sprintf("select %s from %s.collection_artist", strings.Join(CollectionArtistTableKeys(), ","), arc53Database())
+	query := fmt.Sprintf("select %s from collection_artist", strings.Join(CollectionArtistTableKeys(), ","))
 
 	var ccs []CollectionArtist
 	err := h.Select(&ccs, query)
@@ -36,7 +36,7 @@ func GetCollectionArtist[H Handle](h H) (*[]CollectionArtist, error) {
 
 func GetCollectionArtistByCollection[H Handle](h H, id string) (*[]CollectionArtist, error) {
 	const op errors.Op = "GetCollectionArtistByCollection"
-	query := fmt.Sprintf("select %s from %s.collection_artist where id = ?", strings.Join(CollectionArtistTableKeys(), ","), arc53Database())
+	query := fmt.Sprintf("select %s from collection_artist where id = ?", strings.Join(CollectionArtistTableKeys(), ","))
 
 	var ccs []CollectionArtist
 	err := h.Select(&ccs, query, id)
@@ -51,7 +51,7 @@ func GetCollectionArtistByCollection[H Handle](h H, id string) (*[]CollectionArt
 
 func DeleteCollectionArtists[H Handle](h H, id string) error {
 	const op errors.Op = "DeleteCollectionArtists"
-	query := fmt.Sprintf("delete from %s.collection_artist where id = ?", arc53Database())
+	query := "delete from collection_artist where id = ?"
 
 	switch h := any(h).(type) {
 	case *sqlx.Tx:
@@ -79,7 +79,7 @@ func DeleteCollectionArtistsNotIn[H Handle](h H, id string, addresses ...string)
 	const op errors.Op = "DeleteCollectionArtistsNotIn"
 	var err error
 	data := append([]interface{}{id}, misc.ToInterfaceSlice(addresses)...)
-	query := fmt.Sprintf("delete from %s.collection_artist where id = ?", arc53Database())
+	query := "delete from collection_artist where id = ?"
 
 	if len(addresses) > 0 {
 		qMarks := []rune(strings.Repeat("?, ", len(addresses)))
@@ -106,3 +106,26 @@ func DeleteCollectionArtistsNotIn[H Handle](h H, id string, addresses ...string)
 
 	return nil
 }
+
+// GetCollectionArtistsByIDs is GetCollectionArtistByCollection for several collections in one query
+func GetCollectionArtistsByIDs[H Handle](h H, ids ...string) (*[]CollectionArtist, error) {
+	const op errors.Op = "GetCollectionArtistsByIDs"
+
+	artists := []CollectionArtist{}
+	if len(ids) == 0 {
+		return &artists, nil
+	}
+
+	qMarks := []rune(strings.Repeat("?, ", len(ids)))
+	query := fmt.Sprintf("select %s from collection_artist where id in (%s)", strings.Join(CollectionArtistTableKeys(), ","), string(qMarks[0:len(qMarks)-2]))
+
+	err := h.Select(&artists, query, misc.ToInterfaceSlice(ids)...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.E(pkg, op, errors.DatabaseResultNotFound, err, "CollectionArtist Not Found")
+		}
+		return nil, errors.E(pkg, op, err)
+	}
+
+	return &artists, nil
+}
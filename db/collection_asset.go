@@ -21,7 +21,7 @@ func CollectionAssetTableKeys() []string {
 
 func GetCollectionAssets[H Handle](h H, id string) (*[]CollectionAsset, error) {
 	const op errors.Op = "GetCollectionAssets"
-	query := fmt.Sprintf("select %s from %s.collection_asset where id = ?", strings.Join(CollectionAssetTableKeys(), ","), arc53Database())
+	query := fmt.Sprintf("select %s from collection_asset where id = ?", strings.Join(CollectionAssetTableKeys(), ","))
 
 	var assets []CollectionAsset
 	err := h.Select(&assets, query, id)
@@ -37,7 +37,7 @@ func GetCollectionAssets[H Handle](h H, id string) (*[]CollectionAsset, error) {
 
 func GetCollectionAssetByAsaID[H Handle](h H, asaID uint64) (*[]CollectionAsset, error) {
 	const op errors.Op = "GetCollectionAssetByAsaID"
-	query := fmt.Sprintf("select %s from %s.collection_asset where asa_id = ?", strings.Join(CollectionAssetTableKeys(), ","), arc53Database())
+	query := fmt.Sprintf("select %s from collection_asset where asa_id = ?", strings.Join(CollectionAssetTableKeys(), ","))
 
 	var assets []CollectionAsset
 	err := h.Select(&assets, query, asaID)
@@ -53,7 +53,7 @@ func GetCollectionAssetByAsaID[H Handle](h H, asaID uint64) (*[]CollectionAsset,
 
 func DeleteCollectionAssets[H Handle](h H, id string) error {
 	const op errors.Op = "DeleteCollectionAssets"
-	query := fmt.Sprintf("delete from %s.collection_asset where id = ?", arc53Database())
+	query := "delete from collection_asset where id = ?"
 
 	switch h := any(h).(type) {
 	case *sqlx.Tx:
@@ -81,7 +81,7 @@ func DeleteCollectionAssetsNotIn[H Handle](h H, id string, asaIDs ...uint64) err
 	const op errors.Op = "DeleteCollectionAssetsNotIn"
 	var err error
 	data := append([]interface{}{id}, misc.ToInterfaceSlice(asaIDs)...)
-	query := fmt.Sprintf("delete from %s.collection_asset where id = ?", arc53Database())
+	query := "delete from collection_asset where id = ?"
 
 	if len(asaIDs) > 0 {
 		qMarks := []rune(strings.Repeat("?, ", len(asaIDs)))
@@ -108,3 +108,26 @@ func DeleteCollectionAssetsNotIn[H Handle](h H, id string, asaIDs ...uint64) err
 
 	return nil
 }
+
+// GetCollectionAssetsByIDs is GetCollectionAssets for several collections in one query
+func GetCollectionAssetsByIDs[H Handle](h H, ids ...string) (*[]CollectionAsset, error) {
+	const op errors.Op = "GetCollectionAssetsByIDs"
+
+	assets := []CollectionAsset{}
+	if len(ids) == 0 {
+		return &assets, nil
+	}
+
+	qMarks := []rune(strings.Repeat("?, ", len(ids)))
+	query := fmt.Sprintf("select %s from collection_asset where id in (%s)", strings.Join(CollectionAssetTableKeys(), ","), string(qMarks[0:len(qMarks)-2]))
+
+	err := h.Select(&assets, query, misc.ToInterfaceSlice(ids)...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.E(pkg, op, errors.DatabaseResultNotFound, err, "CollectionAsset Not Found")
+		}
+		return nil, errors.E(pkg, op, err)
+	}
+
+	return &assets, nil
+}
@@ -21,7 +21,7 @@ func CollectionExcludedAssetTableKeys() []string {
 
 func GetCollectionExcludedAssets[H Handle](h H, id string) (*[]CollectionExcludedAsset, error) {
 	const op errors.Op = "GetCollectionExcludedAssets"
-	query := fmt.Sprintf("select %s from %s.collection_excluded_asset where id = ?", strings.Join(CollectionExcludedAssetTableKeys(), ","), arc53Database())
+	query := fmt.Sprintf("select %s from collection_excluded_asset where id = ?", strings.Join(CollectionExcludedAssetTableKeys(), ","))
 
 	var assets []CollectionExcludedAsset
 	err := h.Select(&assets, query, id)
@@ -37,7 +37,7 @@ func GetCollectionExcludedAssets[H Handle](h H, id string) (*[]CollectionExclude
 
 func GetCollectionExcludedAssetByAsaID[H Handle](h H, asaID uint64) (*[]CollectionExcludedAsset, error) {
 	const op errors.Op = "GetCollectionExcludedAssetByAsaID"
-	query := fmt.Sprintf("select %s from %s.collection_excluded_asset where asa_id = ?", strings.Join(CollectionExcludedAssetTableKeys(), ","), arc53Database())
+	query := fmt.Sprintf("select %s from collection_excluded_asset where asa_id = ?", strings.Join(CollectionExcludedAssetTableKeys(), ","))
 
 	var assets []CollectionExcludedAsset
 	err := h.Select(&assets, query, asaID)
@@ -53,7 +53,7 @@ func GetCollectionExcludedAssetByAsaID[H Handle](h H, asaID uint64) (*[]Collecti
 
 func DeleteCollectionExcludedAssets[H Handle](h H, id string) error {
 	const op errors.Op = "DeleteCollectionExcludedAssets"
-	query := fmt.Sprintf("delete from %s.collection_excluded_asset where id = ?", arc53Database())
+	query := "delete from collection_excluded_asset where id = ?"
 
 	switch h := any(h).(type) {
 	case *sqlx.Tx:
@@ -81,7 +81,7 @@ func DeleteCollectionExcludedAssetsNotIn[H Handle](h H, id string, asaIDs ...uin
 	const op errors.Op = "DeleteCollectionExcludedAssetsNotIn"
 	var err error
 	data := append([]interface{}{id}, misc.ToInterfaceSlice(asaIDs)...)
-	query := fmt.Sprintf("delete from %s.collection_excluded_asset where id = ?", arc53Database())
+	query := "delete from collection_excluded_asset where id = ?"
 
 	if len(asaIDs) > 0 {
 		qMarks := []rune(strings.Repeat("?, ", len(asaIDs)))
@@ -108,3 +108,26 @@ func DeleteCollectionExcludedAssetsNotIn[H Handle](h H, id string, asaIDs ...uin
 
 	return nil
 }
+
+// GetCollectionExcludedAssetsByIDs is GetCollectionExcludedAssets for several collections in one query
+func GetCollectionExcludedAssetsByIDs[H Handle](h H, ids ...string) (*[]CollectionExcludedAsset, error) {
+	const op errors.Op = "GetCollectionExcludedAssetsByIDs"
+
+	excludedAssets := []CollectionExcludedAsset{}
+	if len(ids) == 0 {
+		return &excludedAssets, nil
+	}
+
+	qMarks := []rune(strings.Repeat("?, ", len(ids)))
+	query := fmt.Sprintf("select %s from collection_excluded_asset where id in (%s)", strings.Join(CollectionExcludedAssetTableKeys(), ","), string(qMarks[0:len(qMarks)-2]))
+
+	err := h.Select(&excludedAssets, query, misc.ToInterfaceSlice(ids)...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.E(pkg, op, errors.DatabaseResultNotFound, err, "CollectionExcludedAsset Not Found")
+		}
+		return nil, errors.E(pkg, op, err)
+	}
+
+	return &excludedAssets, nil
+}
@@ -22,7 +22,7 @@ func CollectionExtrasTableKeys() []string {
 
 func GetCollectionExtras[H Handle](h H, id string) (*[]CollectionExtras, error) {
 	const op errors.Op = "GetCollectionExtras"
-	query := fmt.Sprintf("select %s from %s.collection_extras where id = ?", strings.Join(CollectionExtrasTableKeys(), ","), arc53Database())
+	query := fmt.Sprintf("select %s from collection_extras where id = ?", strings.Join(CollectionExtrasTableKeys(), ","))
 
 	var extras []CollectionExtras
 	err := h.Select(&extras, query, id)
@@ -38,7 +38,7 @@ func GetCollectionExtras[H Handle](h H, id string) (*[]CollectionExtras, error)
 
 func GetCollectionExtrasWithKey[H Handle](h H, id string, key string) (*CollectionExtras, error) {
 	const op errors.Op = "GetCollectionExtrasWithKey"
-	query := fmt.Sprintf("select %s from %s.collection_extras where id = ? and mkey = ?", strings.Join(CollectionExtrasTableKeys(), ","), arc53Database())
+	query := fmt.Sprintf("select %s from collection_extras where id = ? and mkey = ?", strings.Join(CollectionExtrasTableKeys(), ","))
 
 	var extra CollectionExtras
 	err := h.Get(&extra, query, id, key)
@@ -54,7 +54,7 @@ func GetCollectionExtrasWithKey[H Handle](h H, id string, key string) (*Collecti
 
 func DeleteCollectionExtras[H Handle](h H, id string) error {
 	const op errors.Op = "DeleteCollectionExtras"
-	query := fmt.Sprintf("delete from %s.collection_extras where id = ?", arc53Database())
+	query := "delete from collection_extras where id = ?"
 
 	switch h := any(h).(type) {
 	case *sqlx.Tx:
@@ -82,7 +82,7 @@ func DeleteCollectionExtrasNotIn[H Handle](h H, id string, keys ...string) error
 	const op errors.Op = "DeleteCollectionExtrasNotIn"
 	var err error
 	data := append([]interface{}{id}, misc.ToInterfaceSlice(keys)...)
-	query := fmt.Sprintf("delete from %s.collection_extras where id = ?", arc53Database())
+	query := "delete from collection_extras where id = ?"
 
 	if len(keys) > 0 {
 		qMarks := []rune(strings.Repeat("?, ", len(keys)))
@@ -109,3 +109,26 @@ func DeleteCollectionExtrasNotIn[H Handle](h H, id string, keys ...string) error
 
 	return nil
 }
+
+// GetCollectionExtrasByIDs is GetCollectionExtras for several collections in one query
+func GetCollectionExtrasByIDs[H Handle](h H, ids ...string) (*[]CollectionExtras, error) {
+	const op errors.Op = "GetCollectionExtrasByIDs"
+
+	extras := []CollectionExtras{}
+	if len(ids) == 0 {
+		return &extras, nil
+	}
+
+	qMarks := []rune(strings.Repeat("?, ", len(ids)))
+	query := fmt.Sprintf("select %s from collection_extras where id in (%s)", strings.Join(CollectionExtrasTableKeys(), ","), string(qMarks[0:len(qMarks)-2]))
+
+	err := h.Select(&extras, query, misc.ToInterfaceSlice(ids)...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.E(pkg, op, errors.DatabaseResultNotFound, err, "CollectionExtras Not Found")
+		}
+		return nil, errors.E(pkg, op, err)
+	}
+
+	return &extras, nil
+}
@@ -21,7 +21,7 @@ func CollectionPrefixTableKeys() []string {
 
 func GetCollectionPrefixes[H Handle](h H, id string) (*[]CollectionPrefix, error) {
 	const op errors.Op = "GetCollectionPrefixes"
-	query := fmt.Sprintf("select %s from %s.collection_prefix where id = ?", strings.Join(CollectionPrefixTableKeys(), ","), arc53Database())
+	query := fmt.Sprintf("select %s from collection_prefix where id = ?", strings.Join(CollectionPrefixTableKeys(), ","))
 
 	var prefixes []CollectionPrefix
 	err := h.Select(&prefixes, query, id)
@@ -37,7 +37,7 @@ func GetCollectionPrefixes[H Handle](h H, id string) (*[]CollectionPrefix, error
 
 func DeleteCollectionPrefixes[H Handle](h H, id string) error {
 	const op errors.Op = "DeleteCollectionPrefixes"
-	query := fmt.Sprintf("delete from %s.collection_prefix where id = ?", arc53Database())
+	query := "delete from collection_prefix where id = ?"
 
 	switch h := any(h).(type) {
 	case *sqlx.Tx:
@@ -65,7 +65,7 @@ func DeleteCollectionPrefixesNotIn[H Handle](h H, id string, prefixes ...string)
 	const op errors.Op = "DeleteCollectionPrefixesNotIn"
 	var err error
 	data := append([]interface{}{id}, misc.ToInterfaceSlice(prefixes)...)
-	query := fmt.Sprintf("delete from %s.collection_prefix where id = ?", arc53Database())
+	query := "delete from collection_prefix where id = ?"
 
 	if len(prefixes) > 0 {
 		qMarks := []rune(strings.Repeat("?, ", len(prefixes)))
@@ -92,3 +92,26 @@ func DeleteCollectionPrefixesNotIn[H Handle](h H, id string, prefixes ...string)
 
 	return nil
 }
+
+// GetCollectionPrefixesByIDs is GetCollectionPrefixes for several collections in one query
+func GetCollectionPrefixesByIDs[H Handle](h H, ids ...string) (*[]CollectionPrefix, error) {
+	const op errors.Op = "GetCollectionPrefixesByIDs"
+
+	prefixes := []CollectionPrefix{}
+	if len(ids) == 0 {
+		return &prefixes, nil
+	}
+
+	qMarks := []rune(strings.Repeat("?, ", len(ids)))
+	query := fmt.Sprintf("select %s from collection_prefix where id in (%s)", strings.Join(CollectionPrefixTableKeys(), ","), string(qMarks[0:len(qMarks)-2]))
+
+	err := h.Select(&prefixes, query, misc.ToInterfaceSlice(ids)...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.E(pkg, op, errors.DatabaseResultNotFound, err, "CollectionPrefix Not Found")
+		}
+		return nil, errors.E(pkg, op, err)
+	}
+
+	return &prefixes, nil
+}
@@ -19,7 +19,7 @@ func CollectionSettingsTableKeys() []string {
 
 func GetCollectionSettings[H Handle](h H, id string) (*CollectionSettings, error) {
 	const op errors.Op = "GetCollectionSettings"
-	query := fmt.Sprintf("select %s from %s.collection_settings where id = ?", strings.Join(CollectionSettingsTableKeys(), ","), arc53Database())
+	query := fmt.Sprintf("select %s from collection_settings where id = ?", strings.Join(CollectionSettingsTableKeys(), ","))
 
 	var settings CollectionSettings
 	err := h.Get(&settings, query, id)
@@ -35,7 +35,7 @@ func GetCollectionSettings[H Handle](h H, id string) (*CollectionSettings, error
 
 func GetAllCollectionSettings[H Handle](h H) (*CollectionSettings, error) {
 	const op errors.Op = "GetAllCollectionSettings"
-	query := fmt.Sprintf("select %s from %s.collection_settings", strings.Join(CollectionSettingsTableKeys(), ","), arc53Database())
+	query := fmt.Sprintf("select %s from collection_settings", strings.Join(CollectionSettingsTableKeys(), ","))
 
 	var settings CollectionSettings
 	err := h.Select(&settings, query)
@@ -20,7 +20,7 @@ func CommunityTableKeys() []string {
 
 func IsCommunity[H Handle](h H, id uint64) (bool, error) {
 	const op errors.Op = "IsCommunity"
-	query := fmt.Sprintf("select exists(select id from %v.community where id = ?)", arc53Database())
+	query := "select exists(select id from community where id = ?)"
 
 	var exists bool
 	err := h.Get(&exists, query, id)
@@ -36,7 +36,7 @@ func IsCommunity[H Handle](h H, id uint64) (bool, error) {
 
 func GetCommunities[H Handle](h H, start, limit uint64) (*[]Community, error) {
 	const op errors.Op = "GetCommunities"
-	query := fmt.Sprintf("select %v from %v.community order by akta desc limit ?, ?", strings.Join(CommunityTableKeys(), ","), arc53Database())
+	query := fmt.Sprintf("select %v from community order by akta desc limit ?, ?", strings.Join(CommunityTableKeys(), ","))
 
 	var communities []Community
 	err := h.Select(&communities, query, start, limit)
@@ -51,7 +51,7 @@ func GetCommunities[H Handle](h H, start, limit uint64) (*[]Community, error) {
 
 func GetAllCommunityVerifiedAddresses[H Handle](h H) ([]string, error) {
 	const op errors.Op = "GetAllCommunityVerifiedAddresses"
-	query := fmt.Sprintf("select address from %v.provider_address where id in (select id from %v.community)", arc53Database(), arc53Database())
+	query := "select address from provider_address where id in (select id from community) and verified = 1"
 
 	var wallets []string
 	err := h.Select(&wallets, query)
@@ -66,7 +66,7 @@ func GetAllCommunityVerifiedAddresses[H Handle](h H) ([]string, error) {
 
 func GetCommunity[H Handle](h H, id uint64) (*Community, error) {
 	const op errors.Op = "GetCommunity"
-	query := fmt.Sprintf("select %v from %v.community where id = ?", strings.Join(CommunityTableKeys(), ","), arc53Database())
+	query := fmt.Sprintf("select %v from community where id = ?", strings.Join(CommunityTableKeys(), ","))
 
 	var community Community
 	err := h.Get(&community, query, id)
@@ -82,7 +82,7 @@ func GetCommunity[H Handle](h H, id uint64) (*Community, error) {
 
 func DeleteCommunity[H Handle](h H, id uint64) error {
 	const op errors.Op = "DeleteCommunity"
-	query := fmt.Sprintf("delete from %v.community where id = ?", arc53Database())
+	query := "delete from community where id = ?"
 
 	switch h := any(h).(type) {
 	case *sqlx.Tx:
@@ -24,7 +24,7 @@ func CommunityAssociateTableKeys() []string {
 
 func GetCommunityAssociates[H Handle](h H, id uint64) (*[]CommunityAssociate, error) {
 	const op errors.Op = "GetCommunityAssociates"
-	query := fmt.Sprintf("select %s from %s.community_associate where id = ?", strings.Join(CommunityAssociateTableKeys(), ","), arc53Database())
+	query := fmt.Sprintf("select %s from community_associate where id = ?", strings.Join(CommunityAssociateTableKeys(), ","))
 
 	var associates []CommunityAssociate
 	err := h.Select(&associates, query, id)
@@ -40,7 +40,7 @@ func GetCommunityAssociates[H Handle](h H, id uint64) (*[]CommunityAssociate, er
 
 func GetCommunityAssociate[H Handle](h H, id uint64, address string) (*CommunityAssociate, error) {
 	const op errors.Op = "GetCommunityAssociate"
-	query := fmt.Sprintf("select %s from %s.community_associate where id = ? and address = ?", strings.Join(CommunityAssociateTableKeys(), ","), arc53Database())
+	query := fmt.Sprintf("select %s from community_associate where id = ? and address = ?", strings.Join(CommunityAssociateTableKeys(), ","))
 
 	var associate CommunityAssociate
 	err := h.Get(&associate, query, id, address)
@@ -56,7 +56,7 @@ func GetCommunityAssociate[H Handle](h H, id uint64, address string) (*Community
 
 func GetCommunityAssociateByAddress[H Handle](h H, address string) (*[]CommunityAssociate, error) {
 	const op errors.Op = "GetCommunityAssociateByAddress"
-	query := fmt.Sprintf("select %s from %s.community_associate where address = ?", strings.Join(CommunityAssociateTableKeys(), ","), arc53Database())
+	query := fmt.Sprintf("select %s from community_associate where address = ?", strings.Join(CommunityAssociateTableKeys(), ","))
 
 	var associates []CommunityAssociate
 	err := h.Select(&associates, query, address)
@@ -72,7 +72,7 @@ func GetCommunityAssociateByAddress[H Handle](h H, address string) (*[]Community
 
 func DeleteCommunityAssociate[H Handle](h H, id uint64, address string) error {
 	const op errors.Op = "DeleteCommunityAssociate"
-	query := fmt.Sprintf("delete from %s.community_associate where id = ? and address = ?", arc53Database())
+	query := "delete from community_associate where id = ? and address = ?"
 
 	switch h := any(h).(type) {
 	case *sqlx.Tx:
@@ -95,7 +95,7 @@ func DeleteCommunityAssociatesNotIn[H Handle](h H, id uint64, addresses ...strin
 	const op errors.Op = "DeleteCommunityAssociatesNotIn"
 	var err error
 	data := append([]interface{}{id}, misc.ToInterfaceSlice(addresses)...)
-	query := fmt.Sprintf("delete from %s.community_associate where id = ?", arc53Database())
+	query := "delete from community_associate where id = ?"
 
 	if len(addresses) > 0 {
 		qMarks := []rune(strings.Repeat("?, ", len(addresses)))
@@ -122,3 +122,26 @@ func DeleteCommunityAssociatesNotIn[H Handle](h H, id uint64, addresses ...strin
 
 	return nil
 }
+
+// GetCommunityAssociatesByIDs is GetCommunityAssociates for several communities in one query
+func GetCommunityAssociatesByIDs[H Handle](h H, ids ...uint64) (*[]CommunityAssociate, error) {
+	const op errors.Op = "GetCommunityAssociatesByIDs"
+
+	associates := []CommunityAssociate{}
+	if len(ids) == 0 {
+		return &associates, nil
+	}
+
+	qMarks := []rune(strings.Repeat("?, ", len(ids)))
+	query := fmt.Sprintf("select %s from community_associate where id in (%s)", strings.Join(CommunityAssociateTableKeys(), ","), string(qMarks[0:len(qMarks)-2]))
+
+	err := h.Select(&associates, query, misc.ToInterfaceSlice(ids)...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.E(pkg, op, errors.DatabaseResultNotFound, err, "CommunityAssociate Not Found")
+		}
+		return nil, errors.E(pkg, op, err)
+	}
+
+	return &associates, nil
+}
@@ -22,7 +22,7 @@ func CommunityExtrasTableKeys() []string {
 
 func GetCommunityExtras[H Handle](h H, id uint64) (*[]CommunityExtras, error) {
 	const op errors.Op = "GetCommunityExtras"
-	query := fmt.Sprintf("select %s from %s.community_extras where id = ?", strings.Join(CommunityExtrasTableKeys(), ","), arc53Database())
+	query := fmt.Sprintf("select %s from community_extras where id = ?", strings.Join(CommunityExtrasTableKeys(), ","))
 
 	var extras []CommunityExtras
 	err := h.Select(&extras, query, id)
@@ -38,7 +38,7 @@ func GetCommunityExtras[H Handle](h H, id uint64) (*[]CommunityExtras, error) {
 
 func GetCommunityExtrasWithKey[H Handle](h H, id uint64, key string) (*CommunityExtras, error) {
 	const op errors.Op = "GetCommunityExtrasWithKey"
-	query := fmt.Sprintf("select %s from %s.community_extras where id = ? and mkey = ?", strings.Join(CommunityExtrasTableKeys(), ","), arc53Database())
+	query := fmt.Sprintf("select %s from community_extras where id = ? and mkey = ?", strings.Join(CommunityExtrasTableKeys(), ","))
 
 	var extra CommunityExtras
 	err := h.Get(&extra, query, id, key)
@@ -54,7 +54,7 @@ func GetCommunityExtrasWithKey[H Handle](h H, id uint64, key string) (*Community
 
 func DeleteCommunityExtras[H Handle](h H, id uint64) error {
 	const op errors.Op = "DeleteCommunityExtras"
-	query := fmt.Sprintf("delete from %s.community_extras where id = ?", arc53Database())
+	query := "delete from community_extras where id = ?"
 
 	switch h := any(h).(type) {
 	case *sqlx.Tx:
@@ -82,7 +82,7 @@ func DeleteCommunityExtrasNotIn[H Handle](h H, id uint64, keys ...string) error
 	const op errors.Op = "DeleteCommunityExtrasNotIn"
 	var err error
 	data := append([]interface{}{id}, misc.ToInterfaceSlice(keys)...)
-	query := fmt.Sprintf("delete from %s.community_extras where id = ?", arc53Database())
+	query := "delete from community_extras where id = ?"
 
 	if len(keys) > 0 {
 		qMarks := []rune(strings.Repeat("?, ", len(keys)))
@@ -109,3 +109,26 @@ func DeleteCommunityExtrasNotIn[H Handle](h H, id uint64, keys ...string) error
 
 	return nil
 }
+
+// GetCommunityExtrasByIDs is GetCommunityExtras for several communities in one query
+func GetCommunityExtrasByIDs[H Handle](h H, ids ...uint64) (*[]CommunityExtras, error) {
+	const op errors.Op = "GetCommunityExtrasByIDs"
+
+	extras := []CommunityExtras{}
+	if len(ids) == 0 {
+		return &extras, nil
+	}
+
+	qMarks := []rune(strings.Repeat("?, ", len(ids)))
+	query := fmt.Sprintf("select %s from community_extras where id in (%s)", strings.Join(CommunityExtrasTableKeys(), ","), string(qMarks[0:len(qMarks)-2]))
+
+	err := h.Select(&extras, query, misc.ToInterfaceSlice(ids)...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.E(pkg, op, errors.DatabaseResultNotFound, err, "CommunityExtras Not Found")
+		}
+		return nil, errors.E(pkg, op, err)
+	}
+
+	return &extras, nil
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/jmoiron/sqlx"
 	"github.com/kylebeee/arc53-watcher-go/errors"
+	"github.com/kylebeee/arc53-watcher-go/misc"
 )
 
 type CommunityFaq struct {
@@ -22,7 +23,7 @@ func CommunityFaqTableKeys() []string {
 
 func GetCommunityFaq[H Handle](h H, id, start, limit uint64) (*[]CommunityFaq, error) {
 	const op errors.Op = "GetCommunityFaq"
-	query := fmt.Sprintf("select %s from %s.community_faq where id = ? order by ordering asc limit ?, ?", strings.Join(CommunityFaqTableKeys(), ","), arc53Database())
+	query := fmt.Sprintf("select %s from community_faq where id = ? order by ordering asc limit ?, ?", strings.Join(CommunityFaqTableKeys(), ","))
 
 	var faq []CommunityFaq
 	err := h.Select(&faq, query, id, start, limit)
@@ -38,7 +39,7 @@ func GetCommunityFaq[H Handle](h H, id, start, limit uint64) (*[]CommunityFaq, e
 
 func DeleteCommunityFaq[H Handle](h H, id uint64) error {
 	const op errors.Op = "DeleteCommunityFaq"
-	query := fmt.Sprintf("delete from %s.community_faq where id = ?", arc53Database())
+	query := "delete from community_faq where id = ?"
 	var err error
 
 	switch h := any(h).(type) {
@@ -61,3 +62,26 @@ func DeleteCommunityFaq[H Handle](h H, id uint64) error {
 
 	return nil
 }
+
+// GetCommunityFaqByIDs is GetCommunityFaq for several communities in one query, without paging & ordered by community
+func GetCommunityFaqByIDs[H Handle](h H, ids ...uint64) (*[]CommunityFaq, error) {
+	const op errors.Op = "GetCommunityFaqByIDs"
+
+	faq := []CommunityFaq{}
+	if len(ids) == 0 {
+		return &faq, nil
+	}
+
+	qMarks := []rune(strings.Repeat("?, ", len(ids)))
+	query := fmt.Sprintf("select %s from community_faq where id in (%s) order by id asc, ordering asc", strings.Join(CommunityFaqTableKeys(), ","), string(qMarks[0:len(qMarks)-2]))
+
+	err := h.Select(&faq, query, misc.ToInterfaceSlice(ids)...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.E(pkg, op, errors.DatabaseResultNotFound, err, "CommunityFaq Not Found")
+		}
+		return nil, errors.E(pkg, op, err)
+	}
+
+	return &faq, nil
+}
@@ -13,15 +13,21 @@ type CommunityJson struct {
 	ID        uint64 `structs:"id,omitempty" db:"id" json:"id,omitempty"`
 	Data      string `structs:"data,omitempty" db:"data" json:"data,omitempty"`
 	Malformed *bool  `structs:"malformed,omitempty" db:"malformed" json:"malformed,omitempty"`
+	// CIDMismatch is set when the content served for an ipfs:// url didn't match its CID,
+	// Data is left as the last content that did verify
+	CIDMismatch *bool `structs:"cid_mismatch,omitempty" db:"cid_mismatch" json:"cid_mismatch,omitempty"`
+	// ValidationErrors is a json array of the schema violations found in Data,
+	// "[]" once a document validates
+	ValidationErrors *string `structs:"validation_errors,omitempty" db:"validation_errors" json:"validation_errors,omitempty"`
 }
 
 func CommunityJsonTableKeys() []string {
-	return []string{"id", "data", "malformed"}
+	return []string{"id", "data", "malformed", "cid_mismatch", "validation_errors"}
 }
 
 func GetCommunityJson[H Handle](h H, id uint64) (*CommunityJson, error) {
 	const op errors.Op = "GetCollectionJson"
-	query := fmt.Sprintf("select %s from %s.community_json where id = ?", strings.Join(CommunityJsonTableKeys(), ","), arc53Database())
+	query := fmt.Sprintf("select %s from community_json where id = ?", strings.Join(CommunityJsonTableKeys(), ","))
 
 	var json CommunityJson
 	err := h.Get(&json, query, id)
@@ -37,7 +43,7 @@ func GetCommunityJson[H Handle](h H, id uint64) (*CommunityJson, error) {
 
 func DeleteCommunityJson[H Handle](h H, id uint64) error {
 	const op errors.Op = "DeleteCommunityJson"
-	query := fmt.Sprintf("delete from %s.community_json where id = ?", arc53Database())
+	query := "delete from community_json where id = ?"
 
 	switch h := any(h).(type) {
 	case *sqlx.Tx:
@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"github.com/kylebeee/arc53-watcher-go/errors"
+	"github.com/kylebeee/arc53-watcher-go/misc"
 )
 
 var DefaultUserTabList = []string{
@@ -45,7 +46,7 @@ func CommunitySettingsTableKeys() []string {
 
 func GetCommunitySettings[H Handle](h H, id uint64) (*CommunitySettings, error) {
 	const op errors.Op = "GetCommunitySettings"
-	query := fmt.Sprintf("select %s from %s.community_settings where id = ?", strings.Join(CommunitySettingsTableKeys(), ","), arc53Database())
+	query := fmt.Sprintf("select %s from community_settings where id = ?", strings.Join(CommunitySettingsTableKeys(), ","))
 
 	var settings CommunitySettings
 	err := h.Get(&settings, query, id)
@@ -61,7 +62,7 @@ func GetCommunitySettings[H Handle](h H, id uint64) (*CommunitySettings, error)
 
 func GetAllCommunitySettings[H Handle](h H) (*CommunitySettings, error) {
 	const op errors.Op = "GetAllCommunitySettings"
-	query := fmt.Sprintf("select %s from %s.community_settings", strings.Join(CommunitySettingsTableKeys(), ","), arc53Database())
+	query := fmt.Sprintf("select %s from community_settings", strings.Join(CommunitySettingsTableKeys(), ","))
 
 	var settings CommunitySettings
 	err := h.Select(&settings, query)
@@ -74,3 +75,26 @@ func GetAllCommunitySettings[H Handle](h H) (*CommunitySettings, error) {
 
 	return &settings, nil
 }
+
+// GetCommunitySettingsByIDs is GetCommunitySettings for several communities in one query
+func GetCommunitySettingsByIDs[H Handle](h H, ids ...uint64) (*[]CommunitySettings, error) {
+	const op errors.Op = "GetCommunitySettingsByIDs"
+
+	settings := []CommunitySettings{}
+	if len(ids) == 0 {
+		return &settings, nil
+	}
+
+	qMarks := []rune(strings.Repeat("?, ", len(ids)))
+	query := fmt.Sprintf("select %s from community_settings where id in (%s)", strings.Join(CommunitySettingsTableKeys(), ","), string(qMarks[0:len(qMarks)-2]))
+
+	err := h.Select(&settings, query, misc.ToInterfaceSlice(ids)...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.E(pkg, op, errors.DatabaseResultNotFound, err, "Community Settings Not Found")
+		}
+		return nil, errors.E(pkg, op, err)
+	}
+
+	return &settings, nil
+}
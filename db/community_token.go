@@ -24,7 +24,7 @@ func CommunityTokenTableKeys() []string {
 
 func GetCommunityTokens[H Handle](h H, id uint64) (*[]CommunityToken, error) {
 	const op errors.Op = "GetCommunityTokens"
-	query := fmt.Sprintf("select %s from %s.community_token where id = ?", strings.Join(CommunityTokenTableKeys(), ","), arc53Database())
+	query := fmt.Sprintf("select %s from community_token where id = ?", strings.Join(CommunityTokenTableKeys(), ","))
 
 	var assets []CommunityToken
 	err := h.Select(&assets, query, id)
@@ -40,7 +40,7 @@ func GetCommunityTokens[H Handle](h H, id uint64) (*[]CommunityToken, error) {
 
 func GetCommunityTokenByAsaID[H Handle](h H, asaID uint64) (*CommunityToken, error) {
 	const op errors.Op = "GetCommunityTokenByAsaID"
-	query := fmt.Sprintf("select %s from %s.community_token where asset_id = ?", strings.Join(CommunityTokenTableKeys(), ","), arc53Database())
+	query := fmt.Sprintf("select %s from community_token where asset_id = ?", strings.Join(CommunityTokenTableKeys(), ","))
 
 	var asset CommunityToken
 	err := h.Get(&asset, query, asaID)
@@ -56,7 +56,7 @@ func GetCommunityTokenByAsaID[H Handle](h H, asaID uint64) (*CommunityToken, err
 
 func DeleteCommunityTokens[H Handle](h H, id uint64) error {
 	const op errors.Op = "DeleteCommunityTokens"
-	query := fmt.Sprintf("delete from %s.community_token where id = ?", arc53Database())
+	query := "delete from community_token where id = ?"
 
 	switch h := any(h).(type) {
 	case *sqlx.Tx:
@@ -84,7 +84,7 @@ func DeleteCommunityTokensNotIn[H Handle](h H, id uint64, asaIDs ...uint64) erro
 	const op errors.Op = "DeleteCommunityTokensNotIn"
 	var err error
 	data := append([]interface{}{id}, misc.ToInterfaceSlice(asaIDs)...)
-	query := fmt.Sprintf("delete from %s.community_token where id = ?", arc53Database())
+	query := "delete from community_token where id = ?"
 
 	if len(asaIDs) > 0 {
 		qMarks := []rune(strings.Repeat("?, ", len(asaIDs)))
@@ -111,3 +111,26 @@ func DeleteCommunityTokensNotIn[H Handle](h H, id uint64, asaIDs ...uint64) erro
 
 	return nil
 }
+
+// GetCommunityTokensByIDs is GetCommunityTokens for several communities in one query
+func GetCommunityTokensByIDs[H Handle](h H, ids ...uint64) (*[]CommunityToken, error) {
+	const op errors.Op = "GetCommunityTokensByIDs"
+
+	tokens := []CommunityToken{}
+	if len(ids) == 0 {
+		return &tokens, nil
+	}
+
+	qMarks := []rune(strings.Repeat("?, ", len(ids)))
+	query := fmt.Sprintf("select %s from community_token where id in (%s)", strings.Join(CommunityTokenTableKeys(), ","), string(qMarks[0:len(qMarks)-2]))
+
+	err := h.Select(&tokens, query, misc.ToInterfaceSlice(ids)...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.E(pkg, op, errors.DatabaseResultNotFound, err, "CommunityToken Not Found")
+		}
+		return nil, errors.E(pkg, op, err)
+	}
+
+	return &tokens, nil
+}
@@ -54,166 +54,199 @@ func GetCollectionsByProviderID[H db.Handle](h H, providerID uint64, exclude ...
 		return nil, errors.E(op, err)
 	}
 
+	exclude = misc.UniqueSlice(exclude)
 	for i := range *cols {
 		col := (*cols)[i]
-		compCollection := Collection{
-			Collection: &col,
-		}
-		buffer := (len(allCollectionGetExcludes) - len(exclude))
-		if buffer == 0 {
-			continue
+		compCollection, err := getCollectionDetails(h, &col, exclude)
+		if err != nil {
+			return nil, errors.E(op, err)
 		}
+		collections = append(collections, *compCollection)
+	}
 
-		rChan := make(chan interface{}, buffer)
-		defer close(rChan)
-
-		if !misc.InSlice(CollectionGetExcludePrefixes, exclude) {
-			go func() {
-				prefixes, err := db.GetCollectionPrefixes(h, col.ID)
-				if err != nil && !db.ErrNoRows(err) {
-					rChan <- err
-					return
-				}
-				rChan <- prefixes
-			}()
-		}
+	return &collections, nil
+}
 
-		if !misc.InSlice(CollectionGetExcludeAddresses, exclude) {
-			go func() {
-				addresses, err := db.GetCollectionAddresses(h, col.ID)
-				if err != nil && !db.ErrNoRows(err) {
-					rChan <- err
-					return
-				}
-				rChan <- addresses
-			}()
-		}
+// GetCollection returns a single collection with its details by collection ID
+func GetCollection[H db.Handle](h H, id string, exclude ...CollectionGetExclude) (*Collection, error) {
+	const op errors.Op = "GetCollection"
 
-		if !misc.InSlice(CollectionGetExcludeAssets, exclude) {
-			go func() {
-				assets, err := db.GetCollectionAssets(h, col.ID)
-				if err != nil && !db.ErrNoRows(err) {
-					rChan <- err
-					return
-				}
-				rChan <- assets
-			}()
-		}
+	col, err := db.GetCollection(h, id)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
 
-		if !misc.InSlice(CollectionGetExcludeExcludedAssets, exclude) {
-			go func() {
-				excludedAssets, err := db.GetCollectionExcludedAssets(h, col.ID)
-				if err != nil && !db.ErrNoRows(err) {
-					rChan <- err
-					return
-				}
-				rChan <- excludedAssets
-			}()
-		}
+	compCollection, err := getCollectionDetails(h, col, misc.UniqueSlice(exclude))
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
 
-		if !misc.InSlice(CollectionGetExcludeArtists, exclude) {
-			go func() {
-				artists, err := db.GetCollectionArtistByCollection(h, col.ID)
-				if err != nil && !db.ErrNoRows(err) {
-					rChan <- err
-					return
-				}
-				rChan <- artists
-			}()
-		}
+	return compCollection, nil
+}
 
-		if !misc.InSlice(CollectionGetExcludeProperties, exclude) {
-			go func() {
-				properties, err := GetProperties(h, col.ID)
-				if err != nil && !db.ErrNoRows(err) {
-					rChan <- err
-					return
-				}
-				rChan <- properties
-			}()
-		}
+func getCollectionDetails[H db.Handle](h H, col *db.Collection, exclude []CollectionGetExclude) (*Collection, error) {
+	const op errors.Op = "getCollectionDetails"
 
-		if !misc.InSlice(CollectionGetExcludeExtras, exclude) {
-			go func() {
-				extras, err := db.GetCollectionExtras(h, col.ID)
-				if err != nil && !db.ErrNoRows(err) {
-					rChan <- err
-					return
-				}
-				rChan <- extras
-			}()
-		}
+	compCollection := Collection{
+		Collection: col,
+	}
+	buffer := (len(allCollectionGetExcludes) - len(exclude))
+	if buffer == 0 {
+		return &compCollection, nil
+	}
 
-		var errs []error
-		for i := 0; i < buffer; i++ {
-			data := <-rChan
-			switch result := data.(type) {
-			case *[]db.CollectionPrefix:
-				prefixes := []string{}
-				for i := range *result {
-					prefix := (*result)[i]
-					prefixes = append(prefixes, prefix.Prefix)
-				}
-				compCollection.Prefixes = prefixes
-			case *[]db.CollectionAddress:
-				addresses := []string{}
-				for i := range *result {
-					address := (*result)[i]
-					addresses = append(addresses, address.Address)
-				}
-				compCollection.Addresses = addresses
-			case *[]db.CollectionAsset:
-				assets := []uint64{}
-				for i := range *result {
-					asset := (*result)[i]
-					assets = append(assets, asset.AsaID)
-				}
-				compCollection.Assets = assets
-			case *[]db.CollectionExcludedAsset:
-				assets := []uint64{}
-				for i := range *result {
-					asset := (*result)[i]
-					assets = append(assets, asset.AsaID)
-				}
-				compCollection.ExcludedAssets = assets
-			case *[]db.CollectionArtist:
-				artists := []string{}
-				for i := range *result {
-					artist := (*result)[i]
-					artists = append(artists, artist.Address)
-				}
-				compCollection.Artists = artists
-			case *[]Property:
-				compCollection.Properties = *result
-			case *[]db.CollectionExtras:
-				extras := map[string]string{}
-				for i := range *result {
-					extra := (*result)[i]
-					extras[extra.Key] = extra.Value
-				}
-
-				compCollection.Extras = extras
-			case error:
-				errs = append(errs, result)
-			default:
-				fmt.Println(op, "defaulted")
-				fmt.Println(result)
+	rChan := make(chan interface{}, buffer)
+	defer close(rChan)
+
+	if !misc.InSlice(CollectionGetExcludePrefixes, exclude) {
+		go func() {
+			prefixes, err := db.GetCollectionPrefixes(h, col.ID)
+			if err != nil && !db.ErrNoRows(err) {
+				rChan <- err
+				return
 			}
-		}
+			rChan <- prefixes
+		}()
+	}
+
+	if !misc.InSlice(CollectionGetExcludeAddresses, exclude) {
+		go func() {
+			addresses, err := db.GetCollectionAddresses(h, col.ID)
+			if err != nil && !db.ErrNoRows(err) {
+				rChan <- err
+				return
+			}
+			rChan <- addresses
+		}()
+	}
+
+	if !misc.InSlice(CollectionGetExcludeAssets, exclude) {
+		go func() {
+			assets, err := db.GetCollectionAssets(h, col.ID)
+			if err != nil && !db.ErrNoRows(err) {
+				rChan <- err
+				return
+			}
+			rChan <- assets
+		}()
+	}
+
+	if !misc.InSlice(CollectionGetExcludeExcludedAssets, exclude) {
+		go func() {
+			excludedAssets, err := db.GetCollectionExcludedAssets(h, col.ID)
+			if err != nil && !db.ErrNoRows(err) {
+				rChan <- err
+				return
+			}
+			rChan <- excludedAssets
+		}()
+	}
+
+	if !misc.InSlice(CollectionGetExcludeArtists, exclude) {
+		go func() {
+			artists, err := db.GetCollectionArtistByCollection(h, col.ID)
+			if err != nil && !db.ErrNoRows(err) {
+				rChan <- err
+				return
+			}
+			rChan <- artists
+		}()
+	}
+
+	if !misc.InSlice(CollectionGetExcludeProperties, exclude) {
+		go func() {
+			properties, err := GetProperties(h, col.ID)
+			if err != nil && !db.ErrNoRows(err) {
+				rChan <- err
+				return
+			}
+			rChan <- properties
+		}()
+	}
 
-		if len(errs) > 0 {
+	if !misc.InSlice(CollectionGetExcludeExtras, exclude) {
+		go func() {
+			extras, err := db.GetCollectionExtras(h, col.ID)
+			if err != nil && !db.ErrNoRows(err) {
+				rChan <- err
+				return
+			}
+			rChan <- extras
+		}()
+	}
 
-			msg := ""
-			for _, err := range errs {
-				msg += err.Error() + "\n"
+	var errs []error
+	for i := 0; i < buffer; i++ {
+		data := <-rChan
+		switch result := data.(type) {
+		case *[]db.CollectionPrefix:
+			prefixes := []string{}
+			for i := range *result {
+				prefix := (*result)[i]
+				prefixes = append(prefixes, prefix.Prefix)
+			}
+			compCollection.Prefixes = prefixes
+		case *[]db.CollectionAddress:
+			addresses := []string{}
+			for i := range *result {
+				address := (*result)[i]
+				addresses = append(addresses, address.Address)
+			}
+			compCollection.Addresses = addresses
+		case *[]db.CollectionAsset:
+			assets := []uint64{}
+			for i := range *result {
+				asset := (*result)[i]
+				assets = append(assets, asset.AsaID)
+			}
+			compCollection.Assets = assets
+		case *[]db.CollectionExcludedAsset:
+			assets := []uint64{}
+			for i := range *result {
+				asset := (*result)[i]
+				assets = append(assets, asset.AsaID)
+			}
+			compCollection.ExcludedAssets = assets
+		case *[]db.CollectionArtist:
+			artists := []string{}
+			for i := range *result {
+				artist := (*result)[i]
+				artists = append(artists, artist.Address)
+			}
+			compCollection.Artists = artists
+		case *[]Property:
+			compCollection.Properties = *result
+		case *[]db.CollectionExtras:
+			extras := map[string]string{}
+			for i := range *result {
+				extra := (*result)[i]
+				extras[extra.Key] = extra.Value
 			}
 
-			return nil, errors.E(op, fmt.Errorf(msg))
+			compCollection.Extras = extras
+		case error:
+			errs = append(errs, result)
+		default:
+			fmt.Println(op, "defaulted")
+			fmt.Println(result)
 		}
-		collections = append(collections, compCollection)
 	}
 
-	return &collections, nil
+	if len(errs) > 0 {
+		msg := ""
+		for _, err := range errs {
+			msg += err.Error() + "\n"
+		}
+
+		return nil, errors.E(op, fmt.Errorf(msg))
+	}
+
+	return &compCollection, nil
+}
+
+// Valid reports whether e is a known collection exclude option
+func (e CollectionGetExclude) Valid() bool {
+	return misc.InSlice(e, allCollectionGetExcludes)
 }
 
 func GetCollectionCriteria[H db.Handle](h H, collectionID string) (*Collection, []string, error) {
@@ -324,3 +357,115 @@ func GetCollectionCriteria[H db.Handle](h H, collectionID string) (*Collection,
 
 	return &collection, creators, nil
 }
+
+// GetCollectionsByProviderIDs returns the collections of several providers with their details keyed by
+// provider ID, each detail is read for every collection in one query rather than once per collection
+func GetCollectionsByProviderIDs[H db.Handle](h H, providerIDs []uint64, exclude ...CollectionGetExclude) (map[uint64][]Collection, error) {
+	const op errors.Op = "GetCollectionsByProviderIDs"
+
+	cols, err := db.GetCollectionsByProviderIDs(h, providerIDs...)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	ids := []string{}
+	for i := range *cols {
+		ids = append(ids, (*cols)[i].ID)
+	}
+
+	exclude = misc.UniqueSlice(exclude)
+	prefixes := map[string][]string{}
+	addresses := map[string][]string{}
+	assets := map[string][]uint64{}
+	excludedAssets := map[string][]uint64{}
+	artists := map[string][]string{}
+	extras := map[string]map[string]string{}
+	properties := map[string][]Property{}
+
+	if !misc.InSlice(CollectionGetExcludePrefixes, exclude) {
+		rows, err := db.GetCollectionPrefixesByIDs(h, ids...)
+		if err != nil {
+			return nil, errors.E(op, err)
+		}
+		for _, row := range *rows {
+			prefixes[row.ID] = append(prefixes[row.ID], row.Prefix)
+		}
+	}
+
+	if !misc.InSlice(CollectionGetExcludeAddresses, exclude) {
+		rows, err := db.GetCollectionAddressesByIDs(h, ids...)
+		if err != nil {
+			return nil, errors.E(op, err)
+		}
+		for _, row := range *rows {
+			addresses[row.ID] = append(addresses[row.ID], row.Address)
+		}
+	}
+
+	if !misc.InSlice(CollectionGetExcludeAssets, exclude) {
+		rows, err := db.GetCollectionAssetsByIDs(h, ids...)
+		if err != nil {
+			return nil, errors.E(op, err)
+		}
+		for _, row := range *rows {
+			assets[row.ID] = append(assets[row.ID], row.AsaID)
+		}
+	}
+
+	if !misc.InSlice(CollectionGetExcludeExcludedAssets, exclude) {
+		rows, err := db.GetCollectionExcludedAssetsByIDs(h, ids...)
+		if err != nil {
+			return nil, errors.E(op, err)
+		}
+		for _, row := range *rows {
+			excludedAssets[row.ID] = append(excludedAssets[row.ID], row.AsaID)
+		}
+	}
+
+	if !misc.InSlice(CollectionGetExcludeArtists, exclude) {
+		rows, err := db.GetCollectionArtistsByIDs(h, ids...)
+		if err != nil {
+			return nil, errors.E(op, err)
+		}
+		for _, row := range *rows {
+			artists[row.ID] = append(artists[row.ID], row.Address)
+		}
+	}
+
+	if !misc.InSlice(CollectionGetExcludeExtras, exclude) {
+		rows, err := db.GetCollectionExtrasByIDs(h, ids...)
+		if err != nil {
+			return nil, errors.E(op, err)
+		}
+		for _, row := range *rows {
+			if extras[row.ID] == nil {
+				extras[row.ID] = map[string]string{}
+			}
+			extras[row.ID][row.Key] = row.Value
+		}
+	}
+
+	if !misc.InSlice(CollectionGetExcludeProperties, exclude) {
+		properties, err = GetPropertiesByCollectionIDs(h, ids...)
+		if err != nil {
+			return nil, errors.E(op, err)
+		}
+	}
+
+	collections := map[uint64][]Collection{}
+	for i := range *cols {
+		col := (*cols)[i]
+		collections[col.ProviderID] = append(collections[col.ProviderID], Collection{
+			Collection:     &col,
+			Prefixes:       prefixes[col.ID],
+			Addresses:      addresses[col.ID],
+			Assets:         assets[col.ID],
+			ExcludedAssets: excludedAssets[col.ID],
+			Artists:        artists[col.ID],
+			Properties:     properties[col.ID],
+			Extras:         extras[col.ID],
+		})
+	}
+
+	return collections, nil
+}
@@ -30,10 +30,28 @@ const (
 	CommunityGetExcludeExtras      CommunityGetExclude = "extras"
 )
 
+var allCommunityGetExcludes = []CommunityGetExclude{
+	CommunityGetExcludeSettings,
+	CommunityGetExcludeTokens,
+	CommunityGetExcludeAssociates,
+	CommunityGetExcludeCollections,
+	CommunityGetExcludeFaq,
+	CommunityGetExcludeExtras,
+}
+
+// communityFaqLimit is how many faq entries come with a community
+const communityFaqLimit = 10
+
+// Valid reports whether e is a known community exclude option
+func (e CommunityGetExclude) Valid() bool {
+	return misc.InSlice(e, allCommunityGetExcludes)
+}
+
 func GetCommunity[H db.Handle](h H, providerID uint64, exclude ...CommunityGetExclude) (*Community, error) {
 	const op errors.Op = "GetCommunity"
 	var community Community
-	buffer := (7 - len(exclude))
+	exclude = misc.UniqueSlice(exclude)
+	buffer := (len(allCommunityGetExcludes) + 1 - len(exclude))
 	rChan := make(chan interface{}, buffer)
 	defer close(rChan)
 
@@ -95,7 +113,7 @@ func GetCommunity[H db.Handle](h H, providerID uint64, exclude ...CommunityGetEx
 
 	if !misc.InSlice(CommunityGetExcludeFaq, exclude) {
 		go func() {
-			faq, err := db.GetCommunityFaq(h, providerID, 0, 10)
+			faq, err := db.GetCommunityFaq(h, providerID, 0, communityFaqLimit)
 			if err != nil {
 				rChan <- err
 				return
@@ -157,6 +175,101 @@ func GetCommunity[H db.Handle](h H, providerID uint64, exclude ...CommunityGetEx
 	return &community, nil
 }
 
+// GetCommunities returns the details of several communities in their given order, each section is read
+// for every community in one query so listing a page doesn't query once per community
+func GetCommunities[H db.Handle](h H, communities []db.Community, exclude ...CommunityGetExclude) ([]Community, error) {
+	const op errors.Op = "GetCommunities"
+
+	ids := []uint64{}
+	for i := range communities {
+		ids = append(ids, communities[i].ID)
+	}
+
+	exclude = misc.UniqueSlice(exclude)
+	settings := map[uint64]*db.CommunitySettings{}
+	tokens := map[uint64][]db.CommunityToken{}
+	associates := map[uint64][]db.CommunityAssociate{}
+	collections := map[uint64][]Collection{}
+	faq := map[uint64][]db.CommunityFaq{}
+	extras := map[uint64][]db.CommunityExtras{}
+
+	if !misc.InSlice(CommunityGetExcludeSettings, exclude) {
+		rows, err := db.GetCommunitySettingsByIDs(h, ids...)
+		if err != nil {
+			return nil, errors.E(op, err)
+		}
+		for i := range *rows {
+			settings[(*rows)[i].ID] = &(*rows)[i]
+		}
+	}
+
+	if !misc.InSlice(CommunityGetExcludeTokens, exclude) {
+		rows, err := db.GetCommunityTokensByIDs(h, ids...)
+		if err != nil {
+			return nil, errors.E(op, err)
+		}
+		for _, row := range *rows {
+			tokens[row.ID] = append(tokens[row.ID], row)
+		}
+	}
+
+	if !misc.InSlice(CommunityGetExcludeAssociates, exclude) {
+		rows, err := db.GetCommunityAssociatesByIDs(h, ids...)
+		if err != nil {
+			return nil, errors.E(op, err)
+		}
+		for _, row := range *rows {
+			associates[row.ID] = append(associates[row.ID], row)
+		}
+	}
+
+	if !misc.InSlice(CommunityGetExcludeCollections, exclude) {
+		var err error
+		collections, err = GetCollectionsByProviderIDs(h, ids)
+		if err != nil {
+			return nil, errors.E(op, err)
+		}
+	}
+
+	if !misc.InSlice(CommunityGetExcludeFaq, exclude) {
+		rows, err := db.GetCommunityFaqByIDs(h, ids...)
+		if err != nil {
+			return nil, errors.E(op, err)
+		}
+		for _, row := range *rows {
+			if len(faq[row.ID]) < communityFaqLimit {
+				faq[row.ID] = append(faq[row.ID], row)
+			}
+		}
+	}
+
+	if !misc.InSlice(CommunityGetExcludeExtras, exclude) {
+		rows, err := db.GetCommunityExtrasByIDs(h, ids...)
+		if err != nil {
+			return nil, errors.E(op, err)
+		}
+		for _, row := range *rows {
+			extras[row.ID] = append(extras[row.ID], row)
+		}
+	}
+
+	comms := []Community{}
+	for i := range communities {
+		id := communities[i].ID
+		comms = append(comms, Community{
+			Community:   &communities[i],
+			Settings:    settings[id],
+			Tokens:      tokens[id],
+			Associates:  associates[id],
+			Collections: collections[id],
+			Faq:         faq[id],
+			Extras:      extras[id],
+		})
+	}
+
+	return comms, nil
+}
+
 func DeleteCommunity[H db.Handle](h H, providerID uint64) error {
 	const op errors.Op = "DeleteCommunity"
 	var err error
@@ -210,3 +210,65 @@ func GetPropertiesByTraits[H db.Handle](h H, collectionID string, traits map[str
 
 	return &properties, nil
 }
+
+// GetPropertiesByCollectionIDs returns the properties of several collections keyed by collection ID,
+// reading the properties, their values & the values' extras in one query each
+func GetPropertiesByCollectionIDs[H db.Handle](h H, collectionIDs ...string) (map[string][]Property, error) {
+	const op errors.Op = "GetPropertiesByCollectionIDs"
+
+	props, err := db.GetPropertiesByCollectionIDs(h, collectionIDs...)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	propertyIDs := []string{}
+	for i := range *props {
+		propertyIDs = append(propertyIDs, (*props)[i].ID)
+	}
+
+	values, err := db.GetPropertyValuesByIDs(h, propertyIDs...)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	extras, err := db.GetPropertyValueExtrasByIDs(h, propertyIDs...)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	// extras are keyed by property ID & value name
+	valueExtras := map[string]map[string]map[string]string{}
+	for _, extra := range *extras {
+		if valueExtras[extra.ID] == nil {
+			valueExtras[extra.ID] = map[string]map[string]string{}
+		}
+		if valueExtras[extra.ID][extra.Name] == nil {
+			valueExtras[extra.ID][extra.Name] = map[string]string{}
+		}
+		valueExtras[extra.ID][extra.Name][extra.Key] = extra.Value
+	}
+
+	propertyValues := map[string][]PropertyValue{}
+	for i := range *values {
+		value := (*values)[i]
+		compPropertyValue := PropertyValue{
+			PropertyValue: &value,
+			Extras:        valueExtras[value.ID][value.Name],
+		}
+		if compPropertyValue.Extras == nil {
+			compPropertyValue.Extras = map[string]string{}
+		}
+		propertyValues[value.ID] = append(propertyValues[value.ID], compPropertyValue)
+	}
+
+	properties := map[string][]Property{}
+	for i := range *props {
+		prop := (*props)[i]
+		properties[prop.CollectionID] = append(properties[prop.CollectionID], Property{
+			Property: &prop,
+			Values:   propertyValues[prop.ID],
+		})
+	}
+
+	return properties, nil
+}
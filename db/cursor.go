@@ -0,0 +1,66 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/kylebeee/arc53-watcher-go/errors"
+)
+
+// CursorWatcher is the cursor id used by the live block watcher
+const CursorWatcher = "watcher"
+
+// Cursor is a row of the cursor table, cursor is reserved in mysql so queries quote it
+type Cursor struct {
+	ID string `structs:"id,omitempty" db:"id" json:"id,omitempty"`
+	// Round is the next round the owner of the cursor will process
+	Round uint64 `structs:"round,omitempty" db:"round" json:"round"`
+}
+
+func CursorTableKeys() []string {
+	return []string{"id", "round"}
+}
+
+func GetCursor[H Handle](h H, id string) (*Cursor, error) {
+	const op errors.Op = "GetCursor"
+	query := fmt.Sprintf("select %s from `cursor` where id = ?", strings.Join(CursorTableKeys(), ","))
+
+	var cursor Cursor
+	err := h.Get(&cursor, query, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.E(pkg, op, errors.DatabaseResultNotFound, err, "Cursor Not Found")
+		}
+		return nil, errors.E(pkg, op, err)
+	}
+
+	return &cursor, nil
+}
+
+// SetCursor creates or moves a cursor to the given round in a single statement
+func SetCursor[H Handle](h H, id string, round uint64) error {
+	const op errors.Op = "SetCursor"
+	query := "insert into `cursor` (id, round) values (?, ?) on duplicate key update round = values(round)"
+
+	switch h := any(h).(type) {
+	case *sqlx.Tx:
+		stmt, err := h.Prepare(query)
+		if err != nil {
+			return errors.E(pkg, op, errors.Database, err, "Failed to Prepare Query")
+		}
+
+		_, err = stmt.Exec(id, round)
+		if err != nil {
+			return errors.E(pkg, op, errors.Database, err, "Failed to Execute Query")
+		}
+	case *sqlx.DB:
+		_, err := h.Exec(query, id, round)
+		if err != nil {
+			return errors.E(pkg, op, errors.Database, err, "Failed to Execute Query")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,46 @@
+package db
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+)
+
+// cursor is reserved in mysql, every query of the table has to quote it
+func TestCursorQueriesQuoteTable(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	h := sqlx.NewDb(conn, "mysql")
+
+	mock.ExpectQuery(regexp.QuoteMeta("select id,round from `cursor` where id = ?")).WithArgs(CursorWatcher).
+		WillReturnRows(sqlmock.NewRows(CursorTableKeys()).AddRow(CursorWatcher, 10))
+	mock.ExpectExec(regexp.QuoteMeta("insert into `cursor` (id, round) values (?, ?) on duplicate key update round = values(round)")).WithArgs(CursorWatcher, 11).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	cursor, err := GetCursor(h, CursorWatcher)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cursor.Round != 10 {
+		t.Fatalf("cursor at round %d, want 10", cursor.Round)
+	}
+
+	err = SetCursor(h, CursorWatcher, 11)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if getTable(&Cursor{}) != "`cursor`" {
+		t.Fatalf("cursor table is %s, want it quoted", getTable(&Cursor{}))
+	}
+
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
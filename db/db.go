@@ -8,7 +8,7 @@ import (
 	"strings"
 
 	"github.com/fatih/structs"
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/go-sql-driver/mysql"
 	"github.com/jmoiron/sqlx"
 	"github.com/kylebeee/arc53-watcher-go/errors"
 )
@@ -30,7 +30,7 @@ const (
 	TKSFull  TableKeySlice = "full"
 )
 
-// Connect connects to a mysql database
+// Connect connects to a mysql database, arc53 when ENV is production & arc53_test otherwise
 func Connect() (*sqlx.DB, error) {
 	const op errors.Op = "Connect"
 
@@ -39,11 +39,39 @@ func Connect() (*sqlx.DB, error) {
 		return nil, errors.E(pkg, op, errors.Database, err)
 	}
 
-	db, err := sqlx.Open("mysql", string(credentials))
+	database := "arc53_test"
+	if os.Getenv("ENV") == "production" {
+		database = "arc53"
+	}
+
+	return Open(string(credentials), database, 0)
+}
+
+// Open connects to a database at dsn, queries aren't schema qualified so the connection
+// is pinned to the database given, maxOpenConns below one keeps the default of 10
+func Open(dsn string, database string, maxOpenConns int) (*sqlx.DB, error) {
+	const op errors.Op = "Open"
+
+	if maxOpenConns < 1 {
+		maxOpenConns = 10
+	}
+
+	cfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return nil, errors.E(pkg, op, errors.Database, err)
+	}
+	if database != "" {
+		cfg.DBName = database
+	}
+	if cfg.DBName == "" {
+		return nil, errors.E(pkg, op, errors.Database, fmt.Errorf("no database to connect to"))
+	}
+
+	db, err := sqlx.Open("mysql", cfg.FormatDSN())
 	if err != nil {
 		return nil, errors.E(pkg, op, errors.Database, err)
 	}
-	db.SetMaxOpenConns(10)
+	db.SetMaxOpenConns(maxOpenConns)
 	return db, nil
 }
 
@@ -263,13 +291,6 @@ func Delete[H Handle, S DBObject](h H, s S) (*sql.Result, error) {
 	return &res, nil
 }
 
-func arc53Database() string {
-	if os.Getenv("ENV") == "production" {
-		return "arc53"
-	}
-	return "arc53_test"
-}
-
 func ErrNoRows(err error) bool {
 	return err != nil && (err == sql.ErrNoRows || err.(*errors.Error).Kind == errors.DatabaseResultNotFound)
 }
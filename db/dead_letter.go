@@ -0,0 +1,198 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/kylebeee/arc53-watcher-go/errors"
+)
+
+const (
+	// DeadLetterSync is an app a provider type failed to sync, it's retried by syncing the app
+	DeadLetterSync = "sync"
+	// DeadLetterDecode is a transaction that failed to decode, decoding the same block again fails
+	// the same way so it's only retried by hand, which refetches its block
+	DeadLetterDecode = "decode"
+)
+
+// maxDeadLetterErrorLength matches the size of the error column
+const maxDeadLetterErrorLength = 1024
+
+// DeadLetter is a failed sync or transaction decode waiting to be retried
+type DeadLetter struct {
+	ID   uint64 `structs:"id,omitempty" db:"id" json:"id,omitempty"`
+	Kind string `structs:"kind,omitempty" db:"kind" json:"kind,omitempty"`
+	// DedupeKey identifies the failure so it's only queued once, see DeadLetterKey
+	DedupeKey    string `structs:"dedupe_key,omitempty" db:"dedupe_key" json:"-"`
+	ProviderType string `structs:"provider_type,omitempty" db:"provider_type" json:"provider_type,omitempty"`
+	AppID        uint64 `structs:"app_id,omitempty" db:"app_id" json:"app_id,omitempty"`
+	// Round is the latest round the failure happened in
+	Round     uint64 `structs:"round,omitempty" db:"round" json:"round"`
+	TxnIndex  uint64 `structs:"txn_index,omitempty" db:"txn_index" json:"txn_index,omitempty"`
+	ErrorKind string `structs:"error_kind,omitempty" db:"error_kind" json:"error_kind,omitempty"`
+	Error     string `structs:"error,omitempty" db:"error" json:"error,omitempty"`
+	Attempts  uint64 `structs:"attempts,omitempty" db:"attempts" json:"attempts"`
+	// NextAttempt, LastAttempt & Created are unix timestamps, NextAttempt is zero for
+	// entries that are only retried by hand
+	NextAttempt int64 `structs:"next_attempt,omitempty" db:"next_attempt" json:"next_attempt"`
+	LastAttempt int64 `structs:"last_attempt,omitempty" db:"last_attempt" json:"last_attempt"`
+	Created     int64 `structs:"created,omitempty" db:"created" json:"created"`
+}
+
+func DeadLetterTableKeys() []string {
+	return []string{"id", "kind", "dedupe_key", "provider_type", "app_id", "round", "txn_index", "error_kind", "error", "attempts", "next_attempt", "last_attempt", "created"}
+}
+
+// DeadLetterKey is the dedupe key of a failure, an app is queued once per provider type
+// whatever round it failed in & a transaction once per round & index
+func DeadLetterKey(letter *DeadLetter) string {
+	if letter.Kind == DeadLetterDecode {
+		return fmt.Sprintf("%s:%d:%d", letter.Kind, letter.Round, letter.TxnIndex)
+	}
+	return fmt.Sprintf("%s:%s:%d", letter.Kind, letter.ProviderType, letter.AppID)
+}
+
+func GetDeadLetter[H Handle](h H, id uint64) (*DeadLetter, error) {
+	const op errors.Op = "GetDeadLetter"
+	query := fmt.Sprintf("select %s from dead_letter where id = ?", strings.Join(DeadLetterTableKeys(), ","))
+
+	var letter DeadLetter
+	err := h.Get(&letter, query, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.E(pkg, op, errors.DatabaseResultNotFound, err, "Dead Letter Not Found")
+		}
+		return nil, errors.E(pkg, op, err)
+	}
+
+	return &letter, nil
+}
+
+func GetDeadLetters[H Handle](h H, start, limit uint64) (*[]DeadLetter, error) {
+	const op errors.Op = "GetDeadLetters"
+	query := fmt.Sprintf("select %s from dead_letter order by id asc limit ?, ?", strings.Join(DeadLetterTableKeys(), ","))
+
+	var letters []DeadLetter
+	err := h.Select(&letters, query, start, limit)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.E(pkg, op, errors.DatabaseResultNotFound, err, "Dead Letters Not Found")
+		}
+		return nil, errors.E(pkg, op, err)
+	}
+
+	return &letters, nil
+}
+
+// GetDueDeadLetters lists the entries of a kind whose next attempt is due, entries that
+// already had maxAttempts attempts are parked & only retried by hand
+func GetDueDeadLetters[H Handle](h H, kind string, now time.Time, maxAttempts uint64, limit uint64) (*[]DeadLetter, error) {
+	const op errors.Op = "GetDueDeadLetters"
+	query := fmt.Sprintf("select %s from dead_letter where kind = ? and next_attempt <= ? and attempts < ? order by next_attempt asc limit ?", strings.Join(DeadLetterTableKeys(), ","))
+
+	var letters []DeadLetter
+	err := h.Select(&letters, query, kind, now.Unix(), maxAttempts, limit)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.E(pkg, op, errors.DatabaseResultNotFound, err, "Dead Letters Not Found")
+		}
+		return nil, errors.E(pkg, op, err)
+	}
+
+	return &letters, nil
+}
+
+// RecordDeadLetter queues a failure to be retried at next, a failure that's already queued
+// moves to the later round & counts another attempt but keeps its schedule
+func RecordDeadLetter[H Handle](h H, letter *DeadLetter, failure error, next time.Time) error {
+	const op errors.Op = "RecordDeadLetter"
+	query := "insert into dead_letter (kind, dedupe_key, provider_type, app_id, round, txn_index, error_kind, error, attempts, next_attempt, last_attempt, created) values (?, ?, ?, ?, ?, ?, ?, ?, 1, ?, ?, ?) on duplicate key update round = greatest(round, values(round)), error_kind = values(error_kind), error = values(error), attempts = attempts + 1, last_attempt = values(last_attempt)"
+
+	now := time.Now().Unix()
+	args := []interface{}{letter.Kind, DeadLetterKey(letter), letter.ProviderType, letter.AppID, letter.Round, letter.TxnIndex, string(errors.KindOf(failure)), deadLetterError(failure), next.Unix(), now, now}
+
+	switch h := any(h).(type) {
+	case *sqlx.Tx:
+		stmt, err := h.Prepare(query)
+		if err != nil {
+			return errors.E(pkg, op, errors.Database, err, "Failed to Prepare Query")
+		}
+
+		_, err = stmt.Exec(args...)
+		if err != nil {
+			return errors.E(pkg, op, errors.Database, err, "Failed to Execute Query")
+		}
+	case *sqlx.DB:
+		_, err := h.Exec(query, args...)
+		if err != nil {
+			return errors.E(pkg, op, errors.Database, err, "Failed to Execute Query")
+		}
+	}
+
+	return nil
+}
+
+// RescheduleDeadLetter records a failed retry & when the next one is due
+func RescheduleDeadLetter[H Handle](h H, id uint64, failure error, next time.Time) error {
+	const op errors.Op = "RescheduleDeadLetter"
+	query := "update dead_letter set error_kind = ?, error = ?, attempts = attempts + 1, next_attempt = ?, last_attempt = ? where id = ?"
+
+	args := []interface{}{string(errors.KindOf(failure)), deadLetterError(failure), next.Unix(), time.Now().Unix(), id}
+
+	switch h := any(h).(type) {
+	case *sqlx.Tx:
+		stmt, err := h.Prepare(query)
+		if err != nil {
+			return errors.E(pkg, op, errors.Database, err, "Failed to Prepare Query")
+		}
+
+		_, err = stmt.Exec(args...)
+		if err != nil {
+			return errors.E(pkg, op, errors.Database, err, "Failed to Execute Query")
+		}
+	case *sqlx.DB:
+		_, err := h.Exec(query, args...)
+		if err != nil {
+			return errors.E(pkg, op, errors.Database, err, "Failed to Execute Query")
+		}
+	}
+
+	return nil
+}
+
+func DeleteDeadLetter[H Handle](h H, id uint64) error {
+	const op errors.Op = "DeleteDeadLetter"
+	query := "delete from dead_letter where id = ?"
+
+	switch h := any(h).(type) {
+	case *sqlx.Tx:
+		stmt, err := h.Prepare(query)
+		if err != nil {
+			return errors.E(pkg, op, errors.Database, err, "Failed to Prepare Query")
+		}
+
+		_, err = stmt.Exec(id)
+		if err != nil {
+			return errors.E(pkg, op, errors.Database, err, "Failed to Execute Query")
+		}
+	case *sqlx.DB:
+		_, err := h.Exec(query, id)
+		if err != nil {
+			return errors.E(pkg, op, errors.Database, err, "Failed to Execute Query")
+		}
+	}
+
+	return nil
+}
+
+// deadLetterError trims an error to fit the error column
+func deadLetterError(err error) string {
+	message := err.Error()
+	if len(message) > maxDeadLetterErrorLength {
+		message = message[:maxDeadLetterErrorLength]
+	}
+	return message
+}
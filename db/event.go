@@ -0,0 +1,168 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/kylebeee/arc53-watcher-go/errors"
+)
+
+// event types, named after what changed & how
+const (
+	EventCommunityCreated  = "community.created"
+	EventCommunityUpdated  = "community.updated"
+	EventCommunityDeleted  = "community.deleted"
+	EventCollectionCreated = "collection.created"
+	EventCollectionUpdated = "collection.updated"
+	EventCollectionDeleted = "collection.deleted"
+	EventTokenCreated      = "token.created"
+	EventTokenUpdated      = "token.updated"
+	EventTokenDeleted      = "token.deleted"
+	EventAssociateCreated  = "associate.created"
+	EventAssociateDeleted  = "associate.deleted"
+	EventAddressCreated    = "address.created"
+	EventAddressDeleted    = "address.deleted"
+)
+
+// EventTypes lists every event type
+var EventTypes = []string{
+	EventCommunityCreated, EventCommunityUpdated, EventCommunityDeleted,
+	EventCollectionCreated, EventCollectionUpdated, EventCollectionDeleted,
+	EventTokenCreated, EventTokenUpdated, EventTokenDeleted,
+	EventAssociateCreated, EventAssociateDeleted,
+	EventAddressCreated, EventAddressDeleted,
+}
+
+// Event is a change to a community, events are written in the same transaction as the
+// change so the event table doubles as an outbox that streams resume from
+type Event struct {
+	ID         uint64 `structs:"id,omitempty" db:"id" json:"id"`
+	ProviderID uint64 `structs:"provider_id,omitempty" db:"provider_id" json:"provider_id"`
+	Type       string `structs:"type,omitempty" db:"type" json:"type"`
+	// Subject is what changed within the community, a collection name, asset ID or address
+	Subject string `structs:"subject,omitempty" db:"subject" json:"subject,omitempty"`
+	// Round is the round the provider app was synced at
+	Round uint64 `structs:"round,omitempty" db:"round" json:"round"`
+	// Created is a unix millisecond timestamp
+	Created int64 `structs:"created,omitempty" db:"created" json:"created"`
+}
+
+func EventTableKeys() []string {
+	return []string{"id", "provider_id", "type", "subject", "round", "created"}
+}
+
+func GetEvent[H Handle](h H, id uint64) (*Event, error) {
+	const op errors.Op = "GetEvent"
+	query := fmt.Sprintf("select %s from event where id = ?", strings.Join(EventTableKeys(), ","))
+
+	var event Event
+	err := h.Get(&event, query, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.E(pkg, op, errors.DatabaseResultNotFound, err, "Event Not Found")
+		}
+		return nil, errors.E(pkg, op, err)
+	}
+
+	return &event, nil
+}
+
+// GetEventsAfter lists up to limit events after the given event ID in order
+func GetEventsAfter[H Handle](h H, after uint64, limit uint64) (*[]Event, error) {
+	const op errors.Op = "GetEventsAfter"
+	query := fmt.Sprintf("select %s from event where id > ? order by id asc limit ?", strings.Join(EventTableKeys(), ","))
+
+	var events []Event
+	err := h.Select(&events, query, after, limit)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.E(pkg, op, errors.DatabaseResultNotFound, err, "Events Not Found")
+		}
+		return nil, errors.E(pkg, op, err)
+	}
+
+	return &events, nil
+}
+
+// GetLatestEventID is the ID of the newest event, zero when there are none
+func GetLatestEventID[H Handle](h H) (uint64, error) {
+	const op errors.Op = "GetLatestEventID"
+	query := "select coalesce(max(id), 0) from event"
+
+	var id uint64
+	err := h.Get(&id, query)
+	if err != nil {
+		return 0, errors.E(pkg, op, err)
+	}
+
+	return id, nil
+}
+
+// InsertEvents writes events with the next IDs of the event sequence, the sequence row stays
+// locked until the transaction ends so events are committed in ID order without gaps & a
+// reader past the last ID it saw can't miss an event committed later. A DB handle writes the
+// events in a transaction of their own
+func InsertEvents[H Handle](h H, events []Event) error {
+	const op errors.Op = "InsertEvents"
+
+	if len(events) == 0 {
+		return nil
+	}
+
+	switch h := any(h).(type) {
+	case *sqlx.Tx:
+		err := insertEvents(h, events)
+		if err != nil {
+			return errors.E(pkg, op, err)
+		}
+	case *sqlx.DB:
+		tx, err := h.Beginx()
+		if err != nil {
+			return errors.E(pkg, op, errors.Database, err, "Failed to Begin Transaction")
+		}
+
+		err = insertEvents(tx, events)
+		if err != nil {
+			tx.Rollback()
+			return errors.E(pkg, op, err)
+		}
+
+		err = tx.Commit()
+		if err != nil {
+			return errors.E(pkg, op, errors.Database, err, "Failed to Commit Transaction")
+		}
+	}
+
+	return nil
+}
+
+func insertEvents(tx *sqlx.Tx, events []Event) error {
+	const op errors.Op = "insertEvents"
+
+	var last uint64
+	err := tx.Get(&last, "select last_id from event_sequence where id = 1 for update")
+	if err != nil {
+		return errors.E(pkg, op, errors.Database, err, "Failed to Lock Event Sequence")
+	}
+
+	values := []interface{}{}
+	for i := range events {
+		events[i].ID = last + uint64(i) + 1
+		values = append(values, events[i].ID, events[i].ProviderID, events[i].Type, events[i].Subject, events[i].Round, events[i].Created)
+	}
+	query := fmt.Sprintf("insert into event (id, provider_id, type, subject, round, created) values %s", strings.TrimSuffix(strings.Repeat("(?, ?, ?, ?, ?, ?), ", len(events)), ", "))
+
+	_, err = tx.Exec(query, values...)
+	if err != nil {
+		return errors.E(pkg, op, errors.Database, err, "Failed to Execute Query")
+	}
+
+	_, err = tx.Exec("update event_sequence set last_id = ? where id = 1", last+uint64(len(events)))
+	if err != nil {
+		return errors.E(pkg, op, errors.Database, err, "Failed to Advance Event Sequence")
+	}
+
+	return nil
+}
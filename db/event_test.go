@@ -0,0 +1,101 @@
+package db
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+)
+
+func TestInsertEvents(t *testing.T) {
+	tests := []struct {
+		name string
+		// tx writes the events in a transaction that's already open
+		tx bool
+	}{
+		{name: "in a transaction", tx: true},
+		{name: "in a transaction of their own", tx: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+			h := sqlx.NewDb(conn, "mysql")
+
+			events := []Event{
+				{ProviderID: 1, Type: EventCommunityUpdated, Round: 10, Created: 1000},
+				{ProviderID: 1, Type: EventCollectionCreated, Subject: "art", Round: 10, Created: 1000},
+			}
+
+			// the sequence is locked before the events are written & advanced past them
+			mock.ExpectBegin()
+			mock.ExpectQuery(regexp.QuoteMeta("select last_id from event_sequence where id = 1 for update")).
+				WillReturnRows(sqlmock.NewRows([]string{"last_id"}).AddRow(41))
+			mock.ExpectExec(regexp.QuoteMeta("insert into event (id, provider_id, type, subject, round, created) values (?, ?, ?, ?, ?, ?), (?, ?, ?, ?, ?, ?)")).
+				WithArgs(42, 1, EventCommunityUpdated, "", 10, 1000, 43, 1, EventCollectionCreated, "art", 10, 1000).
+				WillReturnResult(sqlmock.NewResult(43, 2))
+			mock.ExpectExec(regexp.QuoteMeta("update event_sequence set last_id = ? where id = 1")).
+				WithArgs(43).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectCommit()
+
+			if tt.tx {
+				tx, err := h.Beginx()
+				if err != nil {
+					t.Fatal(err)
+				}
+				err = InsertEvents(tx, events)
+				if err != nil {
+					t.Fatal(err)
+				}
+				err = tx.Commit()
+				if err != nil {
+					t.Fatal(err)
+				}
+			} else {
+				err = InsertEvents(h, events)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if fmt.Sprint(events[0].ID, events[1].ID) != "42 43" {
+				t.Fatalf("events got IDs %d & %d, want 42 & 43", events[0].ID, events[1].ID)
+			}
+			err = mock.ExpectationsWereMet()
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestInsertEventsRollsBackOnFailure(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	h := sqlx.NewDb(conn, "mysql")
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("select last_id from event_sequence where id = 1 for update")).
+		WillReturnRows(sqlmock.NewRows([]string{"last_id"}).AddRow(41))
+	mock.ExpectExec("insert into event").WillReturnError(fmt.Errorf("deadlock"))
+	mock.ExpectRollback()
+
+	err = InsertEvents(h, []Event{{ProviderID: 1, Type: EventCommunityUpdated}})
+	if err == nil {
+		t.Fatal("InsertEvents succeeded with the insert failing")
+	}
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
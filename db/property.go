@@ -22,7 +22,7 @@ func PropertyTableKeys() []string {
 
 func GetProperties[H Handle](h H, collectionID string) (*[]Property, error) {
 	const op errors.Op = "GetProperties"
-	query := fmt.Sprintf("select %s from %s.property where collection_id = ?", strings.Join(PropertyTableKeys(), ","), arc53Database())
+	query := fmt.Sprintf("select %s from property where collection_id = ?", strings.Join(PropertyTableKeys(), ","))
 
 	var properties []Property
 	err := h.Select(&properties, query, collectionID)
@@ -38,7 +38,7 @@ func GetProperties[H Handle](h H, collectionID string) (*[]Property, error) {
 
 func GetPropertiesByName[H Handle](h H, collectionID string, name string) (*[]Property, error) {
 	const op errors.Op = "GetProperties"
-	query := fmt.Sprintf("select %s from %s.property where collection_id = ? and name = ?", strings.Join(PropertyTableKeys(), ","), arc53Database())
+	query := fmt.Sprintf("select %s from property where collection_id = ? and name = ?", strings.Join(PropertyTableKeys(), ","))
 
 	var properties []Property
 	err := h.Select(&properties, query, collectionID, name)
@@ -54,7 +54,7 @@ func GetPropertiesByName[H Handle](h H, collectionID string, name string) (*[]Pr
 
 func GetPropertiesWhereNameIn[H Handle](h H, collectionID string, names ...string) (*[]Property, error) {
 	const op errors.Op = "GetProperties"
-	query := fmt.Sprintf("select %s from %s.property where collection_id = ? and name in (%s)", strings.Join(PropertyTableKeys(), ","), arc53Database(), strings.Repeat("?, ", len(names))[0:(len(names)*3)-2])
+	query := fmt.Sprintf("select %s from property where collection_id = ? and name in (%s)", strings.Join(PropertyTableKeys(), ","), strings.Repeat("?, ", len(names))[0:(len(names)*3)-2])
 
 	var properties []Property
 	err := h.Select(&properties, query, append([]interface{}{collectionID}, misc.ToInterfaceSlice(names)...)...)
@@ -70,7 +70,7 @@ func GetPropertiesWhereNameIn[H Handle](h H, collectionID string, names ...strin
 
 func DeleteCollectionProperties[H Handle](h H, collectionID string) error {
 	const op errors.Op = "DeleteCollectionProperties"
-	query := fmt.Sprintf("delete from %s.property where collection_id = ?", arc53Database())
+	query := "delete from property where collection_id = ?"
 
 	switch h := any(h).(type) {
 	case *sqlx.Tx:
@@ -98,7 +98,7 @@ func DeletePropertyNotIn[H Handle](h H, collectionID string, ids ...string) erro
 	const op errors.Op = "DeletePropertyNotIn"
 	var err error
 	data := append([]interface{}{collectionID}, misc.ToInterfaceSlice(ids)...)
-	query := fmt.Sprintf("delete from %s.property where collection_id = ?", arc53Database())
+	query := "delete from property where collection_id = ?"
 
 	if len(ids) > 0 {
 		qMarks := []rune(strings.Repeat("?, ", len(ids)))
@@ -125,3 +125,26 @@ func DeletePropertyNotIn[H Handle](h H, collectionID string, ids ...string) erro
 
 	return nil
 }
+
+// GetPropertiesByCollectionIDs is GetProperties for several collections in one query
+func GetPropertiesByCollectionIDs[H Handle](h H, collectionIDs ...string) (*[]Property, error) {
+	const op errors.Op = "GetPropertiesByCollectionIDs"
+
+	properties := []Property{}
+	if len(collectionIDs) == 0 {
+		return &properties, nil
+	}
+
+	qMarks := []rune(strings.Repeat("?, ", len(collectionIDs)))
+	query := fmt.Sprintf("select %s from property where collection_id in (%s)", strings.Join(PropertyTableKeys(), ","), string(qMarks[0:len(qMarks)-2]))
+
+	err := h.Select(&properties, query, misc.ToInterfaceSlice(collectionIDs)...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.E(pkg, op, errors.DatabaseResultNotFound, err, "Property Not Found")
+		}
+		return nil, errors.E(pkg, op, err)
+	}
+
+	return &properties, nil
+}
@@ -28,7 +28,7 @@ func PropertyValueTableKeys() []string {
 
 func GetPropertyValues[H Handle](h H, id string) (*[]PropertyValue, error) {
 	const op errors.Op = "GetPropertyValues"
-	query := fmt.Sprintf("select %s from %s.property_value where id = ?", strings.Join(PropertyValueTableKeys(), ","), arc53Database())
+	query := fmt.Sprintf("select %s from property_value where id = ?", strings.Join(PropertyValueTableKeys(), ","))
 
 	var propertyValues []PropertyValue
 	err := h.Select(&propertyValues, query, id)
@@ -44,7 +44,7 @@ func GetPropertyValues[H Handle](h H, id string) (*[]PropertyValue, error) {
 
 func GetPropertyValueByName[H Handle](h H, id string, name string) (*PropertyValue, error) {
 	const op errors.Op = "GetPropertyValueByName"
-	query := fmt.Sprintf("select %s from %s.property_value where id = ? and name = ?", strings.Join(PropertyValueTableKeys(), ","), arc53Database())
+	query := fmt.Sprintf("select %s from property_value where id = ? and name = ?", strings.Join(PropertyValueTableKeys(), ","))
 
 	var propertyValue PropertyValue
 	err := h.Get(&propertyValue, query, id, name)
@@ -60,7 +60,7 @@ func GetPropertyValueByName[H Handle](h H, id string, name string) (*PropertyVal
 
 func DeletePropertyValues[H Handle](h H, id string) error {
 	const op errors.Op = "DeletePropertyValues"
-	query := fmt.Sprintf("delete from %s.property_value where id = ?", arc53Database())
+	query := "delete from property_value where id = ?"
 
 	switch h := any(h).(type) {
 	case *sqlx.Tx:
@@ -88,7 +88,7 @@ func DeletePropertyValueNotIn[H Handle](h H, id string, names ...string) error {
 	const op errors.Op = "DeletePropertyValueNotIn"
 	var err error
 	data := append([]interface{}{id}, misc.ToInterfaceSlice(names)...)
-	query := fmt.Sprintf("delete from %s.property_value where id = ?", arc53Database())
+	query := "delete from property_value where id = ?"
 
 	if len(names) > 0 {
 		qMarks := []rune(strings.Repeat("?, ", len(names)))
@@ -115,3 +115,26 @@ func DeletePropertyValueNotIn[H Handle](h H, id string, names ...string) error {
 
 	return nil
 }
+
+// GetPropertyValuesByIDs is GetPropertyValues for several properties in one query
+func GetPropertyValuesByIDs[H Handle](h H, ids ...string) (*[]PropertyValue, error) {
+	const op errors.Op = "GetPropertyValuesByIDs"
+
+	values := []PropertyValue{}
+	if len(ids) == 0 {
+		return &values, nil
+	}
+
+	qMarks := []rune(strings.Repeat("?, ", len(ids)))
+	query := fmt.Sprintf("select %s from property_value where id in (%s)", strings.Join(PropertyValueTableKeys(), ","), string(qMarks[0:len(qMarks)-2]))
+
+	err := h.Select(&values, query, misc.ToInterfaceSlice(ids)...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.E(pkg, op, errors.DatabaseResultNotFound, err, "PropertyValue Not Found")
+		}
+		return nil, errors.E(pkg, op, err)
+	}
+
+	return &values, nil
+}
@@ -23,7 +23,7 @@ func PropertyValueExtrasTableKeys() []string {
 
 func GetPropertyValueExtras[H Handle](h H, id string) (*[]PropertyValueExtras, error) {
 	const op errors.Op = "GetPropertyValueExtras"
-	query := fmt.Sprintf("select %s from %s.property_value_extras where id = ?", strings.Join(PropertyValueExtrasTableKeys(), ","), arc53Database())
+	query := fmt.Sprintf("select %s from property_value_extras where id = ?", strings.Join(PropertyValueExtrasTableKeys(), ","))
 
 	var extras []PropertyValueExtras
 	err := h.Select(&extras, query, id)
@@ -39,7 +39,7 @@ func GetPropertyValueExtras[H Handle](h H, id string) (*[]PropertyValueExtras, e
 
 func GetPropertyValueExtrasByName[H Handle](h H, id string, name string) (*[]PropertyValueExtras, error) {
 	const op errors.Op = "GetPropertyValueExtrasByName"
-	query := fmt.Sprintf("select %s from %s.property_value_extras where id = ? and name = ?", strings.Join(PropertyValueExtrasTableKeys(), ","), arc53Database())
+	query := fmt.Sprintf("select %s from property_value_extras where id = ? and name = ?", strings.Join(PropertyValueExtrasTableKeys(), ","))
 
 	var extras []PropertyValueExtras
 	err := h.Select(&extras, query, id, name)
@@ -55,7 +55,7 @@ func GetPropertyValueExtrasByName[H Handle](h H, id string, name string) (*[]Pro
 
 func DeletePropertyValueExtras[H Handle](h H, id string) error {
 	const op errors.Op = "DeletePropertyValueExtras"
-	query := fmt.Sprintf("delete from %s.property_value_extras where id = ?", arc53Database())
+	query := "delete from property_value_extras where id = ?"
 
 	switch h := any(h).(type) {
 	case *sqlx.Tx:
@@ -83,7 +83,7 @@ func DeletePropertyValueExtrasNotIn[H Handle](h H, id string, name string, keys
 	const op errors.Op = "DeletePropertyValueExtrasNotIn"
 	var err error
 	data := append([]interface{}{id, name}, misc.ToInterfaceSlice(keys)...)
-	query := fmt.Sprintf("delete from %s.property_value_extras where id = ? and name = ?", arc53Database())
+	query := "delete from property_value_extras where id = ? and name = ?"
 
 	if len(keys) > 0 {
 		qMarks := []rune(strings.Repeat("?, ", len(keys)))
@@ -110,3 +110,26 @@ func DeletePropertyValueExtrasNotIn[H Handle](h H, id string, name string, keys
 
 	return nil
 }
+
+// GetPropertyValueExtrasByIDs is GetPropertyValueExtras for several properties in one query
+func GetPropertyValueExtrasByIDs[H Handle](h H, ids ...string) (*[]PropertyValueExtras, error) {
+	const op errors.Op = "GetPropertyValueExtrasByIDs"
+
+	extras := []PropertyValueExtras{}
+	if len(ids) == 0 {
+		return &extras, nil
+	}
+
+	qMarks := []rune(strings.Repeat("?, ", len(ids)))
+	query := fmt.Sprintf("select %s from property_value_extras where id in (%s)", strings.Join(PropertyValueExtrasTableKeys(), ","), string(qMarks[0:len(qMarks)-2]))
+
+	err := h.Select(&extras, query, misc.ToInterfaceSlice(ids)...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.E(pkg, op, errors.DatabaseResultNotFound, err, "PropertyValueExtras Not Found")
+		}
+		return nil, errors.E(pkg, op, err)
+	}
+
+	return &extras, nil
+}
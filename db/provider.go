@@ -20,7 +20,7 @@ func ProviderTableKeys() []string {
 
 func GetProvider[H Handle](h H, id uint64) (*Provider, error) {
 	const op errors.Op = "GetProvider"
-	query := fmt.Sprintf("select %s from %s.provider where id = ?", strings.Join(ProviderTableKeys(), ","), arc53Database())
+	query := fmt.Sprintf("select %s from provider where id = ?", strings.Join(ProviderTableKeys(), ","))
 	var provider Provider
 
 	err := h.Get(&provider, query, id)
@@ -36,7 +36,7 @@ func GetProvider[H Handle](h H, id uint64) (*Provider, error) {
 
 func GetAllProvidersByType[H Handle](h H, t string) (*[]Provider, error) {
 	const op errors.Op = "GetAllProvidersByType"
-	query := fmt.Sprintf("select %s from %s.provider where type = ?", strings.Join(ProviderTableKeys(), ","), arc53Database())
+	query := fmt.Sprintf("select %s from provider where type = ?", strings.Join(ProviderTableKeys(), ","))
 	var list []Provider
 
 	err := h.Select(&list, query, t)
@@ -56,7 +56,7 @@ func GetAllProvidersByType[H Handle](h H, t string) (*[]Provider, error) {
 
 func GetLatestProviderRound[H Handle](h H, t string) (uint64, error) {
 	const op errors.Op = "GetLatestProviderRound"
-	query := fmt.Sprintf("select coalesce(max(round),0) from %s.provider where type = ?", arc53Database())
+	query := "select coalesce(max(round),0) from provider where type = ?"
 	var round uint64
 
 	err := h.Get(&round, query, t)
@@ -14,15 +14,18 @@ type ProviderAddress struct {
 	// ID is the app id of the Provider Contract
 	ID      uint64 `structs:"id,omitempty" db:"id" json:"id,omitempty"`
 	Address string `structs:"address,omitempty" db:"address" json:"address,omitempty"`
+	// Verified is set when the address proved it's controlled by the provider, unverified
+	// addresses are stored but left out of lookups & ownership checks
+	Verified *bool `structs:"verified,omitempty" db:"verified" json:"verified,omitempty"`
 }
 
 func ProviderAddressTableKeys() []string {
-	return []string{"id", "address"}
+	return []string{"id", "address", "verified"}
 }
 
 func GetAllProviderAddresses[H DBStruct](h H) (*[]ProviderAddress, error) {
 	const op errors.Op = "GetAllProviderAddresses"
-	query := fmt.Sprintf("select %s from %s.provider_address", strings.Join(ProviderAddressTableKeys(), ","), arc53Database())
+	query := fmt.Sprintf("select %s from provider_address", strings.Join(ProviderAddressTableKeys(), ","))
 	var list []ProviderAddress
 
 	err := h.Select(&list, query)
@@ -42,7 +45,7 @@ func GetAllProviderAddresses[H DBStruct](h H) (*[]ProviderAddress, error) {
 
 func GetAllProviderAddressesByType[H Handle](h H, t string) (*[]ProviderAddress, error) {
 	const op errors.Op = "GetAllProviderAddressesByType"
-	query := fmt.Sprintf("select %s from %s.provider_address where type = ?", strings.Join(ProviderAddressTableKeys(), ","), arc53Database())
+	query := fmt.Sprintf("select %s from provider_address where type = ?", strings.Join(ProviderAddressTableKeys(), ","))
 	var list []ProviderAddress
 
 	err := h.Select(&list, query, t)
@@ -63,7 +66,7 @@ func GetAllProviderAddressesByType[H Handle](h H, t string) (*[]ProviderAddress,
 
 func GetProviderAddresses[H DBStruct](h H, id uint64) (*[]ProviderAddress, error) {
 	const op errors.Op = "GetProviderAddresses"
-	query := fmt.Sprintf("select %s from %s.provider_address where id = ?", strings.Join(ProviderAddressTableKeys(), ","), arc53Database())
+	query := fmt.Sprintf("select %s from provider_address where id = ?", strings.Join(ProviderAddressTableKeys(), ","))
 	var list []ProviderAddress
 
 	err := h.Select(&list, query, id)
@@ -83,7 +86,7 @@ func GetProviderAddresses[H DBStruct](h H, id uint64) (*[]ProviderAddress, error
 
 func GetProviderAddressesAddressesByAdjacentAddresses[H DBStruct](h H, addresses []string) (*[]string, error) {
 	const op errors.Op = "GetProviderAddressesAddressesByAdjacentAddresses"
-	query := fmt.Sprintf("select distinct(address) from %s.provider_address where id in (select id from %v.provider_address where address in (%s))", arc53Database(), arc53Database(), strings.Repeat("?, ", len(addresses))[0:(len(addresses)*3)-2])
+	query := fmt.Sprintf("select distinct(address) from provider_address where verified = 1 and id in (select id from provider_address where verified = 1 and address in (%s))", strings.Repeat("?, ", len(addresses))[0:(len(addresses)*3)-2])
 	var list []string
 
 	err := h.Select(&list, query, misc.ToInterfaceSlice(addresses)...)
@@ -103,7 +106,7 @@ func GetProviderAddressesAddressesByAdjacentAddresses[H DBStruct](h H, addresses
 
 func DeleteProviderAddressesByIDAndAddress[H DBStruct](h H, id uint64, address string) error {
 	const op errors.Op = "DeleteProviderAddressesByIDAndAddress"
-	query := fmt.Sprintf("delete from %s.provider_address where id = ? and address = ?", arc53Database())
+	query := "delete from provider_address where id = ? and address = ?"
 	var err error
 
 	switch h := any(h).(type) {
@@ -133,7 +136,7 @@ func DeleteProviderAddressNotIn[H Handle](h H, id uint64, addresses ...string) e
 	var err error
 	data := append([]interface{}{id}, misc.ToInterfaceSlice(addresses)...)
 	// fmt.Println(data)
-	query := fmt.Sprintf("delete from %s.provider_address where id = ?", arc53Database())
+	query := "delete from provider_address where id = ?"
 
 	if len(addresses) > 0 {
 		qMarks := []rune(strings.Repeat("?, ", len(addresses)))
@@ -0,0 +1,101 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/kylebeee/arc53-watcher-go/errors"
+)
+
+// maxSyncFailureErrorLength matches the size of the error column
+const maxSyncFailureErrorLength = 1024
+
+// SyncFailure records an app that couldn't be synced so it can be retried
+type SyncFailure struct {
+	ID           uint64 `structs:"id,omitempty" db:"id" json:"id,omitempty"`
+	ProviderType string `structs:"provider_type,omitempty" db:"provider_type" json:"provider_type,omitempty"`
+	Error        string `structs:"error,omitempty" db:"error" json:"error,omitempty"`
+	Attempts     uint64 `structs:"attempts,omitempty" db:"attempts" json:"attempts"`
+	// LastAttempt is a unix timestamp
+	LastAttempt int64 `structs:"last_attempt,omitempty" db:"last_attempt" json:"last_attempt"`
+}
+
+func SyncFailureTableKeys() []string {
+	return []string{"id", "provider_type", "error", "attempts", "last_attempt"}
+}
+
+func GetSyncFailures[H Handle](h H, providerType string) (*[]SyncFailure, error) {
+	const op errors.Op = "GetSyncFailures"
+	query := fmt.Sprintf("select %s from sync_failure where provider_type = ? order by id asc", strings.Join(SyncFailureTableKeys(), ","))
+
+	var failures []SyncFailure
+	err := h.Select(&failures, query, providerType)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.E(pkg, op, errors.DatabaseResultNotFound, err, "Sync Failures Not Found")
+		}
+		return nil, errors.E(pkg, op, err)
+	}
+
+	return &failures, nil
+}
+
+// RecordSyncFailure adds a failure for an app or bumps the attempts of an existing one
+func RecordSyncFailure[H Handle](h H, providerType string, id uint64, syncErr error) error {
+	const op errors.Op = "RecordSyncFailure"
+	query := "insert into sync_failure (id, provider_type, error, attempts, last_attempt) values (?, ?, ?, 1, ?) on duplicate key update error = values(error), attempts = attempts + 1, last_attempt = values(last_attempt)"
+
+	message := syncErr.Error()
+	if len(message) > maxSyncFailureErrorLength {
+		message = message[:maxSyncFailureErrorLength]
+	}
+	args := []interface{}{id, providerType, message, time.Now().Unix()}
+
+	switch h := any(h).(type) {
+	case *sqlx.Tx:
+		stmt, err := h.Prepare(query)
+		if err != nil {
+			return errors.E(pkg, op, errors.Database, err, "Failed to Prepare Query")
+		}
+
+		_, err = stmt.Exec(args...)
+		if err != nil {
+			return errors.E(pkg, op, errors.Database, err, "Failed to Execute Query")
+		}
+	case *sqlx.DB:
+		_, err := h.Exec(query, args...)
+		if err != nil {
+			return errors.E(pkg, op, errors.Database, err, "Failed to Execute Query")
+		}
+	}
+
+	return nil
+}
+
+func DeleteSyncFailure[H Handle](h H, providerType string, id uint64) error {
+	const op errors.Op = "DeleteSyncFailure"
+	query := "delete from sync_failure where provider_type = ? and id = ?"
+
+	switch h := any(h).(type) {
+	case *sqlx.Tx:
+		stmt, err := h.Prepare(query)
+		if err != nil {
+			return errors.E(pkg, op, errors.Database, err, "Failed to Prepare Query")
+		}
+
+		_, err = stmt.Exec(providerType, id)
+		if err != nil {
+			return errors.E(pkg, op, errors.Database, err, "Failed to Execute Query")
+		}
+	case *sqlx.DB:
+		_, err := h.Exec(query, providerType, id)
+		if err != nil {
+			return errors.E(pkg, op, errors.Database, err, "Failed to Execute Query")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,112 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/kylebeee/arc53-watcher-go/errors"
+)
+
+// sync job statuses, a job is queued until a worker picks it up
+const (
+	SyncJobQueued    = "queued"
+	SyncJobRunning   = "running"
+	SyncJobSucceeded = "succeeded"
+	SyncJobFailed    = "failed"
+)
+
+// maxSyncJobErrorLength matches the size of the error column
+const maxSyncJobErrorLength = 1024
+
+// SyncJob tracks a requested sync of a provider app
+type SyncJob struct {
+	ID           string `structs:"id,omitempty" db:"id" json:"id,omitempty"`
+	ProviderType string `structs:"provider_type,omitempty" db:"provider_type" json:"provider_type,omitempty"`
+	AppID        uint64 `structs:"app_id,omitempty" db:"app_id" json:"app_id,omitempty"`
+	Status       string `structs:"status,omitempty" db:"status" json:"status,omitempty"`
+	Error        string `structs:"error,omitempty" db:"error" json:"error,omitempty"`
+	// Changes is the summary of what a successful sync changed
+	Changes json.RawMessage `structs:"changes,omitempty" db:"changes" json:"changes,omitempty"`
+	// Created, Started & Finished are unix millisecond timestamps
+	Created  int64 `structs:"created,omitempty" db:"created" json:"created"`
+	Started  int64 `structs:"started,omitempty" db:"started" json:"started,omitempty"`
+	Finished int64 `structs:"finished,omitempty" db:"finished" json:"finished,omitempty"`
+}
+
+func SyncJobTableKeys() []string {
+	return []string{"id", "provider_type", "app_id", "status", "error", "changes", "created", "started", "finished"}
+}
+
+func GetSyncJob[H Handle](h H, id string) (*SyncJob, error) {
+	const op errors.Op = "GetSyncJob"
+	query := fmt.Sprintf("select %s from sync_job where id = ?", strings.Join(SyncJobTableKeys(), ","))
+
+	var job SyncJob
+	err := h.Get(&job, query, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.E(pkg, op, errors.DatabaseResultNotFound, err, "Sync Job Not Found")
+		}
+		return nil, errors.E(pkg, op, err)
+	}
+
+	return &job, nil
+}
+
+// FinishSyncJob marks a job succeeded with what it changed, or failed with its error
+func FinishSyncJob[H Handle](h H, id string, changes json.RawMessage, syncErr error) error {
+	const op errors.Op = "FinishSyncJob"
+
+	job := &SyncJob{
+		Status:   SyncJobSucceeded,
+		Changes:  changes,
+		Finished: time.Now().UnixMilli(),
+	}
+	if syncErr != nil {
+		job.Status = SyncJobFailed
+		job.Changes = nil
+		job.Error = syncErr.Error()
+		if len(job.Error) > maxSyncJobErrorLength {
+			job.Error = job.Error[:maxSyncJobErrorLength]
+		}
+	}
+
+	_, err := Update(h, job, map[string]interface{}{"id": id})
+	if err != nil {
+		return errors.E(pkg, op, err)
+	}
+
+	return nil
+}
+
+// FailUnfinishedSyncJobs fails the jobs left queued or running by a previous run
+func FailUnfinishedSyncJobs[H Handle](h H) error {
+	const op errors.Op = "FailUnfinishedSyncJobs"
+	query := "update sync_job set status = ?, error = ?, finished = ? where status in (?, ?)"
+
+	args := []interface{}{SyncJobFailed, "interrupted by a restart", time.Now().UnixMilli(), SyncJobQueued, SyncJobRunning}
+
+	switch h := any(h).(type) {
+	case *sqlx.Tx:
+		stmt, err := h.Prepare(query)
+		if err != nil {
+			return errors.E(pkg, op, errors.Database, err, "Failed to Prepare Query")
+		}
+
+		_, err = stmt.Exec(args...)
+		if err != nil {
+			return errors.E(pkg, op, errors.Database, err, "Failed to Execute Query")
+		}
+	case *sqlx.DB:
+		_, err := h.Exec(query, args...)
+		if err != nil {
+			return errors.E(pkg, op, errors.Database, err, "Failed to Execute Query")
+		}
+	}
+
+	return nil
+}
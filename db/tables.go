@@ -1,51 +1,63 @@
 package db
 
-import (
-	"fmt"
-)
-
 func getTable(obj interface{}) string {
 	switch obj.(type) {
 	case Community, *Community:
-		return fmt.Sprintf("%s.community", arc53Database())
+		return "community"
 	case CommunityJson, *CommunityJson:
-		return fmt.Sprintf("%s.community_json", arc53Database())
+		return "community_json"
 	case CommunitySettings, *CommunitySettings:
-		return fmt.Sprintf("%s.community_settings", arc53Database())
+		return "community_settings"
 	case CommunityToken, *CommunityToken:
-		return fmt.Sprintf("%s.community_token", arc53Database())
+		return "community_token"
 	case CommunityAssociate, *CommunityAssociate:
-		return fmt.Sprintf("%s.community_associate", arc53Database())
+		return "community_associate"
 	case CommunityFaq, *CommunityFaq:
-		return fmt.Sprintf("%s.community_faq", arc53Database())
+		return "community_faq"
 	case CommunityExtras, *CommunityExtras:
-		return fmt.Sprintf("%s.community_extras", arc53Database())
+		return "community_extras"
 	case Collection, *Collection:
-		return fmt.Sprintf("%s.collection", arc53Database())
+		return "collection"
 	case CollectionSettings, *CollectionSettings:
-		return fmt.Sprintf("%s.collection_settings", arc53Database())
+		return "collection_settings"
 	case CollectionPrefix, *CollectionPrefix:
-		return fmt.Sprintf("%s.collection_prefix", arc53Database())
+		return "collection_prefix"
 	case CollectionAddress, *CollectionAddress:
-		return fmt.Sprintf("%s.collection_address", arc53Database())
+		return "collection_address"
 	case CollectionAsset, *CollectionAsset:
-		return fmt.Sprintf("%s.collection_asset", arc53Database())
+		return "collection_asset"
 	case CollectionExcludedAsset, *CollectionExcludedAsset:
-		return fmt.Sprintf("%s.collection_excluded_asset", arc53Database())
+		return "collection_excluded_asset"
 	case CollectionArtist, *CollectionArtist:
-		return fmt.Sprintf("%s.collection_artist", arc53Database())
+		return "collection_artist"
 	case CollectionExtras, *CollectionExtras:
-		return fmt.Sprintf("%s.collection_extras", arc53Database())
+		return "collection_extras"
 	case Property, *Property:
-		return fmt.Sprintf("%s.property", arc53Database())
+		return "property"
 	case PropertyValue, *PropertyValue:
-		return fmt.Sprintf("%s.property_value", arc53Database())
+		return "property_value"
 	case PropertyValueExtras, *PropertyValueExtras:
-		return fmt.Sprintf("%s.property_value_extras", arc53Database())
+		return "property_value_extras"
 	case Provider, *Provider:
-		return fmt.Sprintf("%s.provider", arc53Database())
+		return "provider"
 	case ProviderAddress, *ProviderAddress:
-		return fmt.Sprintf("%s.provider_address", arc53Database())
+		return "provider_address"
+	case Cursor, *Cursor:
+		return "`cursor`"
+	case CatchupCheckpoint, *CatchupCheckpoint:
+		return "catchup_checkpoint"
+	case SyncFailure, *SyncFailure:
+		return "sync_failure"
+	case DeadLetter, *DeadLetter:
+		return "dead_letter"
+	case SyncJob, *SyncJob:
+		return "sync_job"
+	case Event, *Event:
+		return "event"
+	case Webhook, *Webhook:
+		return "webhook"
+	case WebhookDelivery, *WebhookDelivery:
+		return "webhook_delivery"
 	default:
 		return ""
 	}
@@ -1,5 +1,5 @@
 package db
 
 type DBObject interface {
-	*Community | *CommunityJson | *CommunitySettings | *CommunityAssociate | *CommunityToken | *CommunityFaq | *CommunityExtras | *Collection | *CollectionSettings | *CollectionPrefix | *CollectionAddress | *CollectionArtist | *CollectionAsset | *CollectionExcludedAsset | *CollectionExtras | *Property | *PropertyValue | *PropertyValueExtras | *Provider | *ProviderAddress
+	*Community | *CommunityJson | *CommunitySettings | *CommunityAssociate | *CommunityToken | *CommunityFaq | *CommunityExtras | *Collection | *CollectionSettings | *CollectionPrefix | *CollectionAddress | *CollectionArtist | *CollectionAsset | *CollectionExcludedAsset | *CollectionExtras | *Property | *PropertyValue | *PropertyValueExtras | *Provider | *ProviderAddress | *Cursor | *CatchupCheckpoint | *SyncFailure | *DeadLetter | *SyncJob | *Event | *Webhook | *WebhookDelivery
 }
@@ -0,0 +1,88 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/kylebeee/arc53-watcher-go/errors"
+)
+
+// Webhook is a subscription to the event stream delivered as signed POST requests to its URL
+type Webhook struct {
+	ID  string `structs:"id,omitempty" db:"id" json:"id,omitempty"`
+	URL string `structs:"url,omitempty" db:"url" json:"url,omitempty"`
+	// Secret signs every delivery, it's only shown when the webhook is created
+	Secret string `structs:"secret,omitempty" db:"secret" json:"-"`
+	// ProviderIDs & Types are comma separated filters like the event stream's, empty matches every event
+	ProviderIDs string `structs:"provider_ids,omitempty" db:"provider_ids" json:"provider_ids,omitempty"`
+	Types       string `structs:"types,omitempty" db:"types" json:"types,omitempty"`
+	// LastEventID is the last event queued for the webhook, it only gets events after it
+	LastEventID uint64 `structs:"last_event_id,omitempty" db:"last_event_id" json:"last_event_id"`
+	// Created is a unix millisecond timestamp
+	Created int64 `structs:"created,omitempty" db:"created" json:"created"`
+}
+
+func WebhookTableKeys() []string {
+	return []string{"id", "url", "secret", "provider_ids", "types", "last_event_id", "created"}
+}
+
+func GetWebhook[H Handle](h H, id string) (*Webhook, error) {
+	const op errors.Op = "GetWebhook"
+	query := fmt.Sprintf("select %s from webhook where id = ?", strings.Join(WebhookTableKeys(), ","))
+
+	var webhook Webhook
+	err := h.Get(&webhook, query, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.E(pkg, op, errors.DatabaseResultNotFound, err, "Webhook Not Found")
+		}
+		return nil, errors.E(pkg, op, err)
+	}
+
+	return &webhook, nil
+}
+
+// GetWebhooks lists every webhook, oldest first
+func GetWebhooks[H Handle](h H) (*[]Webhook, error) {
+	const op errors.Op = "GetWebhooks"
+	query := fmt.Sprintf("select %s from webhook order by created asc", strings.Join(WebhookTableKeys(), ","))
+
+	var webhooks []Webhook
+	err := h.Select(&webhooks, query)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.E(pkg, op, errors.DatabaseResultNotFound, err, "Webhooks Not Found")
+		}
+		return nil, errors.E(pkg, op, err)
+	}
+
+	return &webhooks, nil
+}
+
+// AdvanceWebhook moves a webhook's last queued event forward to lastEventID
+func AdvanceWebhook[H Handle](h H, id string, lastEventID uint64) error {
+	const op errors.Op = "AdvanceWebhook"
+	query := "update webhook set last_event_id = ? where id = ? and last_event_id < ?"
+
+	switch h := any(h).(type) {
+	case *sqlx.Tx:
+		stmt, err := h.Prepare(query)
+		if err != nil {
+			return errors.E(pkg, op, errors.Database, err, "Failed to Prepare Query")
+		}
+
+		_, err = stmt.Exec(lastEventID, id, lastEventID)
+		if err != nil {
+			return errors.E(pkg, op, errors.Database, err, "Failed to Execute Query")
+		}
+	case *sqlx.DB:
+		_, err := h.Exec(query, lastEventID, id, lastEventID)
+		if err != nil {
+			return errors.E(pkg, op, errors.Database, err, "Failed to Execute Query")
+		}
+	}
+
+	return nil
+}
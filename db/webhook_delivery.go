@@ -0,0 +1,205 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/kylebeee/arc53-watcher-go/errors"
+)
+
+// webhook delivery statuses, a delivery is pending until it succeeds or runs out of attempts
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliveryDelivered = "delivered"
+	WebhookDeliveryFailed    = "failed"
+)
+
+// maxWebhookDeliveryErrorLength matches the size of the error column
+const maxWebhookDeliveryErrorLength = 1024
+
+// WebhookDelivery is an event queued for a webhook & the log of its attempts
+type WebhookDelivery struct {
+	ID        uint64 `structs:"id,omitempty" db:"id" json:"id,omitempty"`
+	WebhookID string `structs:"webhook_id,omitempty" db:"webhook_id" json:"webhook_id,omitempty"`
+	EventID   uint64 `structs:"event_id,omitempty" db:"event_id" json:"event_id,omitempty"`
+	Status    string `structs:"status,omitempty" db:"status" json:"status,omitempty"`
+	Attempts  uint64 `structs:"attempts,omitempty" db:"attempts" json:"attempts"`
+	// ResponseCode is the http status of the last attempt, zero when no response came back
+	ResponseCode int    `structs:"response_code,omitempty" db:"response_code" json:"response_code,omitempty"`
+	Error        string `structs:"error,omitempty" db:"error" json:"error,omitempty"`
+	// NextAttempt, LastAttempt & Created are unix millisecond timestamps
+	NextAttempt int64 `structs:"next_attempt,omitempty" db:"next_attempt" json:"next_attempt"`
+	LastAttempt int64 `structs:"last_attempt,omitempty" db:"last_attempt" json:"last_attempt,omitempty"`
+	Created     int64 `structs:"created,omitempty" db:"created" json:"created"`
+}
+
+func WebhookDeliveryTableKeys() []string {
+	return []string{"id", "webhook_id", "event_id", "status", "attempts", "response_code", "error", "next_attempt", "last_attempt", "created"}
+}
+
+func GetWebhookDelivery[H Handle](h H, id uint64) (*WebhookDelivery, error) {
+	const op errors.Op = "GetWebhookDelivery"
+	query := fmt.Sprintf("select %s from webhook_delivery where id = ?", strings.Join(WebhookDeliveryTableKeys(), ","))
+
+	var delivery WebhookDelivery
+	err := h.Get(&delivery, query, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.E(pkg, op, errors.DatabaseResultNotFound, err, "Webhook Delivery Not Found")
+		}
+		return nil, errors.E(pkg, op, err)
+	}
+
+	return &delivery, nil
+}
+
+// GetWebhookDeliveries lists a webhook's deliveries, newest first
+func GetWebhookDeliveries[H Handle](h H, webhookID string, start, limit uint64) (*[]WebhookDelivery, error) {
+	const op errors.Op = "GetWebhookDeliveries"
+	query := fmt.Sprintf("select %s from webhook_delivery where webhook_id = ? order by id desc limit ?, ?", strings.Join(WebhookDeliveryTableKeys(), ","))
+
+	var deliveries []WebhookDelivery
+	err := h.Select(&deliveries, query, webhookID, start, limit)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.E(pkg, op, errors.DatabaseResultNotFound, err, "Webhook Deliveries Not Found")
+		}
+		return nil, errors.E(pkg, op, err)
+	}
+
+	return &deliveries, nil
+}
+
+// GetDueWebhookIDs lists the webhooks with pending deliveries whose next attempt is due
+func GetDueWebhookIDs[H Handle](h H, now time.Time, limit uint64) ([]string, error) {
+	const op errors.Op = "GetDueWebhookIDs"
+	query := "select webhook_id from webhook_delivery where status = ? and next_attempt <= ? group by webhook_id order by min(next_attempt) asc limit ?"
+
+	var ids []string
+	err := h.Select(&ids, query, WebhookDeliveryPending, now.UnixMilli(), limit)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.E(pkg, op, errors.DatabaseResultNotFound, err, "Webhooks Not Found")
+		}
+		return nil, errors.E(pkg, op, err)
+	}
+
+	return ids, nil
+}
+
+// GetDueWebhookDeliveries lists a webhook's pending deliveries whose next attempt is due, in event order
+func GetDueWebhookDeliveries[H Handle](h H, webhookID string, now time.Time, limit uint64) (*[]WebhookDelivery, error) {
+	const op errors.Op = "GetDueWebhookDeliveries"
+	query := fmt.Sprintf("select %s from webhook_delivery where webhook_id = ? and status = ? and next_attempt <= ? order by event_id asc limit ?", strings.Join(WebhookDeliveryTableKeys(), ","))
+
+	var deliveries []WebhookDelivery
+	err := h.Select(&deliveries, query, webhookID, WebhookDeliveryPending, now.UnixMilli(), limit)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.E(pkg, op, errors.DatabaseResultNotFound, err, "Webhook Deliveries Not Found")
+		}
+		return nil, errors.E(pkg, op, err)
+	}
+
+	return &deliveries, nil
+}
+
+// QueueWebhookDeliveries queues pending deliveries in a single statement, an event is only
+// queued once per webhook
+func QueueWebhookDeliveries[H Handle](h H, deliveries []WebhookDelivery) error {
+	const op errors.Op = "QueueWebhookDeliveries"
+
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	values := []interface{}{}
+	for _, delivery := range deliveries {
+		values = append(values, delivery.WebhookID, delivery.EventID, WebhookDeliveryPending, delivery.NextAttempt, delivery.Created)
+	}
+	query := fmt.Sprintf("insert ignore into webhook_delivery (webhook_id, event_id, status, next_attempt, created) values %s", strings.TrimSuffix(strings.Repeat("(?, ?, ?, ?, ?), ", len(deliveries)), ", "))
+
+	switch h := any(h).(type) {
+	case *sqlx.Tx:
+		stmt, err := h.Prepare(query)
+		if err != nil {
+			return errors.E(pkg, op, errors.Database, err, "Failed to Prepare Query")
+		}
+
+		_, err = stmt.Exec(values...)
+		if err != nil {
+			return errors.E(pkg, op, errors.Database, err, "Failed to Execute Query")
+		}
+	case *sqlx.DB:
+		_, err := h.Exec(query, values...)
+		if err != nil {
+			return errors.E(pkg, op, errors.Database, err, "Failed to Execute Query")
+		}
+	}
+
+	return nil
+}
+
+// RecordWebhookAttempt records the outcome of a delivery attempt, its new status & when the next one is due
+func RecordWebhookAttempt[H Handle](h H, id uint64, status string, responseCode int, failure error, next time.Time) error {
+	const op errors.Op = "RecordWebhookAttempt"
+	query := "update webhook_delivery set status = ?, attempts = attempts + 1, response_code = ?, error = ?, next_attempt = ?, last_attempt = ? where id = ?"
+
+	message := ""
+	if failure != nil {
+		message = failure.Error()
+		if len(message) > maxWebhookDeliveryErrorLength {
+			message = message[:maxWebhookDeliveryErrorLength]
+		}
+	}
+	args := []interface{}{status, responseCode, message, next.UnixMilli(), time.Now().UnixMilli(), id}
+
+	switch h := any(h).(type) {
+	case *sqlx.Tx:
+		stmt, err := h.Prepare(query)
+		if err != nil {
+			return errors.E(pkg, op, errors.Database, err, "Failed to Prepare Query")
+		}
+
+		_, err = stmt.Exec(args...)
+		if err != nil {
+			return errors.E(pkg, op, errors.Database, err, "Failed to Execute Query")
+		}
+	case *sqlx.DB:
+		_, err := h.Exec(query, args...)
+		if err != nil {
+			return errors.E(pkg, op, errors.Database, err, "Failed to Execute Query")
+		}
+	}
+
+	return nil
+}
+
+// DeleteWebhookDeliveries removes every delivery of a webhook
+func DeleteWebhookDeliveries[H Handle](h H, webhookID string) error {
+	const op errors.Op = "DeleteWebhookDeliveries"
+	query := "delete from webhook_delivery where webhook_id = ?"
+
+	switch h := any(h).(type) {
+	case *sqlx.Tx:
+		stmt, err := h.Prepare(query)
+		if err != nil {
+			return errors.E(pkg, op, errors.Database, err, "Failed to Prepare Query")
+		}
+
+		_, err = stmt.Exec(webhookID)
+		if err != nil {
+			return errors.E(pkg, op, errors.Database, err, "Failed to Execute Query")
+		}
+	case *sqlx.DB:
+		_, err := h.Exec(query, webhookID)
+		if err != nil {
+			return errors.E(pkg, op, errors.Database, err, "Failed to Execute Query")
+		}
+	}
+
+	return nil
+}
@@ -26,6 +26,7 @@ const (
 	DatabaseResultNotFound Kind = "Database Result Not Found"
 	Network                Kind = "Network"
 	Type                   Kind = "Type"
+	Integrity              Kind = "Integrity"
 )
 
 // IsKind unwraps an *Error and checks if its top level kind matches
@@ -41,6 +42,38 @@ func IsKind(err error, kind Kind) bool {
 	return false
 }
 
+// HasKind checks if any error in an *Error chain is of the given kind
+func HasKind(err error, kind Kind) bool {
+	for err != nil {
+		unwrapped, ok := err.(*Error)
+		if !ok {
+			return false
+		}
+
+		if unwrapped.Kind == kind {
+			return true
+		}
+		err = unwrapped.Err
+	}
+	return false
+}
+
+// KindOf returns the first kind set in an *Error chain, empty when none is
+func KindOf(err error) Kind {
+	for err != nil {
+		unwrapped, ok := err.(*Error)
+		if !ok {
+			return ""
+		}
+
+		if unwrapped.Kind != "" {
+			return unwrapped.Kind
+		}
+		err = unwrapped.Err
+	}
+	return ""
+}
+
 // Error is a custom Error struct for quickly diagnosing issues with our app
 type Error struct {
 	Sn   Sn     `json:"server,omitempty"`    // server name
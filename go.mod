@@ -3,6 +3,7 @@ module github.com/kylebeee/arc53-watcher-go
 go 1.22.1
 
 require (
+	github.com/DATA-DOG/go-sqlmock v1.5.2
 	github.com/algorand/go-algorand v0.0.0-20240530171919-f6338578d31e
 	github.com/algorand/go-algorand-sdk/v2 v2.5.0
 	github.com/algorand/go-codec/codec v1.1.10
@@ -15,12 +16,14 @@ require (
 	github.com/open-policy-agent/opa v0.65.0
 	github.com/rs/xid v1.4.0
 	github.com/tidwall/jsonc v0.3.2
+	golang.org/x/net v0.25.0
 	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
 	filippo.io/edwards25519 v1.1.0 // indirect
 	github.com/OneOfOne/xxhash v1.2.8 // indirect
+	github.com/algorand/avm-abi v0.2.0 // indirect
 	github.com/algorand/go-deadlock v0.2.3 // indirect
 	github.com/algorand/msgp v1.1.60 // indirect
 	github.com/aws/aws-sdk-go v1.44.205 // indirect
@@ -51,7 +54,6 @@ require (
 	golang.org/x/arch v0.0.0-20210923205945-b76863e36670 // indirect
 	golang.org/x/crypto v0.23.0 // indirect
 	golang.org/x/exp v0.0.0-20240213143201-ec583247a57a // indirect
-	golang.org/x/net v0.25.0 // indirect
 	golang.org/x/sys v0.20.0 // indirect
 	golang.org/x/text v0.15.0 // indirect
 	google.golang.org/protobuf v1.33.0 // indirect
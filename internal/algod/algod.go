@@ -20,12 +20,12 @@ import (
 	"fmt"
 	"math"
 	"os"
+	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/algorand/go-algorand-sdk/v2/client/v2/algod"
 	"github.com/algorand/go-algorand-sdk/v2/client/v2/common/models"
-	"github.com/algorand/go-algorand-sdk/v2/encoding/msgpack"
 	"github.com/kylebeee/arc53-watcher-go/internal/utils"
 
 	"github.com/algorand/go-algorand-sdk/v2/types"
@@ -52,19 +52,104 @@ type Status struct {
 }
 
 type BlockWrap struct {
-	Block    *types.Block `json: "block"`
+	Block    *types.Block `json:"block"`
 	BlockRaw []byte       `json:"-"`
 	Src      string       `json:"src"`
 	Ts       time.Time    `json:"ts"`
 }
 
-// globalMaxBlock holds the highest read block across all connected nodes
-// writes must use atomic interface
-// reads are safe as the var is 64bit aligned
-var globalMaxBlock uint64 = 0
+// NodeHealth is how a node of a Streamer has been doing
+type NodeHealth struct {
+	Id      string `json:"id"`
+	Address string `json:"address"`
+	// Running is false once the node's goroutine has stopped
+	Running bool `json:"running"`
+	// Primary is set on the node blocks are currently fetched from
+	Primary bool `json:"primary"`
+	// Demoted is set while the node is erroring or behind & can't be primary
+	Demoted bool `json:"demoted"`
+	// LastRound is the last round the node reported in its status
+	LastRound uint64 `json:"last_round"`
+	// LastBlock is the last block fetched from the node
+	LastBlock uint64 `json:"last_block"`
+	// LagRounds is how many rounds the node is behind the furthest node
+	LagRounds uint64 `json:"lag_rounds"`
+	// LagMs is the time since the node's last round as of its last status
+	LagMs int64 `json:"lag_ms"`
+	// LatencyMs is the moving average time it takes the node to serve a block
+	LatencyMs float64 `json:"latency_ms"`
+	// ErrorRate is the moving average share of failed requests, from 0 to 1
+	ErrorRate float64 `json:"error_rate"`
+	// Score ranks the nodes for picking a primary, lower is better
+	Score float64 `json:"score"`
+	// Errors counts the consecutive failed requests to the node
+	Errors    int       `json:"errors"`
+	LastError string    `json:"last_error,omitempty"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+const (
+	// healthAlpha weighs the latest request in the latency & error rate averages
+	healthAlpha = 0.2
+	// demoteErrors is how many requests in a row can fail before a node is demoted
+	demoteErrors = 3
+	// demoteLagRounds is how many rounds a node can fall behind before it's demoted
+	demoteLagRounds = 2
+)
+
+// known is the furthest round the node is known to have
+func (h *NodeHealth) known() uint64 {
+	if h.LastBlock > h.LastRound {
+		return h.LastBlock
+	}
+	return h.LastRound
+}
+
+// Streamer streams blocks from a set of algod nodes. Blocks are only fetched from the
+// primary node, the others follow their status so they're ready to take over once the
+// primary errors or falls behind. All of its state is its own so several streamers
+// can run in one process
+type Streamer struct {
+	cfg *AlgoConfig
+
+	// maxBlock holds the highest read block across all of the streamer's nodes
+	// writes must use atomic interface
+	maxBlock uint64
+	// streamed is set once maxBlock holds a block that was streamed
+	streamed atomic.Bool
+
+	lock   sync.Mutex
+	health []NodeHealth
+	// primary is the index of the node blocks are fetched from, -1 until a node responds
+	primary int
 
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func NewStreamer(acfg *AlgoConfig) *Streamer {
+	health := make([]NodeHealth, len(acfg.ANodes))
+	for i, node := range acfg.ANodes {
+		health[i] = NodeHealth{Id: node.Id, Address: node.Address}
+	}
+
+	return &Streamer{
+		cfg:     acfg,
+		health:  health,
+		primary: -1,
+		done:    make(chan struct{}),
+	}
+}
+
+// AlgoStreamer starts a streamer that runs until ctx is cancelled
 func AlgoStreamer(ctx context.Context, acfg *AlgoConfig) (chan *BlockWrap, chan *Status, error) {
-	qDepth := acfg.Queue
+	return NewStreamer(acfg).Stream(ctx)
+}
+
+// Stream starts the streamer, both channels are closed once ctx is cancelled, Stop is
+// called or every node has passed the last round. A streamer can only be started once
+func (s *Streamer) Stream(ctx context.Context) (chan *BlockWrap, chan *Status, error) {
+	qDepth := s.cfg.Queue
 	if qDepth < 1 {
 		qDepth = 100
 	}
@@ -72,32 +157,70 @@ func AlgoStreamer(ctx context.Context, acfg *AlgoConfig) (chan *BlockWrap, chan
 	bchan := make(chan *BlockWrap, qDepth)
 	schan := make(chan *Status, qDepth)
 
-	for idx := range acfg.ANodes {
-		if err := algodStreamNode(ctx, acfg, idx, bchan, schan, acfg.FRound, acfg.LRound); err != nil {
+	ctx, s.cancel = context.WithCancel(ctx)
+
+	clients := make([]*algod.Client, len(s.cfg.ANodes))
+	for idx, cfg := range s.cfg.ANodes {
+		// Create an algod client
+		algodClient, err := algod.MakeClient(cfg.Address, cfg.Token)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[!ERR][ALGOD][%s] failed to make algod client: %s\n", cfg.Id, err)
+			s.cancel()
+			close(s.done)
 			return nil, nil, err
 		}
+		fmt.Fprintf(os.Stderr, "[INFO][ALGOD][%s] new algod client: %s\n", cfg.Id, cfg.Address)
+		clients[idx] = algodClient
 	}
 
+	// the node that fetches the last round stops the rest, standbys could otherwise wait on
+	// a round that never comes, blocks already queued are still forwarded
+	nodesCtx, stopNodes := context.WithCancel(ctx)
+
+	var nodes sync.WaitGroup
+	for idx := range clients {
+		nodes.Add(1)
+		s.setHealth(idx, func(h *NodeHealth) { h.Running = true })
+		go func(idx int) {
+			defer nodes.Done()
+			defer s.setHealth(idx, func(h *NodeHealth) { h.Running = false })
+			s.streamNode(nodesCtx, stopNodes, idx, clients[idx], bchan, schan, s.cfg.FRound, s.cfg.LRound)
+		}(idx)
+	}
+
+	// nodes are the only writers, the channels close once they've all returned
+	go func() {
+		nodes.Wait()
+		stopNodes()
+		close(bchan)
+		close(schan)
+	}()
+
 	// filter duplicates, forward only first newer blocks.
 	go func() {
+		defer close(s.done)
+		defer close(bestbchan)
+
 		var maxBlock uint64 = math.MaxUint64
 		var maxTs time.Time = time.Now()
 		var maxLeader string = "'"
-		for {
-			select {
-			case bw := <-bchan:
-				if uint64(bw.Block.Round) > maxBlock || maxBlock == math.MaxUint64 {
-					bestbchan <- bw
-					maxBlock = uint64(bw.Block.Round)
-					atomic.StoreUint64(&globalMaxBlock, maxBlock)
-					maxTs = bw.Ts
-					maxLeader = bw.Src
-				} else {
-					if maxBlock == uint64(bw.Block.Round) {
-						fmt.Fprintf(os.Stderr, "[INFO][ALGOD] Block from %s is %v behind %s\n", bw.Src, bw.Ts.Sub(maxTs), maxLeader)
-					}
+		for bw := range bchan {
+			if uint64(bw.Block.Round) > maxBlock || maxBlock == math.MaxUint64 {
+				select {
+				case bestbchan <- bw:
+				case <-ctx.Done():
+					// keep draining so the nodes aren't stuck sending
+					continue
+				}
+				maxBlock = uint64(bw.Block.Round)
+				atomic.StoreUint64(&s.maxBlock, maxBlock)
+				s.streamed.Store(true)
+				maxTs = bw.Ts
+				maxLeader = bw.Src
+			} else {
+				if maxBlock == uint64(bw.Block.Round) {
+					fmt.Fprintf(os.Stderr, "[INFO][ALGOD] Block from %s is %v behind %s\n", bw.Src, bw.Ts.Sub(maxTs), maxLeader)
 				}
-			case <-ctx.Done():
 			}
 		}
 	}()
@@ -105,100 +228,257 @@ func AlgoStreamer(ctx context.Context, acfg *AlgoConfig) (chan *BlockWrap, chan
 	return bestbchan, schan, nil
 }
 
-func algodStreamNode(ctx context.Context, acfg *AlgoConfig, idx int, bchan chan *BlockWrap, schan chan *Status, start int64, stop int64) error {
+// Stop cancels the streamer & waits for its goroutines to finish
+func (s *Streamer) Stop() {
+	if s.cancel == nil {
+		return
+	}
+	s.cancel()
+	<-s.done
+}
+
+// Health reports how each of the streamer's nodes is doing
+func (s *Streamer) Health() []NodeHealth {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	health := make([]NodeHealth, len(s.health))
+	copy(health, s.health)
+	return health
+}
 
-	cfg := acfg.ANodes[idx]
-	// Create an algod client
-	algodClient, err := algod.MakeClient(cfg.Address, cfg.Token)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "[!ERR][ALGOD][%s] failed to make algod client: %s\n", cfg.Id, err)
-		return err
+// Primary is the id of the node blocks are fetched from, empty until a node responds
+func (s *Streamer) Primary() string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.primary < 0 {
+		return ""
 	}
-	fmt.Fprintf(os.Stderr, "[INFO][ALGOD][%s] new algod client: %s\n", cfg.Id, cfg.Address)
+	return s.health[s.primary].Id
+}
 
-	//Loop until Algoverse gets cancelled
-	go func() {
+func (s *Streamer) isPrimary(idx int) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
 
-		var nodeStatus *models.NodeStatus = nil
-		utils.Backoff(ctx, func(actx context.Context) error {
-			fmt.Printf("[INFO][ALGOD][%s] Getting node status", cfg.Id)
-			ns, err := algodClient.Status().Do(actx)
-			if err != nil {
-				return fmt.Errorf("[!ERR][ALGOD][%s] %s\n", cfg.Id, err.Error())
-			}
-			nodeStatus = &ns
-			return nil
-		}, time.Second*10, time.Millisecond*100, time.Second*10)
-		if nodeStatus == nil {
-			fmt.Fprintf(os.Stderr, "[!ERR][ALGOD][%s] Unable to start node\n", cfg.Id)
+	return s.primary == idx
+}
+
+func (s *Streamer) setHealth(idx int, update func(*NodeHealth)) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	update(&s.health[idx])
+	s.rescore()
+}
+
+// nodeRequest records the outcome of a request to a node
+func (s *Streamer) nodeRequest(idx int, latency time.Duration, err error) {
+	s.setHealth(idx, func(h *NodeHealth) {
+		if err != nil {
+			h.Errors++
+			h.LastError = err.Error()
+			h.ErrorRate = h.ErrorRate*(1-healthAlpha) + healthAlpha
 			return
 		}
-		schan <- &Status{NodeId: cfg.Id, LastCP: nodeStatus.LastCatchpoint, LastRound: uint64(nodeStatus.LastRound), LagMs: int64(nodeStatus.TimeSinceLastRound) / int64(time.Millisecond)}
-
-		var nextRound uint64 = 0
-		if start < 0 {
-			nextRound = nodeStatus.LastRound
-			fmt.Fprintf(os.Stderr, "[WARN][ALGOD][%s] Starting from last round : %d\n", cfg.Id, nodeStatus.LastRound)
-		} else {
-			nextRound = uint64(start)
-			fmt.Fprintf(os.Stderr, "[WARN][ALGOD][%s] Starting from fixed round : %d\n", cfg.Id, nextRound)
+
+		h.Errors = 0
+		h.LastError = ""
+		h.ErrorRate = h.ErrorRate * (1 - healthAlpha)
+		h.LastSeen = time.Now()
+		if latency > 0 {
+			ms := float64(latency) / float64(time.Millisecond)
+			if h.LatencyMs == 0 {
+				h.LatencyMs = ms
+			} else {
+				h.LatencyMs = h.LatencyMs*(1-healthAlpha) + ms*healthAlpha
+			}
 		}
+	})
+}
 
-		ustop := uint64(stop)
-		for stop < 0 || nextRound <= ustop {
-			for ; nextRound <= nodeStatus.LastRound; nextRound++ {
-				err := utils.Backoff(ctx, func(actx context.Context) error {
-					gMax := globalMaxBlock
-					//skip old blocks in case other nodes are ahead of us
-					if gMax > nextRound {
-						fmt.Fprintf(os.Stderr, "[WARN][ALGOD][%s] skipping ahead %d blocks to %d\n", cfg.Id, gMax-nextRound, gMax)
-						nextRound = globalMaxBlock
-					}
-					rawBlock, err := algodClient.BlockRaw(nextRound).Do(ctx)
-					if err != nil {
-						return fmt.Errorf("[!ERR][ALGOD][%s] %s", cfg.Id, err.Error())
-					}
-					var response models.BlockResponse
-					msgpack.CodecHandle.ErrorIfNoField = false
-					if err = msgpack.Decode(rawBlock, &response); err != nil {
-						return fmt.Errorf("[!ERR][ALGOD][%s] %s", cfg.Id, err.Error())
-					}
-					block := response.Block
-
-					//fmt.Fprintf(os.Stderr, "got block %d, queue %d\n", block.Round, len(bchan))
-					select {
-					case bchan <- &BlockWrap{
-						Block:    &block,
-						BlockRaw: rawBlock,
-						Ts:       time.Now(),
-						Src:      cfg.Id,
-					}:
-					case <-ctx.Done():
-					}
-					return ctx.Err()
-				}, time.Second*10, time.Millisecond*100, time.Second*10)
-				if err != nil || nextRound >= ustop {
-					return
-				}
+func (s *Streamer) nodeStatus(idx int, status *models.NodeStatus) {
+	s.nodeRequest(idx, 0, nil)
+	s.setHealth(idx, func(h *NodeHealth) {
+		h.LastRound = status.LastRound
+		h.LagMs = int64(status.TimeSinceLastRound) / int64(time.Millisecond)
+	})
+}
+
+// rescore scores & demotes the nodes, a new primary is only picked once the current one
+// is demoted so blocks don't flap between nodes, the lock must be held
+func (s *Streamer) rescore() {
+	var furthest uint64
+	for i := range s.health {
+		if known := s.health[i].known(); known > furthest {
+			furthest = known
+		}
+	}
+
+	best := -1
+	for i := range s.health {
+		h := &s.health[i]
+		h.LagRounds = furthest - h.known()
+		h.Score = h.LatencyMs + h.ErrorRate*10000 + float64(h.LagRounds)*1000
+		h.Demoted = !h.Running || h.LastSeen.IsZero() || h.Errors >= demoteErrors || h.LagRounds > demoteLagRounds
+
+		if !h.Demoted && (best < 0 || h.Score < s.health[best].Score) {
+			best = i
+		}
+	}
+
+	if s.primary >= 0 && !s.health[s.primary].Demoted {
+		return
+	}
+
+	if s.primary >= 0 {
+		s.health[s.primary].Primary = false
+	}
+	if best >= 0 && best != s.primary && s.primary >= 0 {
+		fmt.Fprintf(os.Stderr, "[WARN][ALGOD] failing over from %s to %s\n", s.health[s.primary].Id, s.health[best].Id)
+	}
+
+	s.primary = best
+	if best >= 0 {
+		s.health[best].Primary = true
+	}
+}
+
+// sendStatus hands a status over unless the streamer is stopping
+func sendStatus(ctx context.Context, schan chan *Status, status *Status) {
+	select {
+	case schan <- status:
+	case <-ctx.Done():
+	}
+}
+
+// committed is the highest round streamed so far, false until a block has been streamed
+func (s *Streamer) committed() (uint64, bool) {
+	if !s.streamed.Load() {
+		return 0, false
+	}
+	return atomic.LoadUint64(&s.maxBlock), true
+}
+
+func (s *Streamer) streamNode(ctx context.Context, stopNodes context.CancelFunc, idx int, algodClient *algod.Client, bchan chan *BlockWrap, schan chan *Status, start int64, stop int64) {
+	cfg := s.cfg.ANodes[idx]
+
+	//Loop until Algoverse gets cancelled
+	var nodeStatus *models.NodeStatus = nil
+	utils.Backoff(ctx, func(actx context.Context) error {
+		fmt.Printf("[INFO][ALGOD][%s] Getting node status", cfg.Id)
+		ns, err := algodClient.Status().Do(actx)
+		if err != nil {
+			s.nodeRequest(idx, 0, err)
+			return fmt.Errorf("[!ERR][ALGOD][%s] %s\n", cfg.Id, err.Error())
+		}
+		nodeStatus = &ns
+		s.nodeStatus(idx, nodeStatus)
+		return nil
+	}, time.Second*10, time.Millisecond*100, time.Second*10)
+	if nodeStatus == nil {
+		fmt.Fprintf(os.Stderr, "[!ERR][ALGOD][%s] Unable to start node\n", cfg.Id)
+		return
+	}
+	sendStatus(ctx, schan, &Status{NodeId: cfg.Id, LastCP: nodeStatus.LastCatchpoint, LastRound: uint64(nodeStatus.LastRound), LagMs: int64(nodeStatus.TimeSinceLastRound) / int64(time.Millisecond)})
+
+	var nextRound uint64 = 0
+	if start < 0 {
+		nextRound = nodeStatus.LastRound
+		fmt.Fprintf(os.Stderr, "[WARN][ALGOD][%s] Starting from last round : %d\n", cfg.Id, nodeStatus.LastRound)
+	} else {
+		nextRound = uint64(start)
+		fmt.Fprintf(os.Stderr, "[WARN][ALGOD][%s] Starting from fixed round : %d\n", cfg.Id, nextRound)
+	}
+
+	ustop := uint64(stop)
+	for stop < 0 || nextRound <= ustop {
+		for ; nextRound <= nodeStatus.LastRound; nextRound++ {
+			// standby nodes only follow their status until they're made primary
+			if !s.isPrimary(idx) {
+				break
 			}
 
+			demoted := false
 			err := utils.Backoff(ctx, func(actx context.Context) error {
-				newStatus, err := algodClient.StatusAfterBlock(nodeStatus.LastRound).Do(actx)
+				// stop retrying once another node has taken over
+				if !s.isPrimary(idx) {
+					demoted = true
+					return nil
+				}
+
+				gMax := atomic.LoadUint64(&s.maxBlock)
+				//skip old blocks in case other nodes are ahead of us
+				if gMax > nextRound {
+					fmt.Fprintf(os.Stderr, "[WARN][ALGOD][%s] skipping ahead %d blocks to %d\n", cfg.Id, gMax-nextRound, gMax)
+					nextRound = gMax
+				}
+				requested := time.Now()
+				rawBlock, err := algodClient.BlockRaw(nextRound).Do(ctx)
 				if err != nil {
+					s.nodeRequest(idx, 0, err)
 					return fmt.Errorf("[!ERR][ALGOD][%s] %s", cfg.Id, err.Error())
 				}
-				nodeStatus = &newStatus
-				//fmt.Fprintf(os.Stderr, "algod last round: %d, lag: %s\n", nodeStatus.LastRound, time.Duration(nodeStatus.TimeSinceLastRound)*time.Nanosecond)
-				schan <- &Status{NodeId: cfg.Id, LastRound: uint64(nodeStatus.LastRound), LagMs: int64(nodeStatus.TimeSinceLastRound) / int64(time.Millisecond)}
-				return nil
-			}, time.Second*10, time.Millisecond*100, time.Second*10)
+				block, err := DecodeBlock(rawBlock)
+				if err != nil {
+					s.nodeRequest(idx, 0, err)
+					return fmt.Errorf("[!ERR][ALGOD][%s] %s", cfg.Id, err.Error())
+				}
+				s.nodeRequest(idx, time.Since(requested), nil)
+				s.setHealth(idx, func(h *NodeHealth) { h.LastBlock = uint64(block.Round) })
 
+				//fmt.Fprintf(os.Stderr, "got block %d, queue %d\n", block.Round, len(bchan))
+				select {
+				case bchan <- &BlockWrap{
+					Block:    block,
+					BlockRaw: rawBlock,
+					Ts:       time.Now(),
+					Src:      cfg.Id,
+				}:
+				case <-ctx.Done():
+				}
+				return ctx.Err()
+			}, time.Second*10, time.Millisecond*100, time.Second*10)
 			if err != nil {
 				return
 			}
+			// the round wasn't fetched, keep it for when the node is primary again
+			if demoted {
+				break
+			}
+			if stop >= 0 && nextRound >= ustop {
+				stopNodes()
+				return
+			}
+		}
 
+		// standbys keep up with the rounds the primary streamed so they take over from
+		// there & stop along with it once it's past the last round
+		if !s.isPrimary(idx) {
+			if committed, ok := s.committed(); ok && committed >= nextRound {
+				nextRound = committed + 1
+			}
+			if stop >= 0 && nextRound > ustop {
+				return
+			}
 		}
-	}()
 
-	return nil
+		err := utils.Backoff(ctx, func(actx context.Context) error {
+			newStatus, err := algodClient.StatusAfterBlock(nodeStatus.LastRound).Do(actx)
+			if err != nil {
+				s.nodeRequest(idx, 0, err)
+				return fmt.Errorf("[!ERR][ALGOD][%s] %s", cfg.Id, err.Error())
+			}
+			nodeStatus = &newStatus
+			s.nodeStatus(idx, nodeStatus)
+			//fmt.Fprintf(os.Stderr, "algod last round: %d, lag: %s\n", nodeStatus.LastRound, time.Duration(nodeStatus.TimeSinceLastRound)*time.Nanosecond)
+			sendStatus(ctx, schan, &Status{NodeId: cfg.Id, LastRound: uint64(nodeStatus.LastRound), LagMs: int64(nodeStatus.TimeSinceLastRound) / int64(time.Millisecond)})
+			return nil
+		}, time.Second*10, time.Millisecond*100, time.Second*10)
+
+		if err != nil {
+			return
+		}
+	}
 }
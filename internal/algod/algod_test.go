@@ -0,0 +1,115 @@
+package algod
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/algorand/go-algorand-sdk/v2/client/v2/common/models"
+	"github.com/algorand/go-algorand-sdk/v2/encoding/msgpack"
+	"github.com/algorand/go-algorand-sdk/v2/types"
+)
+
+// fakeNode serves the status & blocks of a chain at lastRound, a stalled node holds
+// status waits open until the client gives up the way a node with no new rounds does
+type fakeNode struct {
+	*httptest.Server
+	lastRound atomic.Uint64
+	stalled   bool
+}
+
+func newFakeNode(t *testing.T, lastRound uint64, stalled bool) *fakeNode {
+	t.Helper()
+
+	n := &fakeNode{stalled: stalled}
+	n.lastRound.Store(lastRound)
+	n.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/v2/status":
+			json.NewEncoder(w).Encode(models.NodeStatus{LastRound: n.lastRound.Load()})
+		case strings.HasPrefix(r.URL.Path, "/v2/status/wait-for-block-after/"):
+			if n.stalled {
+				<-r.Context().Done()
+				return
+			}
+			// a moving chain makes a new round every few milliseconds
+			time.Sleep(5 * time.Millisecond)
+			json.NewEncoder(w).Encode(models.NodeStatus{LastRound: n.lastRound.Add(1)})
+		case strings.HasPrefix(r.URL.Path, "/v2/blocks/"):
+			var round uint64
+			fmt.Sscan(strings.TrimPrefix(r.URL.Path, "/v2/blocks/"), &round)
+			if round > n.lastRound.Load() {
+				http.NotFound(w, r)
+				return
+			}
+			w.Write(msgpack.Encode(models.BlockResponse{Block: types.Block{BlockHeader: types.BlockHeader{Round: types.Round(round)}}}))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(n.Close)
+
+	return n
+}
+
+func TestStreamerStopsAtLastRoundWithStandbys(t *testing.T) {
+	tests := []struct {
+		name    string
+		stalled bool
+	}{
+		{name: "standby waiting on a chain with no new rounds", stalled: true},
+		{name: "standby following a moving chain", stalled: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nodes := []*AlgoNodeConfig{}
+			for i := 0; i < 2; i++ {
+				n := newFakeNode(t, 10, tt.stalled)
+				nodes = append(nodes, &AlgoNodeConfig{Id: fmt.Sprintf("node%d", i), Address: n.URL})
+			}
+
+			s := NewStreamer(&AlgoConfig{FRound: 5, LRound: 8, ANodes: nodes})
+			blocks, status, err := s.Stream(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer s.Stop()
+
+			go func() {
+				for range status {
+				}
+			}()
+
+			got := []uint64{}
+			timeout := time.After(5 * time.Second)
+			for done := false; !done; {
+				select {
+				case b, ok := <-blocks:
+					if !ok {
+						done = true
+						break
+					}
+					got = append(got, uint64(b.Block.Round))
+				case <-timeout:
+					t.Fatalf("stream still open after %v, want it closed past the last round", got)
+				}
+			}
+
+			if fmt.Sprint(got) != fmt.Sprint([]uint64{5, 6, 7, 8}) {
+				t.Fatalf("streamed rounds %v, want 5 - 8", got)
+			}
+			for _, h := range s.Health() {
+				if h.Running {
+					t.Fatalf("node %s still running after the stream closed", h.Id)
+				}
+			}
+		})
+	}
+}
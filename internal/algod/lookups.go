@@ -0,0 +1,231 @@
+package algod
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// LookupExt is the extension of the lookup logs a Recorder keeps beside its segments, a
+// segment's lookups are every algod response read while its blocks were processed, one
+// JSON line per response stamped with the round being processed
+const LookupExt = ".lookups"
+
+// lookup is a recorded algod response
+type lookup struct {
+	Round       uint64 `json:"round"`
+	Key         string `json:"key"`
+	Status      int    `json:"status"`
+	ContentType string `json:"content_type,omitempty"`
+	Body        []byte `json:"body"`
+}
+
+// lookupKey identifies a request, the same call made while replaying has the same key
+func lookupKey(req *http.Request) string {
+	return req.Method + " " + req.URL.RequestURI()
+}
+
+// lookupsFile is the lookup log of a segment
+func lookupsFile(segment string) string {
+	return strings.TrimSuffix(segment, SegmentExt) + LookupExt
+}
+
+// recordable is whether a request's response belongs in the lookup log, blocks are
+// already in the segments
+func recordable(req *http.Request) bool {
+	return req.Method == http.MethodGet && !strings.HasPrefix(req.URL.Path, "/v2/blocks/")
+}
+
+// Transport wraps next so every algod lookup made while processing a recorded block is
+// written to the lookup log of the segment that block is in, lookups made before the
+// first block is recorded aren't kept
+func (r *Recorder) Transport(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &recordingTransport{recorder: r, next: next}
+}
+
+type recordingTransport struct {
+	recorder *Recorder
+	next     http.RoundTripper
+}
+
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil || !recordable(req) {
+		return resp, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	err = t.recorder.recordLookup(&lookup{
+		Key:         lookupKey(req),
+		Status:      resp.StatusCode,
+		ContentType: resp.Header.Get("Content-Type"),
+		Body:        body,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[!ERR][RECORD] %s\n", err)
+	}
+
+	return resp, nil
+}
+
+// recordLookup appends l to the current lookup log stamped with the last recorded round
+func (r *Recorder) recordLookup(l *lookup) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.lookups == nil {
+		return nil
+	}
+	l.Round = r.lastRound
+
+	line, err := json.Marshal(l)
+	if err != nil {
+		return err
+	}
+	_, err = r.lookups.Write(append(line, '\n'))
+	return err
+}
+
+// ReplayTransport serves the lookups a Recorder logged in place of algod. Once At is told
+// the round being replayed a request gets the responses recorded for it while that round
+// was processed, in the order they were recorded with the last repeated, & the newest
+// recorded before it when there are none, the state the recording had by then. A lookup
+// that wasn't recorded is an error rather than a call to algod so a replay only ever
+// sees recorded state
+type ReplayTransport struct {
+	lock sync.Mutex
+	// byKey holds each request's lookups in the order they were recorded
+	byKey map[string][]*lookup
+	round uint64
+	// served is how many of each request's lookups at round have been served
+	served map[string]int
+}
+
+// NewReplayTransport loads the lookup logs of the segments in dir
+func NewReplayTransport(dir string) (*ReplayTransport, error) {
+	segments, err := Segments(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &ReplayTransport{byKey: map[string][]*lookup{}, served: map[string]int{}}
+	for _, path := range segments {
+		lookups, err := readLookups(lookupsFile(path))
+		if err != nil {
+			return nil, err
+		}
+		for _, l := range lookups {
+			t.byKey[l.Key] = append(t.byKey[l.Key], l)
+		}
+	}
+
+	return t, nil
+}
+
+// At serves the lookups recorded while round was processed from here on
+func (t *ReplayTransport) At(round uint64) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.round = round
+	t.served = map[string]int{}
+}
+
+// lookupFor picks the lookup to serve for key at the current round, nil when there's none
+func (t *ReplayTransport) lookupFor(key string) (*lookup, uint64) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	lookups := t.byKey[key]
+	first := sort.Search(len(lookups), func(i int) bool { return lookups[i].Round >= t.round })
+	end := sort.Search(len(lookups), func(i int) bool { return lookups[i].Round > t.round })
+
+	if first < end {
+		i := first + t.served[key]
+		if i >= end {
+			i = end - 1
+		}
+		t.served[key]++
+		return lookups[i], t.round
+	}
+	if first > 0 {
+		return lookups[first-1], t.round
+	}
+	return nil, t.round
+}
+
+func (t *ReplayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+
+	key := lookupKey(req)
+	l, round := t.lookupFor(key)
+	if l == nil {
+		return nil, fmt.Errorf("lookup %s wasn't recorded by round %d", key, round)
+	}
+
+	header := http.Header{}
+	if l.ContentType != "" {
+		header.Set("Content-Type", l.ContentType)
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", l.Status, http.StatusText(l.Status)),
+		StatusCode:    l.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(l.Body)),
+		ContentLength: int64(len(l.Body)),
+		Request:       req,
+	}, nil
+}
+
+// readLookups reads a lookup log, a missing log is empty & a partial last line left by a crash is dropped
+func readLookups(path string) ([]*lookup, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	lookups := []*lookup{}
+	reader := bufio.NewReader(f)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			return lookups, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		l := &lookup{}
+		err = json.Unmarshal(line, l)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		lookups = append(lookups, l)
+	}
+}
@@ -0,0 +1,239 @@
+package algod
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	sdkalgod "github.com/algorand/go-algorand-sdk/v2/client/v2/algod"
+	"github.com/algorand/go-algorand-sdk/v2/client/v2/common/models"
+	"github.com/algorand/go-algorand-sdk/v2/encoding/msgpack"
+	"github.com/algorand/go-algorand-sdk/v2/types"
+)
+
+// emptyBlock wraps the msgpack block response algod serves for an empty block at round
+func emptyBlock(t *testing.T, round uint64, ts time.Time) *BlockWrap {
+	t.Helper()
+
+	raw := msgpack.Encode(models.BlockResponse{Block: types.Block{BlockHeader: types.BlockHeader{Round: types.Round(round)}}})
+	block, err := DecodeBlock(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &BlockWrap{Block: block, BlockRaw: raw, Ts: ts}
+}
+
+// changingAlgod serves apps whose global state is the round it's at & the round as its status
+type changingAlgod struct {
+	*httptest.Server
+	round    atomic.Uint64
+	requests atomic.Int64
+}
+
+func newChangingAlgod(t *testing.T) *changingAlgod {
+	t.Helper()
+
+	f := &changingAlgod{}
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.requests.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+
+		round := f.round.Load()
+		switch {
+		case r.URL.Path == "/v2/status":
+			json.NewEncoder(w).Encode(models.NodeStatus{LastRound: round})
+		case strings.HasPrefix(r.URL.Path, "/v2/applications/"):
+			var id uint64
+			fmt.Sscan(strings.TrimPrefix(r.URL.Path, "/v2/applications/"), &id)
+			json.NewEncoder(w).Encode(models.Application{Id: id, Params: models.ApplicationParams{GlobalState: []models.TealKeyValue{{
+				Key:   base64.StdEncoding.EncodeToString([]byte("round")),
+				Value: models.TealValue{Type: 2, Uint: round},
+			}}}})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(f.Close)
+
+	return f
+}
+
+// observed is what a lookup of an app saw
+type observed struct {
+	status uint64
+	state  uint64
+}
+
+func lookUp(t *testing.T, client *sdkalgod.Client, appID uint64) observed {
+	t.Helper()
+
+	status, err := client.Status().Do(context.Background())
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	app, err := client.GetApplicationByID(appID).Do(context.Background())
+	if err != nil {
+		t.Fatalf("GetApplicationByID(%d): %v", appID, err)
+	}
+	return observed{status: status.LastRound, state: app.Params.GlobalState[0].Value.Uint}
+}
+
+func TestRecordReplayLookups(t *testing.T) {
+	dir := t.TempDir()
+	node := newChangingAlgod(t)
+
+	// record rounds 10 - 12 looking up app 1001 after each block & app 1002 only after the first,
+	// the node's state moves on between blocks the way a live node's does
+	recorder, err := NewRecorder(RecorderConfig{Dir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := sdkalgod.MakeClientWithTransport(node.URL, "", nil, recorder.Transport(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recorded := map[uint64]observed{}
+	var only1002 observed
+	for round := uint64(10); round <= 12; round++ {
+		node.round.Store(round + 100)
+
+		err = recorder.Record(emptyBlock(t, round, time.Unix(int64(round), 0)))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		recorded[round] = lookUp(t, client, 1001)
+		if round == 10 {
+			only1002 = lookUp(t, client, 1002)
+		}
+
+		// the state changes again before the next block, a replay has to see what was read
+		node.round.Store(round + 200)
+	}
+	err = recorder.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the node is gone, a replay has to be served entirely from the recording
+	node.Close()
+	requests := node.requests.Load()
+
+	lookups, err := NewReplayTransport(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err = sdkalgod.MakeClientWithTransport(node.URL, "", nil, lookups)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	replay := func(first uint64) map[uint64]observed {
+		blocks, _, err := (&ReplaySource{Dir: dir, First: first}).Stream(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		replayed := map[uint64]observed{}
+		for b := range blocks {
+			lookups.At(uint64(b.Block.Round))
+			replayed[uint64(b.Block.Round)] = lookUp(t, client, 1001)
+		}
+		return replayed
+	}
+
+	tests := []struct {
+		name  string
+		first uint64
+	}{
+		{name: "whole recording", first: 10},
+		{name: "whole recording again", first: 10},
+		{name: "part way through", first: 11},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			replayed := replay(tt.first)
+
+			for round := tt.first; round <= 12; round++ {
+				if replayed[round] != recorded[round] {
+					t.Fatalf("round %d replayed %+v, recorded %+v", round, replayed[round], recorded[round])
+				}
+			}
+			if len(replayed) != int(13-tt.first) {
+				t.Fatalf("replayed %d rounds, want %d", len(replayed), 13-tt.first)
+			}
+		})
+	}
+
+	t.Run("lookups from before the first round serve the state read then", func(t *testing.T) {
+		lookups.At(12)
+
+		got := lookUp(t, client, 1002)
+		if got.state != only1002.state {
+			t.Fatalf("app 1002 replayed state %d, recorded %d", got.state, only1002.state)
+		}
+	})
+
+	t.Run("a lookup that wasn't recorded fails", func(t *testing.T) {
+		_, err := client.GetApplicationByID(1003).Do(context.Background())
+		if err == nil || !strings.Contains(err.Error(), "wasn't recorded") {
+			t.Fatalf("GetApplicationByID error %v, want the lookup not recorded", err)
+		}
+	})
+
+	if n := node.requests.Load(); n != requests {
+		t.Fatalf("the node got %d requests while replaying", n-requests)
+	}
+}
+
+func TestRecorderPrunesLookups(t *testing.T) {
+	dir := t.TempDir()
+
+	// every block rolls a new segment & only the newest is kept
+	recorder, err := NewRecorder(RecorderConfig{Dir: dir, SegmentBytes: 1, MaxSegments: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer recorder.Close()
+
+	for round := uint64(1); round <= 3; round++ {
+		err = recorder.Record(emptyBlock(t, round, time.Now()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = recorder.recordLookup(&lookup{Key: "GET /v2/status", Status: http.StatusOK, Body: []byte("{}")})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	segments, err := Segments(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(segments) != 1 {
+		t.Fatalf("%d segments kept, want 1", len(segments))
+	}
+
+	for round := uint64(1); round <= 3; round++ {
+		lookups, err := readLookups(lookupsFile(fmt.Sprintf("%s/%d%s", dir, round, SegmentExt)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if round < 3 && len(lookups) != 0 {
+			t.Fatalf("round %d's lookups kept after its segment was pruned", round)
+		}
+		if round == 3 && (len(lookups) != 1 || lookups[0].Round != 3) {
+			t.Fatalf("round 3's lookups = %+v, want the one lookup stamped round 3", lookups)
+		}
+	}
+}
@@ -0,0 +1,378 @@
+package algod
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SegmentExt is the extension of the segment files a Recorder writes, each segment is
+// named after the first round in it & holds a run of records, a record being the round
+// (uint64), the time the block was received (unix nanoseconds, int64) & the length of
+// the raw block (uint32), all big endian, followed by the raw msgpack block itself
+const SegmentExt = ".blocks"
+
+const recordHeaderSize = 8 + 8 + 4
+
+const defaultSegmentBytes = 64 << 20
+
+// RecorderConfig configures where & how much a Recorder keeps
+type RecorderConfig struct {
+	// Dir is the directory segments are written to
+	Dir string `json:"dir"`
+	// SegmentBytes is the size a segment grows to before a new one is started, 64MB when zero
+	SegmentBytes int64 `json:"segment_bytes"`
+	// MaxSegments is how many segments are kept before the oldest is removed, zero keeps them all
+	MaxSegments int `json:"max_segments"`
+}
+
+// Recorder writes the raw blocks the watcher processes to rolling segment files
+type Recorder struct {
+	cfg RecorderConfig
+
+	lock      sync.Mutex
+	segment   *os.File
+	lookups   *os.File
+	size      int64
+	lastRound uint64
+	recorded  bool
+}
+
+// NewRecorder opens a recorder on cfg.Dir, appending to the newest segment there
+func NewRecorder(cfg RecorderConfig) (*Recorder, error) {
+	if cfg.Dir == "" {
+		return nil, fmt.Errorf("recorder dir is empty")
+	}
+	if cfg.SegmentBytes <= 0 {
+		cfg.SegmentBytes = defaultSegmentBytes
+	}
+
+	err := os.MkdirAll(cfg.Dir, 0o755)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Recorder{cfg: cfg}
+
+	segments, err := Segments(cfg.Dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(segments) == 0 {
+		return r, nil
+	}
+
+	// pick up after the last complete record, a partial one left by a crash is cut off
+	path := segments[len(segments)-1]
+	f, err := os.OpenFile(path, os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	var end int64
+	err = readSegment(f, func(round uint64, _ time.Time, _ []byte, next int64) (bool, error) {
+		r.lastRound = round
+		r.recorded = true
+		end = next
+		return true, nil
+	})
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	err = f.Truncate(end)
+	if err == nil {
+		_, err = f.Seek(end, io.SeekStart)
+	}
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	r.lookups, err = os.OpenFile(lookupsFile(path), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	r.segment = f
+	r.size = end
+
+	return r, nil
+}
+
+// Record appends a block to the current segment, rolling to a new segment once it's full.
+// Rounds at or before the last recorded round are skipped so rewinds don't record twice
+func (r *Recorder) Record(bw *BlockWrap) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	round := uint64(bw.Block.Round)
+	if r.recorded && round <= r.lastRound {
+		return nil
+	}
+	if len(bw.BlockRaw) == 0 {
+		return fmt.Errorf("block %d has no raw block to record", round)
+	}
+
+	if r.segment == nil || r.size >= r.cfg.SegmentBytes {
+		err := r.roll(round)
+		if err != nil {
+			return err
+		}
+	}
+
+	header := make([]byte, recordHeaderSize)
+	binary.BigEndian.PutUint64(header[0:8], round)
+	binary.BigEndian.PutUint64(header[8:16], uint64(bw.Ts.UnixNano()))
+	binary.BigEndian.PutUint32(header[16:20], uint32(len(bw.BlockRaw)))
+
+	n, err := r.segment.Write(append(header, bw.BlockRaw...))
+	r.size += int64(n)
+	if err != nil {
+		return err
+	}
+
+	r.lastRound = round
+	r.recorded = true
+
+	return nil
+}
+
+// roll closes the current segment, starts a new one at round & prunes the oldest segments
+func (r *Recorder) roll(round uint64) error {
+	err := r.closeSegment()
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(r.cfg.Dir, strconv.FormatUint(round, 10)+SegmentExt)
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	r.segment = f
+	r.size = 0
+
+	r.lookups, err = os.OpenFile(lookupsFile(path), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+
+	if r.cfg.MaxSegments <= 0 {
+		return nil
+	}
+
+	segments, err := Segments(r.cfg.Dir)
+	if err != nil {
+		return err
+	}
+	for len(segments) > r.cfg.MaxSegments {
+		err = os.Remove(segments[0])
+		if err != nil {
+			return err
+		}
+		err = os.Remove(lookupsFile(segments[0]))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		segments = segments[1:]
+	}
+
+	return nil
+}
+
+// Close closes the current segment
+func (r *Recorder) Close() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return r.closeSegment()
+}
+
+// closeSegment closes the current segment & its lookup log
+func (r *Recorder) closeSegment() error {
+	var err error
+	if r.segment != nil {
+		err = r.segment.Close()
+		r.segment = nil
+	}
+	if r.lookups != nil {
+		lerr := r.lookups.Close()
+		if err == nil {
+			err = lerr
+		}
+		r.lookups = nil
+	}
+	return err
+}
+
+// Segments lists the segment files in dir from oldest to newest
+func Segments(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	type segment struct {
+		round uint64
+		path  string
+	}
+
+	segments := []segment{}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, SegmentExt) {
+			continue
+		}
+
+		round, err := strconv.ParseUint(strings.TrimSuffix(name, SegmentExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		segments = append(segments, segment{round, filepath.Join(dir, name)})
+	}
+	sort.Slice(segments, func(i, j int) bool { return segments[i].round < segments[j].round })
+
+	paths := make([]string, len(segments))
+	for i := range segments {
+		paths[i] = segments[i].path
+	}
+
+	return paths, nil
+}
+
+// readSegment hands each complete record of a segment to fn along with the offset the
+// next record starts at, it stops at the first partial record or when fn returns false
+func readSegment(f *os.File, fn func(round uint64, ts time.Time, raw []byte, next int64) (bool, error)) error {
+	_, err := f.Seek(0, io.SeekStart)
+	if err != nil {
+		return err
+	}
+
+	reader := bufio.NewReader(f)
+	header := make([]byte, recordHeaderSize)
+
+	var offset int64
+	for {
+		_, err := io.ReadFull(reader, header)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		round := binary.BigEndian.Uint64(header[0:8])
+		ts := time.Unix(0, int64(binary.BigEndian.Uint64(header[8:16])))
+		raw := make([]byte, binary.BigEndian.Uint32(header[16:20]))
+
+		_, err = io.ReadFull(reader, raw)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		offset += int64(recordHeaderSize + len(raw))
+		more, err := fn(round, ts, raw, offset)
+		if err != nil || !more {
+			return err
+		}
+	}
+}
+
+// BlockSource produces the blocks & node statuses the watcher consumes, a Streamer
+// follows algod & a ReplaySource replays recorded blocks
+type BlockSource interface {
+	Stream(ctx context.Context) (chan *BlockWrap, chan *Status, error)
+}
+
+// ReplaySource replays the blocks a Recorder wrote, in round order & with the times
+// they were originally received, the channels are closed once the replay is done
+type ReplaySource struct {
+	// Dir is the recorder directory to replay
+	Dir string
+	// First is the first round replayed
+	First uint64
+	// Last is the last round replayed, zero replays everything after First
+	Last uint64
+}
+
+func (s *ReplaySource) Stream(ctx context.Context) (chan *BlockWrap, chan *Status, error) {
+	segments, err := Segments(s.Dir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	bchan := make(chan *BlockWrap)
+	schan := make(chan *Status)
+
+	go func() {
+		defer close(schan)
+		defer close(bchan)
+
+		var (
+			replayed  bool
+			lastRound uint64
+		)
+		for _, path := range segments {
+			f, err := os.Open(path)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "[!ERR][REPLAY] %s\n", err)
+				return
+			}
+
+			err = readSegment(f, func(round uint64, ts time.Time, raw []byte, _ int64) (bool, error) {
+				if s.Last != 0 && round > s.Last {
+					return false, nil
+				}
+				// segments can overlap after a restart, each round is only replayed once
+				if round < s.First || (replayed && round <= lastRound) {
+					return true, nil
+				}
+
+				block, err := DecodeBlock(raw)
+				if err != nil {
+					return false, fmt.Errorf("round %d: %w", round, err)
+				}
+
+				select {
+				case bchan <- &BlockWrap{
+					Block:    block,
+					BlockRaw: raw,
+					Ts:       ts,
+					Src:      "replay",
+				}:
+				case <-ctx.Done():
+					return false, nil
+				}
+
+				replayed = true
+				lastRound = round
+				return true, nil
+			})
+			f.Close()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "[!ERR][REPLAY] %s: %s\n", path, err)
+				return
+			}
+			if ctx.Err() != nil || (s.Last != 0 && replayed && lastRound >= s.Last) {
+				return
+			}
+		}
+	}()
+
+	return bchan, schan, nil
+}
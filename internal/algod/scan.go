@@ -0,0 +1,136 @@
+package algod
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/algorand/go-algorand-sdk/v2/client/v2/common/models"
+	"github.com/algorand/go-algorand-sdk/v2/encoding/msgpack"
+	"github.com/algorand/go-algorand-sdk/v2/types"
+)
+
+// BlockFileExt is the extension of the files in a block archive, each file is named
+// after its round & holds the raw msgpack block as served by algod's block endpoint
+const BlockFileExt = ".msgpack"
+
+// DecodeBlock decodes a raw msgpack block response from algod
+func DecodeBlock(raw []byte) (*types.Block, error) {
+	var response models.BlockResponse
+	msgpack.CodecHandle.ErrorIfNoField = false
+	if err := msgpack.Decode(raw, &response); err != nil {
+		return nil, err
+	}
+	return &response.Block, nil
+}
+
+// ScanBlocks streams the blocks from acfg.FRound through acfg.LRound & hands them to fn
+// in order, it returns once the last round has been handled or fn fails
+func ScanBlocks(ctx context.Context, acfg *AlgoConfig, fn func(*BlockWrap) error) error {
+	if acfg.FRound < 0 || acfg.LRound < acfg.FRound {
+		return fmt.Errorf("invalid block scan range %d - %d", acfg.FRound, acfg.LRound)
+	}
+
+	s := NewStreamer(acfg)
+	blocks, status, err := s.Stream(ctx)
+	if err != nil {
+		return err
+	}
+	defer s.Stop()
+
+	for {
+		select {
+		case _, ok := <-status:
+			if !ok {
+				status = nil
+			}
+		case bw, ok := <-blocks:
+			if !ok {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				return fmt.Errorf("block stream ended before round %d", acfg.LRound)
+			}
+			err := fn(bw)
+			if err != nil {
+				return err
+			}
+			if int64(bw.Block.Round) >= acfg.LRound {
+				return nil
+			}
+		}
+	}
+}
+
+// ArchiveRounds lists the rounds of the blocks in a block archive directory in order
+func ArchiveRounds(dir string) ([]uint64, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	rounds := []uint64{}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, BlockFileExt) {
+			continue
+		}
+
+		round, err := strconv.ParseUint(strings.TrimSuffix(name, BlockFileExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		rounds = append(rounds, round)
+	}
+	sort.Slice(rounds, func(i, j int) bool { return rounds[i] < rounds[j] })
+
+	return rounds, nil
+}
+
+// ScanArchive reads the blocks from first through last out of a block archive directory
+// & hands them to fn in order, it fails on a round missing from the archive so a gap
+// can't go unnoticed
+func ScanArchive(ctx context.Context, dir string, first uint64, last uint64, fn func(*BlockWrap) error) error {
+	if last < first {
+		return fmt.Errorf("invalid block scan range %d - %d", first, last)
+	}
+
+	for round := first; round <= last; round++ {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		raw, err := os.ReadFile(filepath.Join(dir, strconv.FormatUint(round, 10)+BlockFileExt))
+		if err != nil {
+			if os.IsNotExist(err) {
+				return fmt.Errorf("block archive %s is missing round %d", dir, round)
+			}
+			return err
+		}
+
+		block, err := DecodeBlock(raw)
+		if err != nil {
+			return fmt.Errorf("block archive round %d: %w", round, err)
+		}
+		if uint64(block.Round) != round {
+			return fmt.Errorf("block archive file for round %d holds round %d", round, block.Round)
+		}
+
+		err = fn(&BlockWrap{
+			Block:    block,
+			BlockRaw: raw,
+			Ts:       time.Now(),
+			Src:      dir,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,457 @@
+package config
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/kylebeee/arc53-watcher-go/internal/algod"
+	"github.com/kylebeee/arc53-watcher-go/internal/utils"
+	"github.com/kylebeee/arc53-watcher-go/providers"
+)
+
+const (
+	NetworkMainnet  = "mainnet"
+	NetworkTestnet  = "testnet"
+	NetworkLocalnet = "localnet"
+)
+
+// localnetToken is the algod & indexer token of an AlgoKit localnet
+const localnetToken = "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+
+// networkEndpoints are the algod & indexer used for a network when none are configured,
+// the public algonode endpoints & the ports AlgoKit's localnet listens on
+var networkEndpoints = map[string]struct{ algod, indexer, token string }{
+	NetworkMainnet:  {"https://mainnet-api.algonode.cloud", "https://mainnet-idx.algonode.cloud", ""},
+	NetworkTestnet:  {"https://testnet-api.algonode.cloud", "https://testnet-idx.algonode.cloud", ""},
+	NetworkLocalnet: {"http://localhost:4001", "http://localhost:8980", localnetToken},
+}
+
+const defaultListen = ":3000"
+
+// WatcherConfig is the watcher's configuration, read from the -f config file
+// with environment variables taking precedence over the file
+type WatcherConfig struct {
+	// NetworkConfig is the network watched when Networks is left out
+	NetworkConfig
+	// Networks watches several networks side by side, each with its own nodes, providers
+	// & database, entries without a db dsn share the top level one
+	Networks []NetworkConfig `json:"networks"`
+	// Listen is the address the API listens on
+	Listen string `json:"listen"`
+	// Log configures what the watcher prints
+	Log LogConfig `json:"log"`
+	// Auth guards the routes that make the watcher sync or rewind
+	Auth AuthConfig `json:"auth"`
+	// TrustedProxies are the proxies whose X-Forwarded-For is believed for client IPs,
+	// WATCHER_TRUSTED_PROXIES holds them comma separated, none when left out
+	TrustedProxies []string `json:"trusted_proxies"`
+}
+
+// NetworkConfig is everything a single network is watched with
+type NetworkConfig struct {
+	// Network is the network watched, testnet unless ENV is production
+	Network string `json:"network"`
+	// Algod holds the nodes blocks are streamed from, the first node also serves algod lookups,
+	// first overrides the round the watcher resumes from & last stops it, -r & -l set them too
+	Algod *algod.AlgoConfig `json:"algod"`
+	// Indexer is the indexer used for catch up
+	Indexer IndexerConfig `json:"indexer"`
+	// DB is the database connection
+	DB DBConfig `json:"db"`
+	// Providers configures provider types by their registered name
+	Providers map[string]providers.Config `json:"providers"`
+	// Catchup picks how providers are caught up when the watcher starts
+	Catchup CatchupConfig `json:"catchup"`
+	// Record writes every processed block to rolling segment files when set
+	Record *algod.RecorderConfig `json:"record"`
+	// Replay follows recorded blocks instead of algod when set
+	Replay *ReplayConfig `json:"replay"`
+}
+
+// UnmarshalJSON decodes a network, rounds left out mean the latest round & no limit rather than round 0
+func (n *NetworkConfig) UnmarshalJSON(data []byte) error {
+	type network NetworkConfig
+	decoded := network{Algod: &algod.AlgoConfig{FRound: -1, LRound: -1}}
+
+	err := json.Unmarshal(data, &decoded)
+	if err != nil {
+		return err
+	}
+
+	*n = NetworkConfig(decoded)
+	return nil
+}
+
+// UnmarshalJSON decodes the config, the embedded network's UnmarshalJSON would otherwise
+// be promoted & leave the rest of the fields out
+func (cfg *WatcherConfig) UnmarshalJSON(data []byte) error {
+	err := json.Unmarshal(data, &cfg.NetworkConfig)
+	if err != nil {
+		return err
+	}
+
+	var rest struct {
+		Networks []NetworkConfig `json:"networks"`
+		Listen   string          `json:"listen"`
+		Log      LogConfig       `json:"log"`
+		Auth     AuthConfig      `json:"auth"`
+
+		TrustedProxies []string `json:"trusted_proxies"`
+	}
+	err = json.Unmarshal(data, &rest)
+	if err != nil {
+		return err
+	}
+
+	cfg.Networks = rest.Networks
+	cfg.Listen = rest.Listen
+	cfg.Log = rest.Log
+	cfg.Auth = rest.Auth
+	cfg.TrustedProxies = rest.TrustedProxies
+	return nil
+}
+
+type IndexerConfig struct {
+	Address string `json:"address"`
+	Token   string `json:"token"`
+}
+
+type DBConfig struct {
+	// DSN is the mysql connection string, DB_AUTH holds it base64 encoded
+	DSN string `json:"dsn"`
+	// Database is the schema queries run against, arc53 on mainnet, arc53_test on testnet
+	// & arc53_<network> on any other network
+	Database string `json:"database"`
+	// MaxOpenConns limits the open connections, 10 when zero
+	MaxOpenConns int `json:"max_open_conns"`
+}
+
+type LogConfig struct {
+	// PrintTxns prints each transaction of a processed block, on when left out
+	PrintTxns *bool `json:"print_txns"`
+	// BlocksJSON prints each processed block as json, also turned on with -s
+	BlocksJSON bool `json:"blocks_json"`
+	// GinMode is the gin mode for the API, debug, release or test
+	GinMode string `json:"gin_mode"`
+}
+
+// AuthConfig guards the admin routes with API keys or HMAC signed requests & lets
+// community owners sync their own apps, admin routes aren't served when neither is configured
+type AuthConfig struct {
+	// APIKeys are accepted in the X-API-Key header, WATCHER_API_KEYS holds them comma separated
+	APIKeys []string `json:"api_keys"`
+	// HMACSecret verifies requests signed in the X-Signature header, WATCHER_HMAC_SECRET sets it
+	HMACSecret string `json:"hmac_secret"`
+	// AllowedOrigins are the origins browsers may open the websocket event stream from, "*" allows
+	// any & WATCHER_ALLOWED_ORIGINS holds them comma separated, none when left out
+	AllowedOrigins []string `json:"allowed_origins"`
+	// RateLimit limits the guarded routes & batch asset lookups per client IP & syncs per app
+	RateLimit RateLimitConfig `json:"rate_limit"`
+}
+
+type RateLimitConfig struct {
+	// PerIP is the quota of each client IP across the guarded routes & batch asset lookups, 30 a minute when left out
+	PerIP *QuotaConfig `json:"per_ip"`
+	// PerApp is the quota of syncs of each app, 5 a minute when left out
+	PerApp *QuotaConfig `json:"per_app"`
+}
+
+// QuotaConfig allows Requests every Seconds, zero requests turns the limit off
+type QuotaConfig struct {
+	Requests int `json:"requests"`
+	Seconds  int `json:"seconds"`
+}
+
+// ReplayConfig points the watcher at blocks written by a recorder, catch up is skipped
+// & the watcher processes the recorded rounds from FirstRound through LastRound
+type ReplayConfig struct {
+	// Dir is the recorder directory to replay
+	Dir string `json:"dir"`
+	// FirstRound is the first round replayed, the watcher cursor when zero
+	FirstRound uint64 `json:"first_round"`
+	// LastRound is the last round replayed, every recorded round after FirstRound when zero
+	LastRound uint64 `json:"last_round"`
+}
+
+const (
+	// CatchupIndexer catches providers up through the indexer
+	CatchupIndexer = "indexer"
+	// CatchupBlocks catches providers up by scanning blocks from algod or a block archive
+	CatchupBlocks = "blocks"
+)
+
+// CatchupConfig configures the catch up that runs before the watcher follows the chain
+type CatchupConfig struct {
+	// Mode is either indexer or blocks, indexer when left out
+	Mode string `json:"mode"`
+	// FirstRound is where a block scan starts when there's no watcher cursor or provider round yet
+	FirstRound uint64 `json:"first_round"`
+	// LastRound is where a block scan stops, the node's last round or the archive's last block when zero
+	LastRound uint64 `json:"last_round"`
+	// Nodes are the algod nodes blocks are scanned from, the watcher's algod when empty
+	Nodes []*algod.AlgoNodeConfig `json:"nodes"`
+	// Archive is a directory of recorded msgpack blocks to scan instead of algod
+	Archive string `json:"archive"`
+}
+
+// LoadWatcherConfig loads the watcher configuration, a missing config file leaves every
+// setting at its default & every registered provider type enabled with its defaults.
+// Networks always holds the networks to watch, the top level one when none are listed
+func LoadWatcherConfig() (cfg WatcherConfig, err error) {
+	if !flag.Parsed() {
+		flag.Parse()
+	}
+
+	cfg.Algod = &algod.AlgoConfig{FRound: -1, LRound: -1}
+
+	err = utils.LoadJSONCFromFile(*cfgFile, &cfg)
+	if err != nil && !os.IsNotExist(err) {
+		return cfg, err
+	}
+
+	cfg.applyEnv()
+
+	if len(cfg.Networks) == 0 {
+		cfg.Networks = []NetworkConfig{cfg.NetworkConfig}
+	}
+
+	cfg.applyFlags()
+
+	if cfg.Listen == "" {
+		cfg.Listen = defaultListen
+	}
+
+	if cfg.Log.PrintTxns == nil {
+		printTxns := true
+		cfg.Log.PrintTxns = &printTxns
+	}
+
+	if cfg.Auth.RateLimit.PerIP == nil {
+		cfg.Auth.RateLimit.PerIP = &QuotaConfig{Requests: 30, Seconds: 60}
+	}
+	if cfg.Auth.RateLimit.PerApp == nil {
+		cfg.Auth.RateLimit.PerApp = &QuotaConfig{Requests: 5, Seconds: 60}
+	}
+	for _, quota := range []*QuotaConfig{cfg.Auth.RateLimit.PerIP, cfg.Auth.RateLimit.PerApp} {
+		if quota.Requests > 0 && quota.Seconds <= 0 {
+			return cfg, fmt.Errorf("[CFG] rate limit quotas need seconds")
+		}
+	}
+
+	seen := map[string]bool{}
+	databases := map[string]string{}
+	for i := range cfg.Networks {
+		network := &cfg.Networks[i]
+		if network.DB.DSN == "" {
+			network.DB.DSN = cfg.DB.DSN
+		}
+
+		err = network.applyDefaults()
+		if err != nil {
+			return cfg, err
+		}
+
+		if seen[network.Network] {
+			return cfg, fmt.Errorf("[CFG] network %s is configured twice", network.Network)
+		}
+		seen[network.Network] = true
+
+		// networks writing to one schema would mix their cursors, events & communities
+		target := network.DB.target()
+		if other, shared := databases[target]; shared {
+			return cfg, fmt.Errorf("[CFG] networks %s & %s use the same database %s, give one a db.database of its own", other, network.Network, network.DB.Database)
+		}
+		databases[target] = network.Network
+
+		switch network.Catchup.Mode {
+		case "", CatchupIndexer, CatchupBlocks:
+		default:
+			return cfg, fmt.Errorf("[CFG] unknown catchup mode %s", network.Catchup.Mode)
+		}
+
+		if network.Replay != nil && network.Replay.Dir == "" {
+			return cfg, fmt.Errorf("[CFG] replay needs a dir")
+		}
+	}
+
+	return cfg, nil
+}
+
+// applyEnv overrides the file with the environment, the environment only sets up
+// the top level network
+func (cfg *WatcherConfig) applyEnv() {
+	if network := os.Getenv("WATCHER_NETWORK"); network != "" {
+		cfg.Network = network
+	}
+	if cfg.Network == "" && os.Getenv("ENV") == "production" {
+		cfg.Network = NetworkMainnet
+	}
+
+	if listen := os.Getenv("WATCHER_LISTEN"); listen != "" {
+		cfg.Listen = listen
+	}
+
+	// a node from the environment replaces the configured nodes
+	if address := os.Getenv("ALGOD_ADDRESS"); address != "" {
+		if cfg.Algod == nil {
+			cfg.Algod = &algod.AlgoConfig{FRound: -1, LRound: -1}
+		}
+		cfg.Algod.ANodes = []*algod.AlgoNodeConfig{
+			{
+				Address: address,
+				Token:   os.Getenv("ALGOD_TOKEN"),
+				Id:      "env-node",
+			},
+		}
+	}
+
+	if address := os.Getenv("INDEXER_ADDRESS"); address != "" {
+		cfg.Indexer.Address = address
+	}
+	if token := os.Getenv("INDEXER_TOKEN"); token != "" {
+		cfg.Indexer.Token = token
+	}
+
+	if auth := os.Getenv("DB_AUTH"); auth != "" {
+		dsn, err := base64.StdEncoding.DecodeString(auth)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[WARN][CFG] DB_AUTH isn't base64, ignoring it: %s\n", err)
+		} else {
+			cfg.DB.DSN = string(dsn)
+		}
+	}
+	if database := os.Getenv("DB_DATABASE"); database != "" {
+		cfg.DB.Database = database
+	}
+
+	if printTxns, err := strconv.ParseBool(os.Getenv("LOG_PRINT_TXNS")); err == nil {
+		cfg.Log.PrintTxns = &printTxns
+	}
+	if ginMode := os.Getenv("GIN_MODE"); ginMode != "" {
+		cfg.Log.GinMode = ginMode
+	}
+
+	if keys := os.Getenv("WATCHER_API_KEYS"); keys != "" {
+		cfg.Auth.APIKeys = strings.Split(keys, ",")
+	}
+	if secret := os.Getenv("WATCHER_HMAC_SECRET"); secret != "" {
+		cfg.Auth.HMACSecret = secret
+	}
+	if origins := os.Getenv("WATCHER_ALLOWED_ORIGINS"); origins != "" {
+		cfg.Auth.AllowedOrigins = strings.Split(origins, ",")
+	}
+	if proxies := os.Getenv("WATCHER_TRUSTED_PROXIES"); proxies != "" {
+		cfg.TrustedProxies = strings.Split(proxies, ",")
+	}
+}
+
+// applyFlags overrides the file & environment with the command line,
+// rounds apply to every network
+func (cfg *WatcherConfig) applyFlags() {
+	for i := range cfg.Networks {
+		if cfg.Networks[i].Algod == nil {
+			cfg.Networks[i].Algod = &algod.AlgoConfig{FRound: -1, LRound: -1}
+		}
+	}
+
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "r":
+			for i := range cfg.Networks {
+				cfg.Networks[i].Algod.FRound = *firstRound
+			}
+		case "l":
+			for i := range cfg.Networks {
+				cfg.Networks[i].Algod.LRound = *lastRound
+			}
+		case "s":
+			cfg.Log.BlocksJSON = *simpleFlag
+		}
+	})
+}
+
+// applyDefaults fills in whatever is still unset
+func (cfg *NetworkConfig) applyDefaults() error {
+	if cfg.Network == "" {
+		cfg.Network = NetworkTestnet
+	}
+
+	endpoints, known := networkEndpoints[cfg.Network]
+
+	if len(cfg.Algod.ANodes) == 0 {
+		if !known {
+			return fmt.Errorf("[CFG] configure algod nodes for network %s", cfg.Network)
+		}
+		cfg.Algod.ANodes = []*algod.AlgoNodeConfig{
+			{
+				Address: endpoints.algod,
+				Token:   endpoints.token,
+				Id:      "default-node",
+			},
+		}
+	}
+	for i, node := range cfg.Algod.ANodes {
+		if node.Address == "" {
+			return fmt.Errorf("[CFG] %s algod node %d has no address", cfg.Network, i)
+		}
+		if node.Id == "" {
+			node.Id = fmt.Sprintf("node-%d", i)
+		}
+	}
+	if cfg.Algod.Queue < 1 {
+		cfg.Algod.Queue = 1
+	}
+
+	if cfg.Indexer.Address == "" && known {
+		cfg.Indexer.Address = endpoints.indexer
+		if cfg.Indexer.Token == "" {
+			cfg.Indexer.Token = endpoints.token
+		}
+	}
+
+	if cfg.DB.Database == "" {
+		cfg.DB.Database = defaultDatabase(cfg.Network)
+	}
+
+	return nil
+}
+
+// defaultDatabase is the schema a network uses when none is configured
+func defaultDatabase(network string) string {
+	switch network {
+	case NetworkMainnet:
+		return "arc53"
+	case NetworkTestnet:
+		return "arc53_test"
+	}
+
+	// anything but letters, digits & underscores would need quoting as a schema name
+	name := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' {
+			return r
+		}
+		return '_'
+	}, network)
+	return "arc53_" + name
+}
+
+// target is the server & schema the config connects to, the same for two configs
+// that would read & write the same tables
+func (cfg DBConfig) target() string {
+	database := cfg.Database
+	dsn, err := mysql.ParseDSN(cfg.DSN)
+	if err != nil {
+		// db.Open fails on it, compare it as written until then
+		return cfg.DSN + "/" + database
+	}
+	if database == "" {
+		database = dsn.DBName
+	}
+	return dsn.Net + "(" + dsn.Addr + ")/" + database
+}
@@ -0,0 +1,65 @@
+package config
+
+import "testing"
+
+func TestDefaultDatabase(t *testing.T) {
+	tests := []struct {
+		network string
+		want    string
+	}{
+		{network: NetworkMainnet, want: "arc53"},
+		{network: NetworkTestnet, want: "arc53_test"},
+		{network: NetworkLocalnet, want: "arc53_localnet"},
+		{network: "betanet", want: "arc53_betanet"},
+		{network: "fnet-2", want: "arc53_fnet_2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.network, func(t *testing.T) {
+			got := defaultDatabase(tt.network)
+			if got != tt.want {
+				t.Fatalf("defaultDatabase(%s) = %s, want %s", tt.network, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDBConfigTarget(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b DBConfig
+		same bool
+	}{
+		{
+			name: "same server & database",
+			a:    DBConfig{DSN: "watcher:pw@tcp(db:3306)/", Database: "arc53_localnet"},
+			b:    DBConfig{DSN: "other:pw2@tcp(db:3306)/", Database: "arc53_localnet"},
+			same: true,
+		},
+		{
+			name: "database from the dsn",
+			a:    DBConfig{DSN: "watcher:pw@tcp(db:3306)/arc53"},
+			b:    DBConfig{DSN: "watcher:pw@tcp(db:3306)/", Database: "arc53"},
+			same: true,
+		},
+		{
+			name: "same server, other databases",
+			a:    DBConfig{DSN: "watcher:pw@tcp(db:3306)/", Database: "arc53_localnet"},
+			b:    DBConfig{DSN: "watcher:pw@tcp(db:3306)/", Database: "arc53_betanet"},
+		},
+		{
+			name: "other servers, same database",
+			a:    DBConfig{DSN: "watcher:pw@tcp(db1:3306)/", Database: "arc53"},
+			b:    DBConfig{DSN: "watcher:pw@tcp(db2:3306)/", Database: "arc53"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			same := tt.a.target() == tt.b.target()
+			if same != tt.same {
+				t.Fatalf("%s & %s resolve to the same database = %v, want %v", tt.a.target(), tt.b.target(), same, tt.same)
+			}
+		})
+	}
+}
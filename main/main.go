@@ -25,11 +25,9 @@ func main() {
 	go func() {
 		<-sigc
 		fmt.Printf("[SERVER][%s] Shutting Down\n", time.Now().Format(TimeFormat))
-		// close db
+		// cancel watchers & close dbs
 		s.Close()
-		// cancel watcher
-		s.WatcherCancelFn()
 	}()
 
-	s.Run(":3000")
+	s.Run(s.ListenAddr)
 }
@@ -1,6 +1,10 @@
 package misc
 
-import "github.com/algorand/go-algorand-sdk/v2/types"
+import (
+	"strings"
+
+	"github.com/algorand/go-algorand-sdk/v2/types"
+)
 
 func ListInner(stxn *types.SignedTxnWithAD) []types.SignedTxnWithAD {
 	txns := []types.SignedTxnWithAD{}
@@ -10,3 +14,9 @@ func ListInner(stxn *types.SignedTxnWithAD) []types.SignedTxnWithAD {
 	}
 	return txns
 }
+
+// IsAlgodNotFound checks if an error returned by the algod or indexer clients is a 404,
+// the sdk wraps these as plain errors so the status code is all we have to go on
+func IsAlgodNotFound(err error) bool {
+	return err != nil && strings.HasPrefix(err.Error(), "HTTP 404")
+}
@@ -0,0 +1,222 @@
+package app
+
+import (
+	"context"
+	"encoding/base64"
+
+	"github.com/algorand/go-algorand-sdk/v2/client/v2/algod"
+	"github.com/algorand/go-algorand-sdk/v2/client/v2/common/models"
+	"github.com/algorand/go-algorand-sdk/v2/types"
+	"github.com/kylebeee/arc53-watcher-go/errors"
+	"github.com/kylebeee/arc53-watcher-go/misc"
+)
+
+// MetadataKey is the global state key or box name holding the ARC53 metadata url
+const MetadataKey = "arc53"
+
+// AddressesKey is the global state key or box name holding the addresses an app declares,
+// stored as concatenated 32 byte public keys. Any app can declare any address, so only the
+// app's creator & accounts that signed an opt in to the app are taken as verified
+const AddressesKey = "arc53_addresses"
+
+// AppState is the ARC53 layout read from an application
+type AppState struct {
+	AppID    uint64
+	Metadata *string
+	// Addresses are the declared addresses that proved they're controlled by the app's owner
+	Addresses []string
+	// Unverified are the declared addresses that haven't, they're stored but not trusted
+	Unverified []string
+}
+
+// Declared reports whether the app sets either of the ARC53 keys
+func (s *AppState) Declared() bool {
+	return s.Metadata != nil || len(s.Addresses) > 0 || len(s.Unverified) > 0
+}
+
+// GetAppState reads the ARC53 keys of an application, global state is preferred
+// & boxes of the same name are used when a key isn't in global state,
+// a deleted or unknown app has an empty state
+func GetAppState(algodClient *algod.Client, ctx context.Context, appID uint64) (*AppState, error) {
+	const op errors.Op = "GetAppState"
+	state := &AppState{AppID: appID}
+
+	appData, err := algodClient.GetApplicationByID(appID).Do(ctx)
+	if err != nil && misc.IsAlgodNotFound(err) {
+		return state, nil
+	} else if err != nil {
+		return nil, errors.E(op, errors.Network, err)
+	}
+
+	global := globalBytes(appData.Params.GlobalState)
+
+	metadata, ok := global[MetadataKey]
+	if !ok {
+		metadata, ok, err = getBox(algodClient, ctx, appID, MetadataKey)
+		if err != nil {
+			return nil, errors.E(op, err)
+		}
+	}
+	if ok && len(metadata) > 0 {
+		value := string(metadata)
+		state.Metadata = &value
+	}
+
+	addresses, ok := global[AddressesKey]
+	if !ok {
+		addresses, _, err = getBox(algodClient, ctx, appID, AddressesKey)
+		if err != nil {
+			return nil, errors.E(op, err)
+		}
+	}
+
+	declared, err := DecodeAddresses(addresses)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	state.Addresses, state.Unverified, err = verifyAddresses(algodClient, ctx, appID, appData.Params.Creator, declared)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	return state, nil
+}
+
+// verifyAddresses splits the addresses an app declares into those that proved control & those that
+// didn't, the creator signed the app's creation & an opted in account signed its own opt in
+func verifyAddresses(algodClient *algod.Client, ctx context.Context, appID uint64, creator string, declared []string) ([]string, []string, error) {
+	const op errors.Op = "verifyAddresses"
+
+	verified := []string{}
+	unverified := []string{}
+	for _, address := range declared {
+		if address == creator {
+			verified = append(verified, address)
+			continue
+		}
+
+		info, err := algodClient.AccountApplicationInformation(address, appID).Do(ctx)
+		if err != nil && misc.IsAlgodNotFound(err) {
+			unverified = append(unverified, address)
+			continue
+		} else if err != nil {
+			return nil, nil, errors.E(op, errors.Network, err)
+		}
+
+		if info.AppLocalState.Id == appID {
+			verified = append(verified, address)
+		} else {
+			unverified = append(unverified, address)
+		}
+	}
+
+	return verified, unverified, nil
+}
+
+// DecodeAddresses splits concatenated 32 byte public keys into algorand addresses
+func DecodeAddresses(data []byte) ([]string, error) {
+	const op errors.Op = "DecodeAddresses"
+
+	if len(data)%len(types.Address{}) != 0 {
+		return nil, errors.E(op, errors.Type, "addresses must be a multiple of 32 bytes")
+	}
+
+	addresses := []string{}
+	for i := 0; i < len(data); i += len(types.Address{}) {
+		var address types.Address
+		copy(address[:], data[i:i+len(address)])
+		addresses = append(addresses, address.String())
+	}
+
+	return misc.UniqueSlice(addresses), nil
+}
+
+func globalBytes(globalState []models.TealKeyValue) map[string][]byte {
+	values := map[string][]byte{}
+	for _, kv := range globalState {
+		// only byte slice values can hold the ARC53 keys
+		if kv.Value.Type != uint64(types.TealBytesType) {
+			continue
+		}
+
+		key, err := base64.StdEncoding.DecodeString(kv.Key)
+		if err != nil {
+			continue
+		}
+
+		value, err := base64.StdEncoding.DecodeString(kv.Value.Bytes)
+		if err != nil {
+			continue
+		}
+
+		values[string(key)] = value
+	}
+	return values
+}
+
+func getBox(algodClient *algod.Client, ctx context.Context, appID uint64, name string) ([]byte, bool, error) {
+	const op errors.Op = "getBox"
+
+	box, err := algodClient.GetApplicationBoxByName(appID, []byte(name)).Do(ctx)
+	if err != nil && misc.IsAlgodNotFound(err) {
+		return nil, false, nil
+	} else if err != nil {
+		return nil, false, errors.E(op, errors.Network, err)
+	}
+
+	return box.Value, true, nil
+}
+
+// touchesLayout reports whether a transaction's global state changes set or clear an ARC53 key
+func touchesLayout(stxn types.SignedTxnWithAD) bool {
+	for key := range stxn.EvalDelta.GlobalDelta {
+		if key == MetadataKey || key == AddressesKey {
+			return true
+		}
+	}
+	return false
+}
+
+// layoutBoxApps lists the apps whose ARC53 boxes each transaction & its inner transactions
+// reference, a box can only be written when it's referenced & references are shared across
+// a group, so grouped transactions share the apps of their whole group
+func layoutBoxApps(stxns []types.SignedTxnWithAD) []map[uint64]bool {
+	apps := make([]map[uint64]bool, len(stxns))
+	groups := map[types.Digest]map[uint64]bool{}
+	for i := range stxns {
+		apps[i] = map[uint64]bool{}
+		group := stxns[i].Txn.Group
+		if group != (types.Digest{}) {
+			if _, ok := groups[group]; !ok {
+				groups[group] = map[uint64]bool{}
+			}
+			apps[i] = groups[group]
+		}
+
+		txns := append([]types.SignedTxnWithAD{stxns[i]}, misc.ListInner(&stxns[i])...)
+		for j := range txns {
+			txn := txns[j].Txn
+			for _, ref := range txn.BoxReferences {
+				if string(ref.Name) != MetadataKey && string(ref.Name) != AddressesKey {
+					continue
+				}
+
+				appID := uint64(txn.ApplicationID)
+				if appID == 0 {
+					// app creation, the new ID is in the apply data
+					appID = uint64(txns[j].ApplicationID)
+				}
+				if ref.ForeignAppIdx > 0 {
+					if int(ref.ForeignAppIdx) > len(txn.ForeignApps) {
+						continue
+					}
+					appID = uint64(txn.ForeignApps[ref.ForeignAppIdx-1])
+				}
+
+				apps[i][appID] = true
+			}
+		}
+	}
+	return apps
+}
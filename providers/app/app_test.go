@@ -0,0 +1,105 @@
+package app
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/algorand/go-algorand-sdk/v2/types"
+)
+
+func appCall(appID uint64, group byte, boxes ...string) types.SignedTxnWithAD {
+	var stxn types.SignedTxnWithAD
+	stxn.Txn.Type = types.ApplicationCallTx
+	stxn.Txn.ApplicationID = types.AppIndex(appID)
+	for _, name := range boxes {
+		stxn.Txn.BoxReferences = append(stxn.Txn.BoxReferences, types.BoxReference{Name: []byte(name)})
+	}
+	if group != 0 {
+		stxn.Txn.Group = types.Digest{group}
+	}
+	return stxn
+}
+
+func TestLayoutBoxApps(t *testing.T) {
+	foreign := appCall(1, 0)
+	foreign.Txn.ForeignApps = []types.AppIndex{2}
+	foreign.Txn.BoxReferences = []types.BoxReference{{ForeignAppIdx: 1, Name: []byte(AddressesKey)}, {ForeignAppIdx: 2, Name: []byte(MetadataKey)}}
+
+	created := appCall(0, 0, MetadataKey)
+	created.ApplicationID = 7
+
+	inner := appCall(3, 0)
+	inner.EvalDelta.InnerTxns = []types.SignedTxnWithAD{appCall(4, 0, AddressesKey)}
+
+	tests := []struct {
+		name  string
+		stxns []types.SignedTxnWithAD
+		want  [][]uint64
+	}{
+		{
+			name:  "the called app's ARC53 boxes",
+			stxns: []types.SignedTxnWithAD{appCall(1, 0, MetadataKey), appCall(2, 0, AddressesKey), appCall(3, 0, "other", "")},
+			want:  [][]uint64{{1}, {2}, {}},
+		},
+		{
+			name:  "a foreign app's ARC53 box",
+			stxns: []types.SignedTxnWithAD{foreign},
+			want:  [][]uint64{{2}},
+		},
+		{
+			name:  "an app created with its ARC53 box",
+			stxns: []types.SignedTxnWithAD{created},
+			want:  [][]uint64{{7}},
+		},
+		{
+			name:  "an inner call's ARC53 box",
+			stxns: []types.SignedTxnWithAD{inner},
+			want:  [][]uint64{{4}},
+		},
+		{
+			name:  "references shared across a group",
+			stxns: []types.SignedTxnWithAD{appCall(1, 1), appCall(2, 1, MetadataKey), appCall(3, 0)},
+			want:  [][]uint64{{2}, {2}, {}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := [][]uint64{}
+			for _, apps := range layoutBoxApps(tt.stxns) {
+				ids := []uint64{}
+				for appID := range apps {
+					ids = append(ids, appID)
+				}
+				sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+				got = append(got, ids)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Fatalf("apps %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTouchesLayout(t *testing.T) {
+	tests := []struct {
+		name  string
+		delta types.StateDelta
+		want  bool
+	}{
+		{name: "sets the metadata", delta: types.StateDelta{MetadataKey: {Action: types.SetBytesAction, Bytes: "ipfs://"}}, want: true},
+		{name: "clears the addresses", delta: types.StateDelta{AddressesKey: {Action: types.DeleteAction}}, want: true},
+		{name: "other keys", delta: types.StateDelta{"counter": {Action: types.SetUintAction, Uint: 1}}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stxn := appCall(1, 0)
+			stxn.EvalDelta.GlobalDelta = tt.delta
+			if touchesLayout(stxn) != tt.want {
+				t.Fatalf("touchesLayout = %v, want %v", !tt.want, tt.want)
+			}
+		})
+	}
+}
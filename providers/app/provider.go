@@ -0,0 +1,315 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/algorand/go-algorand-sdk/v2/client/v2/algod"
+	"github.com/algorand/go-algorand-sdk/v2/client/v2/indexer"
+	"github.com/algorand/go-algorand-sdk/v2/types"
+	"github.com/jmoiron/sqlx"
+	"github.com/kylebeee/arc53-watcher-go/arc53"
+	"github.com/kylebeee/arc53-watcher-go/db"
+	"github.com/kylebeee/arc53-watcher-go/errors"
+	"github.com/kylebeee/arc53-watcher-go/misc"
+	"github.com/kylebeee/arc53-watcher-go/providers"
+	"github.com/kylebeee/arc53-watcher-go/providers/community"
+)
+
+const ProviderType = "app"
+
+const defaultSyncIntervalMs int64 = 300
+
+// Settings are the app provider's settings in the providers section of the config
+type Settings struct {
+	// SyncIntervalMs is the minimum time between starting app syncs during catch up
+	SyncIntervalMs int64 `json:"sync_interval_ms"`
+	// CatchupWorkers is how many apps are synced at once during catch up
+	CatchupWorkers int `json:"catchup_workers"`
+	// Fetcher configures how community metadata is fetched
+	Fetcher arc53.FetcherConfig `json:"fetcher"`
+}
+
+func init() {
+	providers.Register(ProviderType, func(raw json.RawMessage) (providers.ProviderType, error) {
+		var settings Settings
+		if len(raw) > 0 {
+			err := json.Unmarshal(raw, &settings)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		return &AppProvider{
+			Fetcher:  arc53.NewGatewayFetcher(settings.Fetcher),
+			settings: settings,
+		}, nil
+	})
+}
+
+// AppProvider tracks any application that declares ARC53 metadata directly
+// through the MetadataKey & AddressesKey global state keys or boxes
+type AppProvider struct {
+	network string
+	DB      *sqlx.DB
+	Algod   *algod.Client
+	SyncMap *sync.Map
+	// Fetcher retrieves community metadata, gateway defaults are used when nil
+	Fetcher  arc53.MetadataFetcher
+	syncer   *community.Syncer
+	settings Settings
+}
+
+func (p *AppProvider) Type() string {
+	return ProviderType
+}
+
+func (p *AppProvider) Init(network string, dbConn *sqlx.DB, algodClient *algod.Client) error {
+	const op errors.Op = "AppProvider.Init"
+
+	p.network = network
+	p.DB = dbConn
+	p.Algod = algodClient
+	p.SyncMap = &sync.Map{}
+
+	p.syncer = community.NewSyncer(dbConn, p.Fetcher)
+	p.Fetcher = p.syncer.Fetcher
+
+	appIDs, err := db.GetAllProvidersByType(p.DB, ProviderType)
+	if err != nil {
+		if !db.ErrNoRows(err) {
+			return errors.E(op, err)
+		} else {
+			return nil
+		}
+	}
+
+	for i := range *appIDs {
+		appID := (*appIDs)[i]
+		p.SyncMap.Store(appID, struct{}{})
+	}
+
+	return nil
+}
+
+// CatchUp resyncs every known app, there's no registry to walk for new ones so
+// apps are discovered as they set their ARC53 keys or registered through Process
+func (p *AppProvider) CatchUp(dbConn *sqlx.DB, algodClient *algod.Client, startingRound uint64, indexerClient *indexer.Client) error {
+	const op errors.Op = "AppProvider.CatchUp"
+
+	status, err := algodClient.Status().Do(context.Background())
+	if err != nil {
+		return errors.E(op, err)
+	}
+
+	appIDs := []uint64{}
+	p.SyncMap.Range(func(key, value any) bool {
+		appIDs = append(appIDs, key.(uint64))
+		return true
+	})
+
+	// apps that failed their first sync were never stored as providers
+	failures, err := db.GetSyncFailures(p.DB, ProviderType)
+	if err != nil && !db.ErrNoRows(err) {
+		return errors.E(op, err)
+	} else if failures != nil {
+		for _, failure := range *failures {
+			appIDs = append(appIDs, failure.ID)
+		}
+	}
+	appIDs = misc.UniqueSlice(appIDs)
+
+	interval := p.settings.SyncIntervalMs
+	if interval <= 0 {
+		interval = defaultSyncIntervalMs
+	}
+
+	pool := providers.NewSyncPool(p.settings.CatchupWorkers, time.Duration(interval)*time.Millisecond)
+	syncCount, err := pool.RunRecorded(context.Background(), p.DB, ProviderType, appIDs, func(appID uint64) error {
+		_, err := p.SyncApp(appID, status.LastRound)
+		return err
+	})
+	if err != nil {
+		return errors.E(op, err)
+	}
+
+	if len(appIDs) > 0 {
+		fmt.Printf("[CATCHUP] [APP] [SYNCED]: %v of %v\n", syncCount, len(appIDs))
+	}
+
+	return nil
+}
+
+func (p *AppProvider) ProcessBlock(btx *community.BlockTx, stxn types.SignedTxnInBlock, round uint64) error {
+	const op errors.Op = "AppProvider.ProcessBlock"
+
+	err := p.processTxns(btx, []types.SignedTxnWithAD{stxn.SignedTxnWithAD}, round)
+	if err != nil {
+		return errors.E(op, err)
+	}
+
+	return nil
+}
+
+func (p *AppProvider) ProcessBlockBatch(btx *community.BlockTx, block *types.Block, round uint64) error {
+	const op errors.Op = "AppProvider.ProcessBlockBatch"
+
+	stxns := make([]types.SignedTxnWithAD, len(block.Payset))
+	for i := range block.Payset {
+		stxns[i] = block.Payset[i].SignedTxnWithAD
+	}
+
+	err := p.processTxns(btx, stxns, round)
+	if err != nil {
+		return errors.E(op, err)
+	}
+
+	return nil
+}
+
+// processTxns syncs every tracked app called by the transactions & their inner
+// transactions exactly once, along with untracked apps that set an ARC53 key in
+// global state or are called with a reference to one of their ARC53 boxes,
+// every app that fails to sync is returned in SyncFailures
+func (p *AppProvider) processTxns(btx *community.BlockTx, stxns []types.SignedTxnWithAD, round uint64) error {
+	const op errors.Op = "processTxns"
+
+	boxApps := layoutBoxApps(stxns)
+	txnsToProcess := []types.SignedTxnWithAD{}
+	// referencedBoxes are the apps whose ARC53 boxes are referenced alongside each txn
+	referencedBoxes := []map[uint64]bool{}
+	for i := range stxns {
+		txns := append([]types.SignedTxnWithAD{stxns[i]}, misc.ListInner(&stxns[i])...)
+		for range txns {
+			referencedBoxes = append(referencedBoxes, boxApps[i])
+		}
+		txnsToProcess = append(txnsToProcess, txns...)
+	}
+
+	toSync := []uint64{}
+	for i := range txnsToProcess {
+		txn := txnsToProcess[i].Txn
+		if txn.Type != types.ApplicationCallTx {
+			continue
+		}
+
+		appID := uint64(txn.ApplicationID)
+		if appID == 0 {
+			// app creation, the new ID is in the apply data
+			appID = uint64(txnsToProcess[i].ApplicationID)
+		}
+
+		if appID == 0 || misc.InSlice(appID, toSync) {
+			continue
+		}
+
+		_, exists := p.SyncMap.Load(appID)
+		if !exists {
+			if !touchesLayout(txnsToProcess[i]) && !referencedBoxes[i][appID] {
+				continue
+			}
+
+			// apps already tracked by another provider type keep their owner, read in the block so
+			// apps another provider type took earlier in it count
+			var provider *db.Provider
+			var err error
+			if btx != nil {
+				provider, err = db.GetProvider(btx.Tx, appID)
+			} else {
+				provider, err = db.GetProvider(p.DB, appID)
+			}
+			if err != nil && !db.ErrNoRows(err) {
+				return errors.E(op, err)
+			} else if provider != nil && provider.Type != ProviderType {
+				continue
+			}
+
+			btx.AfterCommit(func() { p.SyncMap.Store(appID, struct{}{}) })
+		}
+
+		toSync = append(toSync, appID)
+	}
+
+	failures := providers.SyncFailures{}
+	for _, appID := range toSync {
+		_, err := p.syncApp(btx, appID, round)
+		if err != nil {
+			fmt.Println("[APP] [ERROR]: ", err)
+			failures[appID] = err
+		}
+	}
+
+	if len(failures) > 0 {
+		return errors.E(op, failures)
+	}
+
+	return nil
+}
+
+func (p *AppProvider) Process(appID uint64) (*community.Changes, error) {
+	const op errors.Op = "AppProvider.Process"
+
+	if !p.IsProviderApp(appID) {
+		return nil, errors.E(op, fmt.Errorf("app %v doesn't declare any arc53 state", appID))
+	}
+
+	status, err := p.Algod.Status().Do(context.Background())
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	p.SyncMap.Store(appID, struct{}{})
+
+	changes, err := p.SyncApp(appID, status.LastRound)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	return changes, nil
+}
+
+func (p *AppProvider) IsProviderApp(appID uint64) bool {
+	_, exists := p.SyncMap.Load(appID)
+	if exists {
+		return true
+	}
+
+	state, err := GetAppState(p.Algod, context.Background(), appID)
+	if err != nil {
+		return false
+	}
+
+	return state.Declared()
+}
+
+// helpers
+func (p *AppProvider) SyncApp(appID uint64, currentBlock uint64) (*community.Changes, error) {
+	return p.syncApp(nil, appID, currentBlock)
+}
+
+// syncApp reads an app's state from algod & syncs it, as part of btx when it's set
+func (p *AppProvider) syncApp(btx *community.BlockTx, appID uint64, currentBlock uint64) (*community.Changes, error) {
+	const op errors.Op = "SyncApp"
+
+	state, err := GetAppState(p.Algod, context.Background(), appID)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	changes, err := p.syncer.SyncIn(btx, community.State{
+		ID:         appID,
+		Type:       ProviderType,
+		Round:      currentBlock,
+		Metadata:   state.Metadata,
+		Addresses:  state.Addresses,
+		Unverified: state.Unverified,
+	})
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	return changes, nil
+}
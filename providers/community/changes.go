@@ -0,0 +1,58 @@
+package community
+
+import (
+	"github.com/kylebeee/arc53-watcher-go/db"
+)
+
+// Changes summarizes what a sync changed in the database
+type Changes struct {
+	// ProviderCreated is set the first time a provider app is synced
+	ProviderCreated  bool     `json:"provider_created,omitempty"`
+	AddressesAdded   []string `json:"addresses_added,omitempty"`
+	AddressesRemoved []string `json:"addresses_removed,omitempty"`
+	// AddressesVerified & AddressesUnverified hold stored addresses whose verification changed
+	AddressesVerified   []string `json:"addresses_verified,omitempty"`
+	AddressesUnverified []string `json:"addresses_unverified,omitempty"`
+	// MetadataChanged is set when the community json differs from what was stored
+	MetadataChanged bool `json:"metadata_changed,omitempty"`
+	// Malformed is set when the new community json failed validation
+	Malformed bool `json:"malformed,omitempty"`
+	// CIDMismatch is set when the metadata failed verification against its CID
+	CIDMismatch      bool `json:"cid_mismatch,omitempty"`
+	CommunityCreated bool `json:"community_created,omitempty"`
+	CommunityDeleted bool `json:"community_deleted,omitempty"`
+	// CollectionsAdded, CollectionsUpdated & CollectionsRemoved hold collection names
+	CollectionsAdded   []string `json:"collections_added,omitempty"`
+	CollectionsUpdated []string `json:"collections_updated,omitempty"`
+	CollectionsRemoved []string `json:"collections_removed,omitempty"`
+	TokensAdded        []uint64 `json:"tokens_added,omitempty"`
+	TokensRemoved      []uint64 `json:"tokens_removed,omitempty"`
+
+	// events are written to the event outbox when the sync commits
+	events []db.Event
+	// fetchErr is why the metadata couldn't be fetched, the rest of the sync is still written
+	fetchErr error
+}
+
+// emit queues an event about the synced community
+func (c *Changes) emit(eventType string, subject string) {
+	c.events = append(c.events, db.Event{Type: eventType, Subject: subject})
+}
+
+// Changed reports whether the sync changed anything
+func (c *Changes) Changed() bool {
+	return c.ProviderCreated ||
+		len(c.AddressesAdded) > 0 ||
+		len(c.AddressesRemoved) > 0 ||
+		len(c.AddressesVerified) > 0 ||
+		len(c.AddressesUnverified) > 0 ||
+		c.MetadataChanged ||
+		c.CIDMismatch ||
+		c.CommunityCreated ||
+		c.CommunityDeleted ||
+		len(c.CollectionsAdded) > 0 ||
+		len(c.CollectionsUpdated) > 0 ||
+		len(c.CollectionsRemoved) > 0 ||
+		len(c.TokensAdded) > 0 ||
+		len(c.TokensRemoved) > 0
+}
@@ -0,0 +1,1090 @@
+package community
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/kylebeee/arc53-watcher-go/arc53"
+	"github.com/kylebeee/arc53-watcher-go/db"
+	"github.com/kylebeee/arc53-watcher-go/db/compound"
+	"github.com/kylebeee/arc53-watcher-go/errors"
+	"github.com/kylebeee/arc53-watcher-go/misc"
+	"github.com/kylebeee/arc53-watcher-go/uuid"
+)
+
+// State is what a provider app declares on chain, read by its provider type
+type State struct {
+	// ID is the provider app ID
+	ID uint64
+	// Type is the provider type the app belongs to
+	Type string
+	// Round is the round the state was read at
+	Round uint64
+	// Metadata is the ARC53 metadata url or inline document, nil when the app doesn't set one
+	Metadata *string
+	// Addresses are the verified addresses of the provider app
+	Addresses []string
+	// Unverified are addresses the app declares without proof it controls them,
+	// they're stored as unverified & left out of lookups
+	Unverified []string
+}
+
+// Syncer writes a provider app's state & community metadata to the database,
+// it's shared by every provider type so they only need to know how to read their apps
+type Syncer struct {
+	DB      *sqlx.DB
+	Fetcher arc53.MetadataFetcher
+}
+
+func NewSyncer(dbConn *sqlx.DB, fetcher arc53.MetadataFetcher) *Syncer {
+	if fetcher == nil {
+		fetcher = arc53.NewGatewayFetcher(arc53.FetcherConfig{})
+	}
+
+	return &Syncer{
+		DB:      dbConn,
+		Fetcher: fetcher,
+	}
+}
+
+// BlockTx is the transaction the syncs of a block are written in, the watcher commits it along
+// with its cursor so a block is either applied in full or processed again from scratch
+type BlockTx struct {
+	*sqlx.Tx
+	// events are written to the outbox when the block commits
+	events []db.Event
+	syncs  int
+	// committed runs once the block commits, for state kept outside the database
+	committed []func()
+}
+
+// AfterCommit runs fn once the block commits so state kept in memory only changes along with
+// the database, without a block fn runs right away as the sync it follows has committed
+func (b *BlockTx) AfterCommit(fn func()) {
+	if b == nil {
+		fn()
+		return
+	}
+	b.committed = append(b.committed, fn)
+}
+
+func BeginBlock(dbConn *sqlx.DB) (*BlockTx, error) {
+	const op errors.Op = "BeginBlock"
+
+	tx, err := dbConn.Beginx()
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	return &BlockTx{Tx: tx}, nil
+}
+
+// Commit writes the events of the block's syncs to the outbox, commits the block & runs what
+// was left for after the commit
+func (b *BlockTx) Commit() error {
+	const op errors.Op = "BlockTx.Commit"
+
+	err := commitWithEvents(b.Tx, b.events)
+	if err != nil {
+		return errors.E(op, err)
+	}
+
+	for _, fn := range b.committed {
+		fn()
+	}
+
+	return nil
+}
+
+// commitWithEvents writes events to the outbox & commits tx
+func commitWithEvents(tx *sqlx.Tx, events []db.Event) error {
+	const op errors.Op = "commitWithEvents"
+
+	// the event sequence stays locked from here until the commit, so events commit in id order
+	err := db.InsertEvents(tx, events)
+	if err != nil {
+		tx.Rollback()
+		return errors.E(op, err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		tx.Rollback()
+		return errors.E(op, err)
+	}
+
+	return nil
+}
+
+// Sync brings the database in line with the given provider state in a single transaction
+// & returns what it changed
+func (s *Syncer) Sync(state State) (*Changes, error) {
+	const op errors.Op = "Syncer.Sync"
+
+	tx, err := s.DB.Beginx()
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	changes, err := s.sync(tx, state)
+	if err != nil {
+		tx.Rollback()
+		return nil, errors.E(op, err)
+	}
+
+	err = commitWithEvents(tx, changes.events)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	if changes.fetchErr != nil {
+		return changes, errors.E(op, changes.fetchErr)
+	}
+
+	return changes, nil
+}
+
+// SyncIn syncs the provider state as part of a block, a failed sync is rolled back on its own
+// & leaves the rest of the block in place. Without a block it's the same as Sync
+func (s *Syncer) SyncIn(btx *BlockTx, state State) (*Changes, error) {
+	const op errors.Op = "Syncer.SyncIn"
+
+	if btx == nil {
+		return s.Sync(state)
+	}
+
+	savepoint := fmt.Sprintf("sync_%d", btx.syncs)
+	btx.syncs++
+
+	_, err := btx.Exec("savepoint " + savepoint)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	changes, err := s.sync(btx.Tx, state)
+	if err != nil {
+		_, rollbackErr := btx.Exec("rollback to savepoint " + savepoint)
+		if rollbackErr != nil {
+			fmt.Println(errors.E(op, rollbackErr))
+		}
+		return nil, errors.E(op, err)
+	}
+
+	_, err = btx.Exec("release savepoint " + savepoint)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	btx.events = append(btx.events, changes.events...)
+
+	if changes.fetchErr != nil {
+		return changes, errors.E(op, changes.fetchErr)
+	}
+
+	return changes, nil
+}
+
+// sync writes the provider state in tx, events are stamped but left for the caller to write.
+// Metadata that can't be fetched leaves the community as it was while the provider & its
+// addresses are still written, the failure is left in changes for the caller to return once
+// they're committed so the sync is retried
+func (s *Syncer) sync(tx *sqlx.Tx, state State) (*Changes, error) {
+	const op errors.Op = "Syncer.sync"
+	var new bool = false
+
+	changes := &Changes{}
+
+	_, err := db.GetProvider(tx, state.ID)
+	if err != nil && !db.ErrNoRows(err) {
+		return nil, errors.E(op, err)
+	} else if db.ErrNoRows(err) {
+		new = true
+	}
+
+	dniAddresses := []string{}
+	addresses := map[string]db.ProviderAddress{}
+
+	if !new {
+		preexistingAddresses, err := db.GetProviderAddresses(tx, state.ID)
+		if err != nil && !db.ErrNoRows(err) {
+			return nil, errors.E(op, err)
+		}
+
+		if preexistingAddresses != nil {
+			for _, address := range *preexistingAddresses {
+				addresses[address.Address] = address
+			}
+		}
+	}
+
+	if state.Metadata != nil {
+		err = s.processCommunity(tx, changes, state.ID, []byte(*state.Metadata))
+		if err != nil && errors.HasKind(err, errors.Network) {
+			fmt.Printf("[WARN][COMMUNITY] metadata for %v could not be fetched, keeping the community as is\n", state.ID)
+			changes.fetchErr = err
+		} else if err != nil {
+			return nil, errors.E(op, err)
+		}
+	}
+
+	// an address listed as both verified & unverified is verified
+	verified := misc.UniqueSlice(state.Addresses)
+	declared := append([]string{}, verified...)
+	for _, address := range misc.UniqueSlice(state.Unverified) {
+		if !misc.InSlice(address, verified) {
+			declared = append(declared, address)
+		}
+	}
+
+	for _, address := range declared {
+		dniAddresses = append(dniAddresses, address)
+		isVerified := misc.InSlice(address, verified)
+
+		wallet, walletExists := addresses[address]
+		if !walletExists {
+			_, err = db.Insert(tx, &db.ProviderAddress{ID: state.ID, Address: address, Verified: misc.PointerBool(isVerified)})
+			if err != nil {
+				return nil, errors.E(op, err)
+			}
+			changes.AddressesAdded = append(changes.AddressesAdded, address)
+			changes.emit(db.EventAddressCreated, address)
+			continue
+		}
+
+		if (wallet.Verified != nil && *wallet.Verified) != isVerified {
+			_, err = db.Update(tx, &db.ProviderAddress{Verified: misc.PointerBool(isVerified)}, map[string]interface{}{"id": state.ID, "address": address})
+			if err != nil {
+				return nil, errors.E(op, err)
+			}
+			if isVerified {
+				changes.AddressesVerified = append(changes.AddressesVerified, address)
+			} else {
+				changes.AddressesUnverified = append(changes.AddressesUnverified, address)
+			}
+		}
+	}
+
+	for address := range addresses {
+		if !misc.InSlice(address, dniAddresses) {
+			changes.AddressesRemoved = append(changes.AddressesRemoved, address)
+		}
+	}
+	sort.Strings(changes.AddressesRemoved)
+	for _, address := range changes.AddressesRemoved {
+		changes.emit(db.EventAddressDeleted, address)
+	}
+
+	// delete wallets not in list
+	err = db.DeleteProviderAddressNotIn(tx, state.ID, dniAddresses...)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	if state.Metadata == nil {
+		_, err = db.GetCommunity(tx, state.ID)
+		if err != nil && !db.ErrNoRows(err) {
+			return nil, errors.E(op, err)
+		} else if !db.ErrNoRows(err) {
+			err = compound.DeleteCommunity(tx, state.ID)
+			if err != nil {
+				return nil, errors.E(op, err)
+			}
+			changes.CommunityDeleted = true
+			changes.emit(db.EventCommunityDeleted, "")
+		}
+	}
+
+	if new {
+		_, err = db.Insert(tx, &db.Provider{ID: state.ID, Type: state.Type, Round: state.Round})
+		if err != nil {
+			return nil, errors.E(op, err)
+		}
+		changes.ProviderCreated = true
+	}
+
+	now := time.Now().UnixMilli()
+	for i := range changes.events {
+		changes.events[i].ProviderID = state.ID
+		changes.events[i].Round = state.Round
+		changes.events[i].Created = now
+	}
+
+	return changes, nil
+}
+
+func (s *Syncer) processCommunity(tx *sqlx.Tx, changes *Changes, id uint64, data []byte) error {
+	const op errors.Op = "ProcessCommunity"
+
+	if strings.HasPrefix(string(data), "ipfs://") || strings.HasPrefix(string(data), "https://") {
+		metadata, err := s.Fetcher.Fetch(context.Background(), string(data))
+		if err != nil && errors.HasKind(err, errors.Integrity) {
+			fmt.Printf("[WARN][COMMUNITY] metadata for %v failed verification: %s\n", id, data)
+			changes.CIDMismatch = true
+			changes.emit(db.EventCommunityUpdated, "")
+			return s.recordCIDMismatch(tx, id)
+		} else if err != nil {
+			return errors.E(op, errors.Network, err)
+		}
+		data = metadata
+	}
+
+	validationErrors := arc53.ValidateCommunity(data)
+	if validationErrors == nil {
+		validationErrors = []arc53.ValidationError{}
+	}
+
+	validationJson, err := json.Marshal(validationErrors)
+	if err != nil {
+		return errors.E(op, err)
+	}
+
+	commJson := &db.CommunityJson{
+		ID:               id,
+		Data:             string(data),
+		Malformed:        misc.PointerBool(len(validationErrors) > 0),
+		CIDMismatch:      misc.PointerBool(false),
+		ValidationErrors: misc.Pointer(string(validationJson)),
+	}
+
+	// the data column only holds json, the validation errors say why it's missing
+	if !json.Valid(data) {
+		commJson.Data = "null"
+	}
+
+	prevJson, err := db.GetCommunityJson(tx, id)
+	if err != nil && !db.ErrNoRows(err) {
+		return errors.E(op, err)
+	} else if db.ErrNoRows(err) {
+		_, err = db.Insert(tx, commJson)
+		if err != nil {
+			return errors.E(op, err)
+		}
+	} else {
+		if prevJson.Data == commJson.Data &&
+			(prevJson.CIDMismatch == nil || !*prevJson.CIDMismatch) &&
+			prevJson.ValidationErrors != nil {
+			return nil
+		}
+
+		_, err = db.Update(tx, commJson, map[string]interface{}{"id": id})
+		if err != nil {
+			return errors.E(op, err)
+		}
+	}
+	changes.MetadataChanged = true
+
+	if len(validationErrors) > 0 {
+		changes.Malformed = true
+		changes.emit(db.EventCommunityUpdated, "")
+		fmt.Printf("[WARN][COMMUNITY] community %v failed validation with %d errors, first: %s\n", id, len(validationErrors), validationErrors[0])
+		return nil
+	}
+
+	communityData := compound.Community{}
+	err = json.Unmarshal(data, &communityData)
+	if err != nil {
+		fmt.Println(err)
+
+		validationJson, _ = json.Marshal([]arc53.ValidationError{{Message: err.Error()}})
+		commJson.Malformed = misc.PointerBool(true)
+		commJson.ValidationErrors = misc.Pointer(string(validationJson))
+		_, err := db.Update(tx, commJson, map[string]interface{}{"id": id})
+		if err != nil {
+			return errors.E(op, err)
+		}
+		changes.Malformed = true
+		changes.emit(db.EventCommunityUpdated, "")
+
+		return nil
+	}
+
+	comm := communityData.Community
+	comm.ID = id
+
+	_, err = db.GetCommunity(tx, id)
+	if err != nil && !db.ErrNoRows(err) {
+		return errors.E(op, err)
+	} else if db.ErrNoRows(err) {
+		_, err = db.Insert(tx, comm)
+		if err != nil {
+			return errors.E(op, err)
+		}
+		changes.CommunityCreated = true
+		changes.emit(db.EventCommunityCreated, "")
+	} else {
+		changes.emit(db.EventCommunityUpdated, "")
+	}
+
+	err = s.processTokens(tx, changes, id, communityData.Tokens)
+	if err != nil {
+		return errors.E(op, err)
+	}
+
+	err = s.processAssociates(tx, changes, id, communityData.Associates)
+	if err != nil {
+		return errors.E(op, err)
+	}
+
+	err = s.processCollections(tx, changes, id, communityData.Collections)
+	if err != nil {
+		return errors.E(op, err)
+	}
+
+	err = s.processFaq(tx, id, communityData.Faq)
+	if err != nil {
+		return errors.E(op, err)
+	}
+
+	err = s.processExtras(tx, id, communityData.Extras)
+	if err != nil {
+		return errors.E(op, err)
+	}
+
+	return nil
+}
+
+// recordCIDMismatch flags the community json as failing verification without
+// touching the community data that was ingested from content that did verify
+func (s *Syncer) recordCIDMismatch(tx *sqlx.Tx, id uint64) error {
+	const op errors.Op = "recordCIDMismatch"
+
+	_, err := db.GetCommunityJson(tx, id)
+	if err != nil && !db.ErrNoRows(err) {
+		return errors.E(op, err)
+	} else if db.ErrNoRows(err) {
+		_, err = db.Insert(tx, &db.CommunityJson{ID: id, Data: "null", CIDMismatch: misc.PointerBool(true)})
+		if err != nil {
+			return errors.E(op, err)
+		}
+		return nil
+	}
+
+	_, err = db.Update(tx, &db.CommunityJson{CIDMismatch: misc.PointerBool(true)}, map[string]interface{}{"id": id})
+	if err != nil {
+		return errors.E(op, err)
+	}
+
+	return nil
+}
+
+func (s *Syncer) processTokens(tx *sqlx.Tx, changes *Changes, id uint64, tokensData []db.CommunityToken) error {
+	const op errors.Op = "ProcessTokens"
+
+	tokenKeys := map[uint64]db.CommunityToken{}
+	tokens, err := db.GetCommunityTokens(tx, id)
+	if err != nil && !db.ErrNoRows(err) {
+		return errors.E(op, err)
+	}
+
+	if tokens != nil {
+		for _, token := range *tokens {
+			tokenKeys[token.AssetID] = token
+		}
+	}
+
+	dniKeys := []uint64{}
+	for _, t := range tokensData {
+		token := t
+
+		// we dont need to trigger the asset cache processor here because *if*
+		// the asset is actually created by this community it will be picked up while
+		// the system processes the verified wallets created assets
+
+		pre, exists := tokenKeys[token.AssetID]
+		dniKeys = append(dniKeys, token.AssetID)
+		token.ID = id
+		if !exists {
+			_, err = db.Insert(tx, &token)
+			if err != nil {
+				return errors.E(op, err)
+			}
+			changes.TokensAdded = append(changes.TokensAdded, token.AssetID)
+			changes.emit(db.EventTokenCreated, strconv.FormatUint(token.AssetID, 10))
+		} else {
+			_, err = db.Update(tx, &token, map[string]interface{}{"id": id, "asset_id": token.AssetID})
+			if err != nil {
+				return errors.E(op, err)
+			}
+
+			preJson, err := json.Marshal(pre)
+			if err != nil {
+				return errors.E(op, err)
+			}
+
+			tokenJson, err := json.Marshal(token)
+			if err != nil {
+				return errors.E(op, err)
+			}
+
+			if string(preJson) != string(tokenJson) {
+				changes.emit(db.EventTokenUpdated, strconv.FormatUint(token.AssetID, 10))
+			}
+		}
+	}
+
+	err = db.DeleteCommunityTokensNotIn(tx, id, dniKeys...)
+	if err != nil {
+		return errors.E(op, err)
+	}
+
+	if tokens != nil {
+		for _, token := range *tokens {
+			if !misc.InSlice(token.AssetID, dniKeys) {
+				changes.TokensRemoved = append(changes.TokensRemoved, token.AssetID)
+				changes.emit(db.EventTokenDeleted, strconv.FormatUint(token.AssetID, 10))
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *Syncer) processAssociates(tx *sqlx.Tx, changes *Changes, id uint64, associateData []db.CommunityAssociate) error {
+	const op errors.Op = "ProcessTokens"
+
+	associateKeys := map[string]db.CommunityAssociate{}
+	associates, err := db.GetCommunityAssociates(tx, id)
+	if err != nil && !db.ErrNoRows(err) {
+		return errors.E(op, err)
+	}
+
+	for _, associate := range *associates {
+		associateKeys[associate.Address] = associate
+	}
+
+	dniKeys := []string{}
+	for _, a := range associateData {
+		associate := a
+
+		_, exists := associateKeys[associate.Address]
+		dniKeys = append(dniKeys, associate.Address)
+		if !exists {
+			associate.ID = id
+			_, err = db.Insert(tx, &associate)
+			if err != nil {
+				return errors.E(op, err)
+			}
+			changes.emit(db.EventAssociateCreated, associate.Address)
+		}
+	}
+
+	err = db.DeleteCommunityAssociatesNotIn(tx, id, dniKeys...)
+	if err != nil {
+		return errors.E(op, err)
+	}
+
+	for _, associate := range *associates {
+		if !misc.InSlice(associate.Address, dniKeys) {
+			changes.emit(db.EventAssociateDeleted, associate.Address)
+		}
+	}
+
+	return nil
+}
+
+func (s *Syncer) processCollections(tx *sqlx.Tx, changes *Changes, id uint64, collectionsData []compound.Collection) error {
+	const op errors.Op = "ProcessCollections"
+
+	collectionKeys := map[string]compound.Collection{}
+	collections, err := compound.GetCollectionsByProviderID(tx, id)
+	if err != nil {
+		if err.(*errors.Error).Kind != errors.DatabaseResultNotFound {
+			return errors.E(op, err)
+		}
+
+		// even if we get no results back, the map will be empty
+		// and will be caught by the check for pre-existing collections
+	}
+
+	for i := range *collections {
+		col := (*collections)[i]
+		collectionKeys[col.Name] = col
+	}
+
+	dniCollection := []string{}
+	for _, col := range collectionsData {
+		pre, exists := collectionKeys[col.Name]
+		if exists {
+			dniCollection = append(dniCollection, pre.ID)
+
+			preJson, err := json.Marshal(pre)
+			if err != nil {
+				return errors.E(op, err)
+			}
+
+			colJson, err := json.Marshal(col)
+			if err != nil {
+				return errors.E(op, err)
+			}
+
+			if string(preJson) == string(colJson) {
+				continue
+			}
+			changes.CollectionsUpdated = append(changes.CollectionsUpdated, col.Name)
+			changes.emit(db.EventCollectionUpdated, col.Name)
+
+			// update
+			_, err = db.Update(tx, col.Collection, map[string]interface{}{"id": pre.ID})
+			if err != nil {
+				return errors.E(op, err)
+			}
+
+			// insert or update prefixes
+			// dni = Delete not in
+			dniPrefixes := []string{}
+			for _, prefix := range col.Prefixes {
+				dniPrefixes = append(dniPrefixes, prefix)
+
+				if !misc.InSlice(prefix, pre.Prefixes) {
+					_, err = db.Insert(tx, &db.CollectionPrefix{ID: pre.ID, Prefix: prefix})
+					if err != nil {
+						return errors.E(op, err)
+					}
+				}
+			}
+
+			// delete not in prefix list
+			err = db.DeleteCollectionPrefixesNotIn(tx, pre.ID, dniPrefixes...)
+			if err != nil {
+				return errors.E(op, err)
+			}
+
+			dniAddresses := []string{}
+			for _, address := range col.Addresses {
+				dniAddresses = append(dniAddresses, address)
+
+				if !misc.InSlice(address, pre.Addresses) {
+					_, err = db.Insert(tx, &db.CollectionAddress{ID: pre.ID, Address: address})
+					if err != nil {
+						return errors.E(op, err)
+					}
+				}
+			}
+
+			// delete not in address list
+			err = db.DeleteCollectionAddressesNotIn(tx, pre.ID, dniAddresses...)
+			if err != nil {
+				return errors.E(op, err)
+			}
+
+			// insert or update assets
+			// dni = Delete not in
+			dniAssets := []uint64{}
+			for _, asset := range col.Assets {
+				dniAssets = append(dniAssets, asset)
+
+				if !misc.InSlice(asset, pre.Assets) {
+					_, err = db.Insert(tx, &db.CollectionAsset{ID: pre.ID, AsaID: asset})
+					if err != nil {
+						return errors.E(op, err)
+					}
+				}
+			}
+
+			// delete not in asset list
+			err = db.DeleteCollectionAssetsNotIn(tx, pre.ID, dniAssets...)
+			if err != nil {
+				return errors.E(op, err)
+			}
+
+			// insert or update excluded assets
+			// dni = Delete not in
+			dniExcludedAssets := []uint64{}
+			for _, asset := range col.ExcludedAssets {
+				dniExcludedAssets = append(dniExcludedAssets, asset)
+
+				if !misc.InSlice(asset, pre.ExcludedAssets) {
+					_, err = db.Insert(tx, &db.CollectionExcludedAsset{ID: pre.ID, AsaID: asset})
+					if err != nil {
+						return errors.E(op, err)
+					}
+				}
+			}
+
+			// delete not in excluded asset list
+			err = db.DeleteCollectionExcludedAssetsNotIn(tx, pre.ID, dniExcludedAssets...)
+			if err != nil {
+				return errors.E(op, err)
+			}
+
+			dniArtists := []string{}
+			for _, address := range col.Artists {
+				dniArtists = append(dniArtists, address)
+
+				if !misc.InSlice(address, pre.Artists) {
+					_, err = db.Insert(tx, &db.CollectionArtist{ID: pre.ID, Address: address})
+					if err != nil {
+						return errors.E(op, err)
+					}
+				}
+			}
+
+			// delete not in artist list
+			err = db.DeleteCollectionArtistsNotIn(tx, pre.ID, dniArtists...)
+			if err != nil {
+				return errors.E(op, err)
+			}
+
+			propertyKeys := map[string]compound.Property{}
+			for _, property := range pre.Properties {
+				propertyKeys[property.Name] = property
+			}
+
+			// dni = Delete not in
+			dniProperties := []string{}
+			for _, prop := range col.Properties {
+				preProp, propExists := propertyKeys[prop.Name]
+				if propExists {
+					prop.ID = preProp.ID
+					prop.CollectionID = preProp.CollectionID
+
+					dniProperties = append(dniProperties, prop.ID)
+
+					_, err = db.Update(tx, prop.Property, map[string]interface{}{"id": preProp.ID})
+					if err != nil {
+						return errors.E(op, err)
+					}
+
+					propValueNames := []string{}
+					for _, value := range preProp.Values {
+						propValueNames = append(propValueNames, value.Name)
+					}
+
+					// insert or update properties values
+					dniPropertiesValues := []string{}
+					for _, value := range prop.Values {
+						dniPropertiesValues = append(dniPropertiesValues, value.Name)
+						value.ID = preProp.ID
+
+						if misc.InSlice(value.Name, propValueNames) {
+							_, err = db.Update(tx, value.PropertyValue, map[string]interface{}{"id": preProp.ID, "name": value.Name})
+							if err != nil {
+								return errors.E(op, err)
+							}
+						} else {
+							_, err = db.Insert(tx, value.PropertyValue)
+							if err != nil {
+								return errors.E(op, err)
+							}
+						}
+
+						propValueExtrasKeys := []string{}
+						for _, pvalue := range preProp.Values {
+							if pvalue.Name == value.Name {
+								for extraKey := range pvalue.Extras {
+									propValueExtrasKeys = append(propValueExtrasKeys, extraKey)
+								}
+							}
+						}
+
+						// fmt.Println("propValueExtrasKeys: ", propValueExtrasKeys)
+
+						// update or insert properties values extras
+						dniPropertiesValuesExtras := []string{}
+						for extraKey, extraValue := range value.Extras {
+							dniPropertiesValuesExtras = append(dniPropertiesValuesExtras, extraKey)
+
+							extra := &db.PropertyValueExtras{
+								ID:    preProp.ID,
+								Name:  value.Name,
+								Key:   extraKey,
+								Value: extraValue,
+							}
+
+							if misc.InSlice(extraKey, propValueExtrasKeys) {
+								_, err = db.Update(tx, extra, map[string]interface{}{"id": preProp.ID, "name": value.Name, "mkey": extra.Key})
+								if err != nil {
+									return errors.E(op, err)
+								}
+							} else {
+								_, err = db.Insert(tx, extra)
+								if err != nil {
+									return errors.E(op, err)
+								}
+							}
+						}
+
+						// delete not in properties values extras list
+						err = db.DeletePropertyValueExtrasNotIn(tx, preProp.ID, value.Name, dniPropertiesValuesExtras...)
+						if err != nil {
+							return errors.E(op, err)
+						}
+					}
+
+					// delete not in properties values list
+					err = db.DeletePropertyValueNotIn(tx, preProp.ID, dniPropertiesValues...)
+					if err != nil {
+						return errors.E(op, err)
+					}
+
+				} else {
+					// insert prop that didnt exist before
+					prop.ID = uuid.New(uuid.Property)
+					prop.CollectionID = pre.ID
+
+					dniProperties = append(dniProperties, prop.ID)
+
+					_, err = db.Insert(tx, prop.Property)
+					if err != nil {
+						return errors.E(op, err)
+					}
+
+					for _, value := range prop.Values {
+						value.ID = prop.ID
+
+						_, err = db.Insert(tx, value.PropertyValue)
+						if err != nil {
+							return errors.E(op, err)
+						}
+
+						for extraKey, extraValue := range value.Extras {
+							// fmt.Println("inserting extra: ", prop.ID, " - ", extraKey, " - ", extraValue)
+
+							extra := &db.PropertyValueExtras{
+								ID:    prop.ID,
+								Name:  value.Name,
+								Key:   extraKey,
+								Value: extraValue,
+							}
+
+							_, err = db.Insert(tx, extra)
+							if err != nil {
+								return errors.E(op, err)
+							}
+						}
+					}
+				}
+			}
+
+			for _, prop := range pre.Properties {
+				if !misc.InSlice(prop.ID, dniProperties) {
+					// delete prop values & meta that should no longer exist
+					err = db.DeletePropertyValues(tx, prop.ID)
+					if err != nil {
+						return errors.E(op, err)
+					}
+
+					err = db.DeletePropertyValueExtras(tx, prop.ID)
+					if err != nil {
+						return errors.E(op, err)
+					}
+				}
+			}
+
+			err = db.DeletePropertyNotIn(tx, pre.ID, dniProperties...)
+			if err != nil {
+				return errors.E(op, err)
+			}
+
+			preExtras := []string{}
+			for extraKey := range pre.Extras {
+				preExtras = append(preExtras, extraKey)
+			}
+
+			// insert or update extras
+			// dni = Delete not in
+			dniExtras := []string{}
+			for extraKey, extraValue := range col.Extras {
+				dniExtras = append(dniExtras, extraKey)
+
+				extra := &db.CollectionExtras{
+					ID:    pre.ID,
+					Key:   extraKey,
+					Value: extraValue,
+				}
+
+				if misc.InSlice(extraKey, preExtras) {
+					_, err = db.Update(tx, extra, map[string]interface{}{"id": pre.ID, "mkey": extra.Key})
+					if err != nil {
+						return errors.E(op, err)
+					}
+				} else {
+					_, err = db.Insert(tx, extra)
+					if err != nil {
+						return errors.E(op, err)
+					}
+				}
+			}
+
+			// delete not in extras list
+			err = db.DeleteCollectionExtrasNotIn(tx, pre.ID, dniExtras...)
+			if err != nil {
+				return errors.E(op, err)
+			}
+		} else {
+			// insert
+			col.ID = uuid.New(uuid.Collection)
+			col.ProviderID = id
+			dniCollection = append(dniCollection, col.ID)
+			changes.CollectionsAdded = append(changes.CollectionsAdded, col.Name)
+			changes.emit(db.EventCollectionCreated, col.Name)
+
+			// collection
+			_, err = db.Insert(tx, col.Collection)
+			if err != nil {
+				return errors.E(op, err)
+			}
+
+			// prefixes
+			for _, prefix := range col.Prefixes {
+				_, err = db.Insert(tx, &db.CollectionPrefix{ID: col.ID, Prefix: prefix})
+				if err != nil {
+					return errors.E(op, err)
+				}
+			}
+
+			// asset
+			for _, asset := range col.Assets {
+				_, err = db.Insert(tx, &db.CollectionAsset{ID: col.ID, AsaID: asset})
+				if err != nil {
+					return errors.E(op, err)
+				}
+			}
+
+			// excluded_asset
+			for _, asset := range col.ExcludedAssets {
+				_, err = db.Insert(tx, &db.CollectionExcludedAsset{ID: col.ID, AsaID: asset})
+				if err != nil {
+					return errors.E(op, err)
+				}
+			}
+
+			// artist
+			for _, artist := range col.Artists {
+				_, err = db.Insert(tx, &db.CollectionArtist{ID: col.ID, Address: artist})
+				if err != nil {
+					return errors.E(op, err)
+				}
+			}
+
+			// properties
+			for _, prop := range col.Properties {
+				prop.ID = uuid.New(uuid.Property)
+				prop.CollectionID = col.ID
+
+				_, err = db.Insert(tx, prop.Property)
+				if err != nil {
+					return errors.E(op, err)
+				}
+
+				// property values
+				for _, value := range prop.Values {
+					value.ID = prop.ID
+
+					_, err = db.Insert(tx, value.PropertyValue)
+					if err != nil {
+						return errors.E(op, err)
+					}
+
+					for extraKey, extraValue := range value.Extras {
+
+						extra := &db.PropertyValueExtras{
+							ID:    prop.ID,
+							Name:  value.Name,
+							Key:   extraKey,
+							Value: extraValue,
+						}
+
+						_, err = db.Insert(tx, extra)
+						if err != nil {
+							return errors.E(op, err)
+						}
+					}
+				}
+			}
+
+			// extras
+			for extraKey, extraValue := range col.Extras {
+				_, err = db.Insert(tx, &db.CollectionExtras{ID: col.ID, Key: extraKey, Value: extraValue})
+				if err != nil {
+					return errors.E(op, err)
+				}
+			}
+		}
+	}
+
+	err = db.DeleteCollectionNotIn(tx, id, dniCollection...)
+	if err != nil {
+		return errors.E(op, err)
+	}
+
+	for _, col := range *collections {
+		if !misc.InSlice(col.ID, dniCollection) {
+			changes.CollectionsRemoved = append(changes.CollectionsRemoved, col.Name)
+			changes.emit(db.EventCollectionDeleted, col.Name)
+		}
+	}
+
+	return nil
+}
+
+func (s *Syncer) processFaq(tx *sqlx.Tx, id uint64, faqData []db.CommunityFaq) error {
+	const op errors.Op = "ProcessFaq"
+	var err error
+
+	err = db.DeleteCommunityFaq(tx, id)
+	if err != nil {
+		return errors.E(op, err)
+	}
+
+	for i, faq := range faqData {
+		nfaq := faq
+
+		nfaq.ID = id
+		nfaq.Ordering = misc.Pointer(uint64(i))
+
+		_, err = db.Insert(tx, &nfaq)
+		if err != nil {
+			return errors.E(op, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *Syncer) processExtras(tx *sqlx.Tx, id uint64, extrasData []db.CommunityExtras) error {
+	const op errors.Op = "ProcessExtras"
+
+	extraKeys := map[string]db.CommunityExtras{}
+	extras, err := db.GetCommunityExtras(tx, id)
+	if err != nil && !db.ErrNoRows(err) {
+		return errors.E(op, err)
+	}
+
+	for _, extra := range *extras {
+		extraKeys[extra.Key] = extra
+	}
+
+	dniKeys := []string{}
+	for _, extra := range extrasData {
+		nextra := extra
+
+		dniKeys = append(dniKeys, extra.Key)
+		pre, exists := extraKeys[extra.Key]
+		if exists {
+			_, err = db.Update(tx, &nextra, map[string]interface{}{"id": pre.ID})
+			if err != nil {
+				return errors.E(op, err)
+			}
+		} else {
+
+			nextra.ID = id
+			_, err = db.Insert(tx, &nextra)
+			if err != nil {
+				return errors.E(op, err)
+			}
+		}
+	}
+
+	err = db.DeleteCommunityExtrasNotIn(tx, id, dniKeys...)
+	if err != nil {
+		return errors.E(op, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,62 @@
+package community
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+)
+
+func TestBlockTxAfterCommit(t *testing.T) {
+	tests := []struct {
+		name      string
+		commitErr error
+		want      bool
+	}{
+		{name: "runs once the block commits", want: true},
+		{name: "doesn't run when the commit fails", commitErr: fmt.Errorf("deadlock")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+
+			mock.ExpectBegin()
+			mock.ExpectCommit().WillReturnError(tt.commitErr)
+
+			btx, err := BeginBlock(sqlx.NewDb(conn, "mysql"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ran := false
+			btx.AfterCommit(func() { ran = true })
+			if ran {
+				t.Fatal("ran before the block committed")
+			}
+
+			err = btx.Commit()
+			if (err != nil) != (tt.commitErr != nil) {
+				t.Fatalf("Commit error %v, want %v", err, tt.commitErr)
+			}
+			if ran != tt.want {
+				t.Fatalf("ran = %v, want %v", ran, tt.want)
+			}
+		})
+	}
+
+	t.Run("runs right away without a block", func(t *testing.T) {
+		var btx *BlockTx
+
+		ran := false
+		btx.AfterCommit(func() { ran = true })
+		if !ran {
+			t.Fatal("didn't run without a block")
+		}
+	})
+}
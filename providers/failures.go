@@ -0,0 +1,45 @@
+package providers
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/kylebeee/arc53-watcher-go/errors"
+)
+
+// SyncFailures are the apps a provider type failed to sync while processing a block by
+// app ID, block processing returns them so the watcher can retry each app with Process
+type SyncFailures map[uint64]error
+
+func (f SyncFailures) AppIDs() []uint64 {
+	appIDs := make([]uint64, 0, len(f))
+	for appID := range f {
+		appIDs = append(appIDs, appID)
+	}
+	sort.Slice(appIDs, func(i, j int) bool { return appIDs[i] < appIDs[j] })
+	return appIDs
+}
+
+func (f SyncFailures) Error() string {
+	failures := []string{}
+	for _, appID := range f.AppIDs() {
+		failures = append(failures, fmt.Sprintf("%v: %v", appID, f[appID]))
+	}
+	return fmt.Sprintf("failed to sync %d apps: %s", len(f), strings.Join(failures, "; "))
+}
+
+// AsSyncFailures finds the SyncFailures in an *errors.Error chain
+func AsSyncFailures(err error) (SyncFailures, bool) {
+	for err != nil {
+		switch e := err.(type) {
+		case SyncFailures:
+			return e, true
+		case *errors.Error:
+			err = e.Err
+		default:
+			return nil, false
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,113 @@
+//go:build capture
+
+package nfd
+
+// The capture test refreshes the fixtures in testdata from mainnet & testnet. Blocks are
+// fetched raw from algod & indexer transactions as JSON, each fixture is named with the
+// round or transaction ID it's captured from & the minted app IDs found in it are logged so
+// the expectations in mint_test.go can be checked against them:
+//
+//	CAPTURE_FIXTURES="mint_v2_mainnet.msgp=<round>,indexer_mint_v2_mainnet.json=<txid>" \
+//		go test -tags capture -count=1 -v ./providers/nfd/ -run Capture
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/algorand/go-algorand-sdk/v2/client/v2/algod"
+	"github.com/algorand/go-algorand-sdk/v2/client/v2/indexer"
+	streamer "github.com/kylebeee/arc53-watcher-go/internal/algod"
+)
+
+// captureEndpoints are the public nodes fixtures are captured from unless
+// CAPTURE_<NETWORK>_ALGOD or CAPTURE_<NETWORK>_INDEXER point elsewhere
+var captureEndpoints = map[string][2]string{
+	"mainnet": {"https://mainnet-api.algonode.cloud", "https://mainnet-idx.algonode.cloud"},
+	"testnet": {"https://testnet-api.algonode.cloud", "https://testnet-idx.algonode.cloud"},
+}
+
+func captureEndpoint(network string, i int, name string) string {
+	address := os.Getenv("CAPTURE_" + strings.ToUpper(network) + "_" + name)
+	if address != "" {
+		return address
+	}
+	return captureEndpoints[network][i]
+}
+
+func TestCaptureFixtures(t *testing.T) {
+	fixtures := os.Getenv("CAPTURE_FIXTURES")
+	if fixtures == "" {
+		t.Skip("CAPTURE_FIXTURES isn't set")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	for _, fixture := range strings.Split(fixtures, ",") {
+		name, source, ok := strings.Cut(strings.TrimSpace(fixture), "=")
+		if !ok {
+			t.Fatalf("fixture %q isn't <name>=<round or txid>", fixture)
+		}
+
+		network := "mainnet"
+		registry := NFDMainNetRegistryAppID
+		if strings.Contains(name, "_testnet") {
+			network = "testnet"
+			registry = NFDTestNetRegistryAppID
+		}
+
+		var raw []byte
+		var minted []uint64
+		switch filepath.Ext(name) {
+		case ".msgp":
+			round, err := strconv.ParseUint(source, 10, 64)
+			if err != nil {
+				t.Fatalf("%s: round %q: %v", name, source, err)
+			}
+			client, err := algod.MakeClient(captureEndpoint(network, 0, "ALGOD"), os.Getenv("CAPTURE_ALGOD_TOKEN"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			raw, err = client.BlockRaw(round).Do(ctx)
+			if err != nil {
+				t.Fatalf("%s: block %d: %v", name, round, err)
+			}
+
+			block, err := streamer.DecodeBlock(raw)
+			if err != nil {
+				t.Fatalf("%s: DecodeBlock: %v", name, err)
+			}
+			for i := range block.Payset {
+				minted = append(minted, MintedAppIDs(block.Payset[i].SignedTxnWithAD, registry)...)
+			}
+		case ".json":
+			client, err := indexer.MakeClient(captureEndpoint(network, 1, "INDEXER"), os.Getenv("CAPTURE_INDEXER_TOKEN"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			response, err := client.LookupTransaction(source).Do(ctx)
+			if err != nil {
+				t.Fatalf("%s: transaction %s: %v", name, source, err)
+			}
+			raw, err = json.MarshalIndent(response.Transaction, "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			minted = MintedAppIDsFromIndexer(response.Transaction, registry)
+		default:
+			t.Fatalf("fixture %q is neither a .msgp block nor a .json transaction", name)
+		}
+
+		err := os.WriteFile(filepath.Join("testdata", name), raw, 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Logf("%s from %s %s mints %v", name, network, source, minted)
+	}
+}
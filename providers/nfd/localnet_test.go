@@ -0,0 +1,539 @@
+//go:build localnet
+
+package nfd
+
+// The localnet tests run against an AlgoKit localnet (`algokit localnet start`), they deploy
+// a registry that mints NFD apps through an inner app create the way the NFD registry does &
+// follow its mints & updates through algod. The community sync test also needs a mysql
+// schema loaded from db.sql & is skipped unless LOCALNET_DB_DSN is set:
+//
+//	go test -tags localnet ./providers/nfd/ -run Localnet
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/algorand/go-algorand-sdk/v2/client/kmd"
+	"github.com/algorand/go-algorand-sdk/v2/client/v2/algod"
+	"github.com/algorand/go-algorand-sdk/v2/crypto"
+	"github.com/algorand/go-algorand-sdk/v2/transaction"
+	"github.com/algorand/go-algorand-sdk/v2/types"
+	"github.com/kylebeee/arc53-watcher-go/db"
+	streamer "github.com/kylebeee/arc53-watcher-go/internal/algod"
+	"github.com/kylebeee/arc53-watcher-go/misc"
+	"github.com/kylebeee/arc53-watcher-go/providers/community"
+)
+
+// localnetToken is the algod & kmd token of an AlgoKit localnet
+const localnetToken = "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+
+// registryTEAL mints an NFD when called with "mint", the NFD's approval & clear programs,
+// its project url & its packed caAlgo addresses
+const registryTEAL = `#pragma version 8
+txn ApplicationID
+bz done
+txna ApplicationArgs 0
+byte "mint"
+==
+assert
+itxn_begin
+int appl
+itxn_field TypeEnum
+txna ApplicationArgs 1
+itxn_field ApprovalProgram
+txna ApplicationArgs 2
+itxn_field ClearStateProgram
+int 4
+itxn_field GlobalNumByteSlice
+int 1
+itxn_field GlobalNumUint
+txna ApplicationArgs 3
+itxn_field ApplicationArgs
+txna ApplicationArgs 4
+itxn_field ApplicationArgs
+int 0
+itxn_field Fee
+itxn_submit
+done:
+int 1
+`
+
+// nfdTEAL points back at the registry that created it & keeps a project url & caAlgo
+// addresses, a call sets a new project url
+const nfdTEAL = `#pragma version 8
+txn ApplicationID
+bz create
+txn OnCompletion
+int NoOp
+==
+assert
+byte "u.project"
+txna ApplicationArgs 0
+app_global_put
+int 1
+return
+create:
+byte "i.registryID"
+global CallerApplicationID
+app_global_put
+byte "u.project"
+txna ApplicationArgs 0
+app_global_put
+byte "v.caAlgo.0.as"
+txna ApplicationArgs 1
+app_global_put
+int 1
+`
+
+const clearTEAL = `#pragma version 8
+int 1
+`
+
+func localnetEnv(key string, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// localnet is an algod client & a funded account of the localnet's default wallet
+type localnet struct {
+	address string
+	token   string
+	algod   *algod.Client
+	account crypto.Account
+}
+
+func newLocalnet(t *testing.T) *localnet {
+	t.Helper()
+
+	l := &localnet{
+		address: localnetEnv("LOCALNET_ALGOD_ADDRESS", "http://localhost:4001"),
+		token:   localnetEnv("LOCALNET_ALGOD_TOKEN", localnetToken),
+	}
+
+	var err error
+	l.algod, err = algod.MakeClient(l.address, l.token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = l.algod.Status().Do(context.Background())
+	if err != nil {
+		t.Fatalf("localnet algod at %s isn't up, start it with algokit localnet start: %v", l.address, err)
+	}
+
+	kmdClient, err := kmd.MakeClient(localnetEnv("LOCALNET_KMD_ADDRESS", "http://localhost:4002"), localnetEnv("LOCALNET_KMD_TOKEN", localnetToken))
+	if err != nil {
+		t.Fatal(err)
+	}
+	wallets, err := kmdClient.ListWallets()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the genesis accounts live in the default wallet, the richest one pays for the tests
+	var richest uint64
+	for _, wallet := range wallets.Wallets {
+		if wallet.Name != "unencrypted-default-wallet" {
+			continue
+		}
+
+		handle, err := kmdClient.InitWalletHandle(wallet.ID, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys, err := kmdClient.ListKeys(handle.WalletHandleToken)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, address := range keys.Addresses {
+			info, err := l.algod.AccountInformation(address).Do(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Amount <= richest {
+				continue
+			}
+
+			key, err := kmdClient.ExportKey(handle.WalletHandleToken, "", address)
+			if err != nil {
+				t.Fatal(err)
+			}
+			l.account, err = crypto.AccountFromPrivateKey(ed25519.PrivateKey(key.PrivateKey))
+			if err != nil {
+				t.Fatal(err)
+			}
+			richest = info.Amount
+		}
+	}
+	if richest == 0 {
+		t.Fatalf("no funded account in the localnet's default wallet")
+	}
+
+	return l
+}
+
+func (l *localnet) compile(t *testing.T, source string) []byte {
+	t.Helper()
+
+	compiled, err := l.algod.TealCompile([]byte(source)).Do(context.Background())
+	if err != nil {
+		t.Fatalf("compiling TEAL: %v", err)
+	}
+	program, err := base64.StdEncoding.DecodeString(compiled.Result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return program
+}
+
+func (l *localnet) params(t *testing.T, fee uint64) types.SuggestedParams {
+	t.Helper()
+
+	sp, err := l.algod.SuggestedParams().Do(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fee > 0 {
+		sp.FlatFee = true
+		sp.Fee = types.MicroAlgos(fee)
+	}
+	return sp
+}
+
+// send signs & sends a transaction, returning the round it was confirmed in & the apps it created
+func (l *localnet) send(t *testing.T, txn types.Transaction) (uint64, []uint64) {
+	t.Helper()
+
+	txid, signed, err := crypto.SignTransaction(l.account.PrivateKey, txn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = l.algod.SendRawTransaction(signed).Do(context.Background())
+	if err != nil {
+		t.Fatalf("sending %s: %v", txid, err)
+	}
+
+	info, err := transaction.WaitForConfirmation(l.algod, txid, 10, context.Background())
+	if err != nil {
+		t.Fatalf("waiting for %s: %v", txid, err)
+	}
+
+	created := []uint64{}
+	if info.ApplicationIndex != 0 {
+		created = append(created, info.ApplicationIndex)
+	}
+	for _, inner := range info.InnerTxns {
+		if inner.ApplicationIndex != 0 {
+			created = append(created, inner.ApplicationIndex)
+		}
+	}
+
+	return info.ConfirmedRound, created
+}
+
+// deployRegistry creates & funds a registry
+func (l *localnet) deployRegistry(t *testing.T) uint64 {
+	t.Helper()
+
+	txn, err := transaction.MakeApplicationCreateTx(false, l.compile(t, registryTEAL), l.compile(t, clearTEAL),
+		types.StateSchema{}, types.StateSchema{}, nil, nil, nil, nil,
+		l.params(t, 0), l.account.Address, nil, types.Digest{}, [32]byte{}, types.ZeroAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, created := l.send(t, txn)
+	registry := created[0]
+
+	// the registry pays the minimum balance of the NFDs it creates
+	txn, err = transaction.MakePaymentTxn(l.account.Address.String(), crypto.GetApplicationAddress(registry).String(), 10_000_000, nil, "", l.params(t, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.send(t, txn)
+
+	return registry
+}
+
+// mint mints an NFD through the registry, returning its app ID & the round it was minted in
+func (l *localnet) mint(t *testing.T, registry uint64, project string, addresses ...types.Address) (uint64, uint64) {
+	t.Helper()
+
+	packed := []byte{}
+	for _, address := range addresses {
+		packed = append(packed, address[:]...)
+	}
+
+	args := [][]byte{[]byte(mintArg), l.compile(t, nfdTEAL), l.compile(t, clearTEAL), []byte(project), packed}
+	txn, err := transaction.MakeApplicationNoOpTx(registry, args, nil, nil, nil,
+		l.params(t, 2000), l.account.Address, nil, types.Digest{}, [32]byte{}, types.ZeroAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	round, created := l.send(t, txn)
+	if len(created) != 1 {
+		t.Fatalf("mint created apps %v, want one NFD", created)
+	}
+	return created[0], round
+}
+
+// setProject calls an NFD to set a new project url, returning the round it was set in
+func (l *localnet) setProject(t *testing.T, appID uint64, project string) uint64 {
+	t.Helper()
+
+	txn, err := transaction.MakeApplicationNoOpTx(appID, [][]byte{[]byte(project)}, nil, nil, nil,
+		l.params(t, 0), l.account.Address, nil, types.Digest{}, [32]byte{}, types.ZeroAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	round, _ := l.send(t, txn)
+	return round
+}
+
+func (l *localnet) block(t *testing.T, round uint64) *types.Block {
+	t.Helper()
+
+	raw, err := l.algod.BlockRaw(round).Do(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := streamer.DecodeBlock(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return block
+}
+
+func payset(block *types.Block) []types.SignedTxnWithAD {
+	stxns := make([]types.SignedTxnWithAD, len(block.Payset))
+	for i := range block.Payset {
+		stxns[i] = block.Payset[i].SignedTxnWithAD
+	}
+	return stxns
+}
+
+func TestLocalnetMintDetection(t *testing.T) {
+	l := newLocalnet(t)
+	registry := l.deployRegistry(t)
+	other := l.deployRegistry(t)
+
+	appID, round := l.mint(t, registry, "ipfs://bafkreifzjut3te2nhyekklss27nh3k72ysco7y32koao5eei66wof36n5e", l.account.Address)
+
+	block := l.block(t, round)
+	minted := []uint64{}
+	for _, stxn := range payset(block) {
+		minted = append(minted, MintedAppIDs(stxn, registry)...)
+	}
+	if !misc.SliceEqual(minted, []uint64{appID}) {
+		t.Fatalf("MintedAppIDs = %v, want [%d]", minted, appID)
+	}
+
+	tests := []struct {
+		name     string
+		registry uint64
+		appID    uint64
+		want     bool
+	}{
+		{name: "NFD of the registry", registry: registry, appID: appID, want: true},
+		{name: "NFD of another registry", registry: other, appID: appID},
+		{name: "the registry itself", registry: registry, appID: registry},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := IsNFDApp(l.algod, context.Background(), tt.registry, tt.appID)
+			if err != nil {
+				t.Fatalf("IsNFDApp: %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("IsNFDApp = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLocalnetStreamStopsAtLastRound(t *testing.T) {
+	l := newLocalnet(t)
+	registry := l.deployRegistry(t)
+	appID, round := l.mint(t, registry, "ipfs://bafkreifzjut3te2nhyekklss27nh3k72ysco7y32koao5eei66wof36n5e", l.account.Address)
+
+	// localnet only makes rounds when there are transactions, the standby would wait forever
+	nodes := []*streamer.AlgoNodeConfig{
+		{Id: "localnet", Address: l.address, Token: l.token},
+		{Id: "localnet-standby", Address: l.address, Token: l.token},
+	}
+	s := streamer.NewStreamer(&streamer.AlgoConfig{FRound: int64(round), LRound: int64(round), ANodes: nodes})
+	blocks, status, err := s.Stream(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+
+	go func() {
+		for range status {
+		}
+	}()
+
+	minted := []uint64{}
+	timeout := time.After(30 * time.Second)
+	for done := false; !done; {
+		select {
+		case b, ok := <-blocks:
+			if !ok {
+				done = true
+				break
+			}
+			for _, stxn := range payset(b.Block) {
+				minted = append(minted, MintedAppIDs(stxn, registry)...)
+			}
+		case <-timeout:
+			t.Fatalf("stream still open 30s after round %d", round)
+		}
+	}
+
+	if !misc.SliceEqual(minted, []uint64{appID}) {
+		t.Fatalf("streamed mints %v, want [%d]", minted, appID)
+	}
+}
+
+func TestLocalnetNFDState(t *testing.T) {
+	l := newLocalnet(t)
+	registry := l.deployRegistry(t)
+
+	owner := crypto.GenerateAccount().Address
+	project := "ipfs://bafkreifzjut3te2nhyekklss27nh3k72ysco7y32koao5eei66wof36n5e"
+	appID, round := l.mint(t, registry, project, l.account.Address, owner)
+
+	state, err := fetchNFDState(l.algod, context.Background(), appID, round)
+	if err != nil {
+		t.Fatalf("fetchNFDState: %v", err)
+	}
+
+	got := NFDState(state.properties(appID), round)
+	if got.Metadata == nil || *got.Metadata != project {
+		t.Fatalf("metadata = %v, want %s", got.Metadata, project)
+	}
+	want := []string{l.account.Address.String(), owner.String()}
+	if !misc.SliceEqual(got.Addresses, want) {
+		t.Fatalf("addresses = %v, want %v", got.Addresses, want)
+	}
+
+	// the update's delta applied to the fetched state matches the state algod has after it
+	updated := "ipfs://QmT78zSuBmuS4z925WZfrqQ1qHaJ56DQaTfyMUF7F8ff5o"
+	round = l.setProject(t, appID, updated)
+
+	calls := collectAppCalls(payset(l.block(t, round)))
+	if calls[appID] == nil {
+		t.Fatalf("no calls to %d found in round %d", appID, round)
+	}
+	for _, txn := range calls[appID].txns {
+		if !state.applyDelta(txn.EvalDelta.GlobalDelta) {
+			t.Fatalf("applyDelta failed on %v", txn.EvalDelta.GlobalDelta)
+		}
+	}
+
+	fetched, err := fetchNFDState(l.algod, context.Background(), appID, round)
+	if err != nil {
+		t.Fatalf("fetchNFDState: %v", err)
+	}
+
+	applied := NFDState(state.properties(appID), round)
+	refetched := NFDState(fetched.properties(appID), round)
+	if applied.Metadata == nil || *applied.Metadata != updated {
+		t.Fatalf("metadata after the delta = %v, want %s", applied.Metadata, updated)
+	}
+	if *applied.Metadata != *refetched.Metadata || !misc.SliceEqual(applied.Addresses, refetched.Addresses) {
+		t.Fatalf("state after the delta %+v, refetched %+v", applied, refetched)
+	}
+}
+
+// stubFetcher serves the same metadata for every url & keeps the urls it was asked for
+type stubFetcher struct {
+	lock sync.Mutex
+	urls []string
+}
+
+func (f *stubFetcher) Fetch(ctx context.Context, url string) ([]byte, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.urls = append(f.urls, url)
+	return []byte(`{"version":"0.0.1"}`), nil
+}
+
+func TestLocalnetCommunitySync(t *testing.T) {
+	dsn := os.Getenv("LOCALNET_DB_DSN")
+	if dsn == "" {
+		t.Skip("LOCALNET_DB_DSN isn't set")
+	}
+
+	l := newLocalnet(t)
+	registry := l.deployRegistry(t)
+
+	conn, err := db.Open(dsn, localnetEnv("LOCALNET_DB_DATABASE", "arc53_localnet"), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	fetcher := &stubFetcher{}
+	p := &NFDProvider{Fetcher: fetcher, settings: Settings{RegistryAppID: registry}}
+	err = p.Init("localnet", conn, l.algod)
+	if err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+
+	process := func(round uint64) {
+		t.Helper()
+
+		btx, err := community.BeginBlock(conn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = p.ProcessBlockBatch(btx, l.block(t, round), round)
+		if err != nil {
+			btx.Rollback()
+			t.Fatalf("ProcessBlockBatch(%d): %v", round, err)
+		}
+		err = btx.Commit()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	project := "ipfs://bafkreifzjut3te2nhyekklss27nh3k72ysco7y32koao5eei66wof36n5e"
+	appID, round := l.mint(t, registry, project, l.account.Address)
+	process(round)
+
+	c, err := db.GetCommunity(conn, appID)
+	if err != nil {
+		t.Fatalf("GetCommunity(%d): %v", appID, err)
+	}
+	if c.Version != "0.0.1" {
+		t.Fatalf("community version %q, want 0.0.1", c.Version)
+	}
+
+	addresses, err := db.GetProviderAddresses(conn, appID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(*addresses) != 1 || (*addresses)[0].Address != l.account.Address.String() || (*addresses)[0].Verified == nil || !*(*addresses)[0].Verified {
+		t.Fatalf("provider addresses %+v, want %s verified", *addresses, l.account.Address)
+	}
+
+	// a call to the NFD syncs it from the block's delta & fetches the new url
+	updated := "ipfs://QmT78zSuBmuS4z925WZfrqQ1qHaJ56DQaTfyMUF7F8ff5o"
+	process(l.setProject(t, appID, updated))
+
+	if !misc.SliceEqual(fetcher.urls, []string{project, updated}) {
+		t.Fatalf("fetched %v, want %s then %s", fetcher.urls, project, updated)
+	}
+}
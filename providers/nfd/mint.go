@@ -0,0 +1,89 @@
+package nfd
+
+import (
+	"github.com/algorand/go-algorand-sdk/v2/client/v2/common/models"
+	"github.com/algorand/go-algorand-sdk/v2/crypto"
+	"github.com/algorand/go-algorand-sdk/v2/types"
+	"github.com/kylebeee/arc53-watcher-go/misc"
+)
+
+// mintArg is the first application arg of a V1 registry mint
+const mintArg = "mint"
+
+// v1MintedAppIndex is the inner txn of a V1 mint that calls the freshly created NFD
+const v1MintedAppIndex = 1
+
+// MintedAppIDs returns the NFD app IDs minted by a transaction & its inner transactions.
+// A mint is a call to the registry that creates an app from the registry's address,
+// which covers both V2 ABI mints & V1 "mint" calls, V1 mints that don't show the
+// creation fall back to the NFD called by the second inner txn
+func MintedAppIDs(stxn types.SignedTxnWithAD, registryAppID uint64) []uint64 {
+	registryAddress := crypto.GetApplicationAddress(registryAppID)
+
+	minted := []uint64{}
+	txns := append([]types.SignedTxnWithAD{stxn}, misc.ListInner(&stxn)...)
+	for i := range txns {
+		txn := txns[i].Txn
+		if txn.Type != types.ApplicationCallTx || uint64(txn.ApplicationID) != registryAppID {
+			continue
+		}
+
+		created := []uint64{}
+		inner := txns[i].EvalDelta.InnerTxns
+		for j := range inner {
+			itxn := inner[j].Txn
+			if itxn.Type == types.ApplicationCallTx && itxn.ApplicationID == 0 && itxn.Sender == registryAddress && inner[j].ApplicationID != 0 {
+				created = append(created, uint64(inner[j].ApplicationID))
+			}
+		}
+
+		if len(created) == 0 && isV1Mint(txn.ApplicationArgs) && len(inner) > v1MintedAppIndex {
+			appID := uint64(inner[v1MintedAppIndex].Txn.ApplicationID)
+			if appID != 0 {
+				created = append(created, appID)
+			}
+		}
+
+		minted = append(minted, created...)
+	}
+
+	return misc.UniqueSlice(minted)
+}
+
+// MintedAppIDsFromIndexer is MintedAppIDs for transactions returned by the indexer
+func MintedAppIDsFromIndexer(txn models.Transaction, registryAppID uint64) []uint64 {
+	registryAddress := crypto.GetApplicationAddress(registryAppID).String()
+
+	minted := []uint64{}
+	var walk func(txn models.Transaction)
+	walk = func(txn models.Transaction) {
+		if txn.Type == string(types.ApplicationCallTx) && txn.ApplicationTransaction.ApplicationId == registryAppID {
+			created := []uint64{}
+			for _, itxn := range txn.InnerTxns {
+				if itxn.Type == string(types.ApplicationCallTx) && itxn.Sender == registryAddress && itxn.CreatedApplicationIndex != 0 {
+					created = append(created, itxn.CreatedApplicationIndex)
+				}
+			}
+
+			if len(created) == 0 && isV1Mint(txn.ApplicationTransaction.ApplicationArgs) && len(txn.InnerTxns) > v1MintedAppIndex {
+				appID := txn.InnerTxns[v1MintedAppIndex].ApplicationTransaction.ApplicationId
+				if appID != 0 {
+					created = append(created, appID)
+				}
+			}
+
+			minted = append(minted, created...)
+		}
+
+		for _, itxn := range txn.InnerTxns {
+			walk(itxn)
+		}
+	}
+	walk(txn)
+
+	return misc.UniqueSlice(minted)
+}
+
+func isV1Mint(args [][]byte) bool {
+	return len(args) > 0 && string(args[0]) == mintArg
+}
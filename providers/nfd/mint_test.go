@@ -0,0 +1,93 @@
+package nfd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/algorand/go-algorand-sdk/v2/client/v2/common/models"
+	"github.com/kylebeee/arc53-watcher-go/internal/algod"
+	"github.com/kylebeee/arc53-watcher-go/misc"
+)
+
+// the fixtures in testdata are raw msgpack block responses laid out like mainnet & testnet registry
+// calls, V1 fallback fixtures leave the created app out of the apply data the way early V1 mints did.
+// they're synthetic with made up rounds & app IDs until they're recaptured from the networks by
+// capture_test.go, the app IDs below have to follow the captured fixtures when they are
+
+func TestMintedAppIDs(t *testing.T) {
+	tests := []struct {
+		name     string
+		fixture  string
+		registry uint64
+		want     []uint64
+	}{
+		{name: "V2 mint on mainnet", fixture: "mint_v2_mainnet.msgp", registry: NFDMainNetRegistryAppID, want: []uint64{2289512341}},
+		{name: "V2 mint on testnet", fixture: "mint_v2_testnet.msgp", registry: NFDTestNetRegistryAppID, want: []uint64{719203551}},
+		{name: "V2 mint through another app", fixture: "mint_v2_nested_mainnet.msgp", registry: NFDMainNetRegistryAppID, want: []uint64{2289514402}},
+		{name: "V1 mint showing the created app", fixture: "mint_v1_mainnet.msgp", registry: NFDMainNetRegistryAppID, want: []uint64{769441702}},
+		{name: "V1 mint falling back to inner[1] on mainnet", fixture: "mint_v1_fallback_mainnet.msgp", registry: NFDMainNetRegistryAppID, want: []uint64{763325551}},
+		{name: "V1 mint falling back to inner[1] on testnet", fixture: "mint_v1_fallback_testnet.msgp", registry: NFDTestNetRegistryAppID, want: []uint64{84400921}},
+		{name: "registry call that isn't a mint", fixture: "registry_call_mainnet.msgp", registry: NFDMainNetRegistryAppID, want: []uint64{}},
+		{name: "mainnet mint against the testnet registry", fixture: "mint_v2_mainnet.msgp", registry: NFDTestNetRegistryAppID, want: []uint64{}},
+		{name: "testnet V1 mint against the mainnet registry", fixture: "mint_v1_fallback_testnet.msgp", registry: NFDMainNetRegistryAppID, want: []uint64{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw, err := os.ReadFile(filepath.Join("testdata", tt.fixture))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			block, err := algod.DecodeBlock(raw)
+			if err != nil {
+				t.Fatalf("DecodeBlock: %v", err)
+			}
+
+			got := []uint64{}
+			for i := range block.Payset {
+				got = append(got, MintedAppIDs(block.Payset[i].SignedTxnWithAD, tt.registry)...)
+			}
+
+			if !misc.SliceEqual(got, tt.want) {
+				t.Fatalf("MintedAppIDs = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMintedAppIDsFromIndexer(t *testing.T) {
+	tests := []struct {
+		name     string
+		fixture  string
+		registry uint64
+		want     []uint64
+	}{
+		{name: "V2 mint on mainnet", fixture: "indexer_mint_v2_mainnet.json", registry: NFDMainNetRegistryAppID, want: []uint64{2289512341}},
+		{name: "V1 mint falling back to inner[1] on testnet", fixture: "indexer_mint_v1_fallback_testnet.json", registry: NFDTestNetRegistryAppID, want: []uint64{84400921}},
+		{name: "registry call that isn't a mint", fixture: "indexer_registry_call_mainnet.json", registry: NFDMainNetRegistryAppID, want: []uint64{}},
+		{name: "mainnet mint against the testnet registry", fixture: "indexer_mint_v2_mainnet.json", registry: NFDTestNetRegistryAppID, want: []uint64{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw, err := os.ReadFile(filepath.Join("testdata", tt.fixture))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var txn models.Transaction
+			err = json.Unmarshal(raw, &txn)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := MintedAppIDsFromIndexer(txn, tt.registry)
+			if !misc.SliceEqual(got, tt.want) {
+				t.Fatalf("MintedAppIDsFromIndexer = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"github.com/algorand/go-algorand-sdk/v2/crypto"
 	"github.com/algorand/go-algorand-sdk/v2/types"
 	"github.com/kylebeee/arc53-watcher-go/errors"
+	"github.com/kylebeee/arc53-watcher-go/misc"
 	"github.com/mailgun/holster/v4/syncutil"
 )
 
@@ -33,21 +34,12 @@ type NFDProperties struct {
 func GetNFDData(algoClient *algod.Client, ctx context.Context, appID uint64) (*NFDProperties, error) {
 	const op errors.Op = "GetNFDData"
 
-	appData, err := algoClient.GetApplicationByID(appID).Do(ctx)
-	if err != nil {
-		return nil, errors.E(op, err)
-	}
-
-	// Now load all the box data (V2) in parallel
-	boxData, err := GetApplicationBoxes(algoClient, ctx, appID)
+	state, err := fetchNFDState(algoClient, ctx, appID, 0)
 	if err != nil {
 		return nil, errors.E(op, err)
 	}
 
-	properties := FetchAllStateAsNFDProperties(appData.Params.GlobalState, boxData)
-	properties.AppID = appID
-	properties.UserDefined = MergeNFDProperties(properties.UserDefined)
-	return &properties, nil
+	return state.properties(appID), nil
 }
 
 func PrintNFD(properties *NFDProperties) {
@@ -261,6 +253,54 @@ func FetchBToIFromState(appState []models.TealKeyValue, key string) (uint64, boo
 	return 0, false
 }
 
+// fetchUint64FromState fetches a specific key from application state stored either as a uint
+// or as a big-endian 64-bit byte value
+func fetchUint64FromState(appState []models.TealKeyValue, key string) (uint64, bool) {
+	for _, kv := range appState {
+		decodedKey, _ := base64.StdEncoding.DecodeString(kv.Key)
+		if string(decodedKey) != key {
+			continue
+		}
+
+		switch kv.Value.Type {
+		case 1: // bytes
+			value, _ := base64.StdEncoding.DecodeString(kv.Value.Bytes)
+			if len(value) != 8 {
+				return 0, false
+			}
+			return binary.BigEndian.Uint64(value), true
+		case 2: // uint
+			return kv.Value.Uint, true
+		}
+		return 0, false
+	}
+	return 0, false
+}
+
+// IsNFDApp checks on chain that an app is an NFD of the given registry, the app has to be
+// created by the registry's application address & point back at it through i.registryID
+func IsNFDApp(algoClient *algod.Client, ctx context.Context, registryAppID uint64, appID uint64) (bool, error) {
+	const op errors.Op = "IsNFDApp"
+
+	appData, err := algoClient.GetApplicationByID(appID).Do(ctx)
+	if err != nil && misc.IsAlgodNotFound(err) {
+		return false, nil
+	} else if err != nil {
+		return false, errors.E(op, errors.Network, err)
+	}
+
+	return isRegistryApp(appData.Params.Creator, appData.Params.GlobalState, registryAppID), nil
+}
+
+func isRegistryApp(creator string, globalState []models.TealKeyValue, registryAppID uint64) bool {
+	if creator != crypto.GetApplicationAddress(registryAppID).String() {
+		return false
+	}
+
+	registryID, ok := fetchUint64FromState(globalState, "i.registryID")
+	return ok && registryID == registryAppID
+}
+
 // FetchUint64sFromState fetches a specific key from application state - stored as set of 64-bit values (up to 15) // Returns array of values, and optional error
 func FetchUint64sFromState(appState []models.TealKeyValue, key string) ([]uint64, error) {
 	for _, kv := range appState {
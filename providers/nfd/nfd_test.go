@@ -0,0 +1,197 @@
+package nfd
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/algorand/go-algorand-sdk/v2/client/v2/algod"
+	"github.com/algorand/go-algorand-sdk/v2/client/v2/common/models"
+	"github.com/algorand/go-algorand-sdk/v2/crypto"
+)
+
+func uintState(key string, value uint64) models.TealKeyValue {
+	return models.TealKeyValue{
+		Key:   base64.StdEncoding.EncodeToString([]byte(key)),
+		Value: models.TealValue{Type: 2, Uint: value},
+	}
+}
+
+func bytesState(key string, value []byte) models.TealKeyValue {
+	return models.TealKeyValue{
+		Key:   base64.StdEncoding.EncodeToString([]byte(key)),
+		Value: models.TealValue{Type: 1, Bytes: base64.StdEncoding.EncodeToString(value)},
+	}
+}
+
+func packed(value uint64) []byte {
+	return binary.BigEndian.AppendUint64(nil, value)
+}
+
+// fakeAlgod serves the apps it's given from /v2/applications/:id & a 404 for any other app
+type fakeAlgod struct {
+	*httptest.Server
+	apps     map[uint64]models.Application
+	requests atomic.Int64
+	// fail makes every request fail with a 500
+	fail bool
+}
+
+func newFakeAlgod(t *testing.T, apps ...models.Application) *fakeAlgod {
+	t.Helper()
+
+	f := &fakeAlgod{apps: map[uint64]models.Application{}}
+	for _, app := range apps {
+		f.apps[app.Id] = app
+	}
+
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.requests.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+
+		if f.fail {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"message":"node is down"}`))
+			return
+		}
+
+		id, err := strconv.ParseUint(strings.TrimPrefix(r.URL.Path, "/v2/applications/"), 10, 64)
+		app, ok := f.apps[id]
+		if err != nil || !ok {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"message":"application does not exist"}`))
+			return
+		}
+
+		json.NewEncoder(w).Encode(app)
+	}))
+	t.Cleanup(f.Close)
+
+	return f
+}
+
+func (f *fakeAlgod) client(t *testing.T) *algod.Client {
+	t.Helper()
+
+	client, err := algod.MakeClient(f.URL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+// nfdApp is an app created by a registry's address that points back at registryID
+func nfdApp(id uint64, registry uint64, state ...models.TealKeyValue) models.Application {
+	return models.Application{
+		Id: id,
+		Params: models.ApplicationParams{
+			Creator:     crypto.GetApplicationAddress(registry).String(),
+			GlobalState: state,
+		},
+	}
+}
+
+func TestIsRegistryApp(t *testing.T) {
+	registryAddress := crypto.GetApplicationAddress(NFDMainNetRegistryAppID).String()
+
+	tests := []struct {
+		name    string
+		creator string
+		state   []models.TealKeyValue
+		want    bool
+	}{
+		{name: "registry id as a uint", creator: registryAddress, state: []models.TealKeyValue{uintState("i.registryID", NFDMainNetRegistryAppID)}, want: true},
+		{name: "registry id as packed bytes", creator: registryAddress, state: []models.TealKeyValue{bytesState("i.registryID", packed(NFDMainNetRegistryAppID))}, want: true},
+		{name: "another registry id", creator: registryAddress, state: []models.TealKeyValue{uintState("i.registryID", NFDTestNetRegistryAppID)}},
+		{name: "no registry id", creator: registryAddress, state: []models.TealKeyValue{uintState("i.appID", 1)}},
+		{name: "registry id of the wrong length", creator: registryAddress, state: []models.TealKeyValue{bytesState("i.registryID", []byte{1, 2, 3})}},
+		{name: "created by someone else", creator: crypto.GetApplicationAddress(NFDTestNetRegistryAppID).String(), state: []models.TealKeyValue{uintState("i.registryID", NFDMainNetRegistryAppID)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := isRegistryApp(tt.creator, tt.state, NFDMainNetRegistryAppID)
+			if got != tt.want {
+				t.Fatalf("isRegistryApp = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsNFDApp(t *testing.T) {
+	algodServer := newFakeAlgod(t,
+		nfdApp(1001, NFDMainNetRegistryAppID, uintState("i.registryID", NFDMainNetRegistryAppID)),
+		nfdApp(1002, NFDTestNetRegistryAppID, uintState("i.registryID", NFDTestNetRegistryAppID)),
+		// an app that claims the registry but wasn't created by it
+		models.Application{Id: 1003, Params: models.ApplicationParams{
+			Creator:     "AEBAGBAFAYDQQCIKBMGA2DQPCAIREEYUCULBOGAZDINRYHI6D4QDTYK3BA",
+			GlobalState: []models.TealKeyValue{uintState("i.registryID", NFDMainNetRegistryAppID)},
+		}},
+	)
+	client := algodServer.client(t)
+
+	tests := []struct {
+		name  string
+		appID uint64
+		want  bool
+	}{
+		{name: "NFD of the registry", appID: 1001, want: true},
+		{name: "NFD of another registry", appID: 1002},
+		{name: "app pretending to be an NFD", appID: 1003},
+		{name: "unknown app", appID: 1004},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := IsNFDApp(client, context.Background(), NFDMainNetRegistryAppID, tt.appID)
+			if err != nil {
+				t.Fatalf("IsNFDApp: %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("IsNFDApp = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("algod failing is an error", func(t *testing.T) {
+		down := newFakeAlgod(t)
+		down.fail = true
+
+		_, err := IsNFDApp(down.client(t), context.Background(), NFDMainNetRegistryAppID, 1001)
+		if err == nil {
+			t.Fatalf("IsNFDApp succeeded against a failing algod")
+		}
+	})
+}
+
+func TestIsProviderAppCachesNFDs(t *testing.T) {
+	algodServer := newFakeAlgod(t, nfdApp(1001, NFDMainNetRegistryAppID, uintState("i.registryID", NFDMainNetRegistryAppID)))
+	p := &NFDProvider{network: "mainnet", Algod: algodServer.client(t), SyncMap: &sync.Map{}}
+
+	for i := 0; i < 3; i++ {
+		if !p.IsProviderApp(1001) {
+			t.Fatalf("IsProviderApp(1001) = false, want true")
+		}
+	}
+	if n := algodServer.requests.Load(); n != 1 {
+		t.Fatalf("algod got %d requests, want the NFD cached after the first check", n)
+	}
+
+	// apps that aren't NFDs aren't cached so a later check sees them on chain again
+	for i := 0; i < 2; i++ {
+		if p.IsProviderApp(1004) {
+			t.Fatalf("IsProviderApp(1004) = true, want false")
+		}
+	}
+	if n := algodServer.requests.Load(); n != 3 {
+		t.Fatalf("algod got %d requests, want every check of an app that isn't an NFD to go to algod", n)
+	}
+}
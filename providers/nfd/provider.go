@@ -4,10 +4,10 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
-	"net/http"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/algorand/go-algorand-sdk/v2/client/v2/algod"
@@ -15,21 +15,72 @@ import (
 	"github.com/algorand/go-algorand-sdk/v2/client/v2/indexer"
 	"github.com/algorand/go-algorand-sdk/v2/types"
 	"github.com/jmoiron/sqlx"
+	"github.com/kylebeee/arc53-watcher-go/arc53"
 	"github.com/kylebeee/arc53-watcher-go/db"
-	"github.com/kylebeee/arc53-watcher-go/db/compound"
 	"github.com/kylebeee/arc53-watcher-go/errors"
 	"github.com/kylebeee/arc53-watcher-go/misc"
-	"github.com/kylebeee/arc53-watcher-go/uuid"
+	"github.com/kylebeee/arc53-watcher-go/providers"
+	"github.com/kylebeee/arc53-watcher-go/providers/community"
 )
 
 const NFDMainNetRegistryAppID uint64 = 760937186
 const NFDTestNetRegistryAppID uint64 = 84366825
 
+// registryAppIDs are the NFD registries of the public networks, any other
+// network (ie. a localnet) needs registry_app_id set
+var registryAppIDs = map[string]uint64{
+	"mainnet": NFDMainNetRegistryAppID,
+	"testnet": NFDTestNetRegistryAppID,
+}
+
+const defaultSyncIntervalMs int64 = 300
+const defaultCatchupPageSize uint64 = 1000
+const maxSearchAttempts = 4
+
+// Settings are the nfd provider's settings in the providers section of the config
+type Settings struct {
+	// RegistryAppID overrides the NFD registry of the network, it's required on networks other than mainnet & testnet
+	RegistryAppID uint64 `json:"registry_app_id"`
+	// SyncIntervalMs is the minimum time between starting NFD syncs during catch up
+	SyncIntervalMs int64 `json:"sync_interval_ms"`
+	// CatchupWorkers is how many NFDs are synced at once during catch up
+	CatchupWorkers int `json:"catchup_workers"`
+	// CatchupPageSize is how many registry transactions are fetched per indexer page
+	CatchupPageSize uint64 `json:"catchup_page_size"`
+	// Fetcher configures how community metadata is fetched
+	Fetcher arc53.FetcherConfig `json:"fetcher"`
+}
+
+func init() {
+	providers.Register("nfd", func(raw json.RawMessage) (providers.ProviderType, error) {
+		var settings Settings
+		if len(raw) > 0 {
+			err := json.Unmarshal(raw, &settings)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		return &NFDProvider{
+			Fetcher:  arc53.NewGatewayFetcher(settings.Fetcher),
+			settings: settings,
+		}, nil
+	})
+}
+
 type NFDProvider struct {
 	network string
 	DB      *sqlx.DB
 	Algod   *algod.Client
 	SyncMap *sync.Map
+	// Fetcher retrieves community metadata, gateway defaults are used when nil
+	Fetcher  arc53.MetadataFetcher
+	syncer   *community.Syncer
+	settings Settings
+	states   *stateCache
+	// lastRound is the last round algod is known to have reached, from the block stream's
+	// node status & the rounds synced at, so refetched state doesn't need a status call
+	lastRound atomic.Uint64
 }
 
 func (p *NFDProvider) Type() string {
@@ -40,9 +91,17 @@ func (p *NFDProvider) Init(network string, dbConn *sqlx.DB, algodClient *algod.C
 	const op errors.Op = "NFDProvider.Init"
 
 	p.network = network
+	if p.registryAppID() == 0 {
+		return errors.E(op, fmt.Errorf("no NFD registry known for network %s, set registry_app_id", network))
+	}
+
 	p.DB = dbConn
 	p.Algod = algodClient
 	p.SyncMap = &sync.Map{}
+	p.states = newStateCache()
+
+	p.syncer = community.NewSyncer(dbConn, p.Fetcher)
+	p.Fetcher = p.syncer.Fetcher
 
 	appIDs, err := db.GetAllProvidersByType(p.DB, "nfd")
 	if err != nil {
@@ -61,146 +120,97 @@ func (p *NFDProvider) Init(network string, dbConn *sqlx.DB, algodClient *algod.C
 	return nil
 }
 
+// CatchUp walks the registry's transactions since startingRound a page at a time & syncs
+// every NFD minted in them through a worker pool, a checkpoint is kept after each page
+// so an interrupted catch up resumes from the page it was on
 func (p *NFDProvider) CatchUp(dbConn *sqlx.DB, algodClient *algod.Client, startingRound uint64, indexerClient *indexer.Client) error {
 	const op errors.Op = "NFDProvider.CatchUp"
-	var err error
+	ctx := context.Background()
 
-	// fetch all NFD accounts via walking registry app transactions
-	nextToken := ""
-	loop := true
-	transactions := []models.Transaction{}
-
-	registry := NFDTestNetRegistryAppID
-	if p.network == "mainnet" {
-		registry = NFDMainNetRegistryAppID
+	registry := p.registryAppID()
+	pool := providers.NewSyncPool(p.settings.CatchupWorkers, p.syncInterval())
+	syncApp := func(round uint64) func(uint64) error {
+		return func(appID uint64) error {
+			p.SyncMap.Store(appID, struct{}{})
+			_, err := p.SyncNFDByAppID(appID, round)
+			return err
+		}
 	}
 
-	for loop {
-		loop = false
-		nextPage := nextToken
-		resp, err := indexerClient.SearchForTransactions().ApplicationId(registry).MinRound(startingRound).NextToken(nextPage).Do(context.Background())
+	checkpoint, err := db.GetCatchupCheckpoint(p.DB, p.Type())
+	if err != nil && !db.ErrNoRows(err) {
+		return errors.E(op, err)
+	} else if db.ErrNoRows(err) {
+		status, err := algodClient.Status().Do(ctx)
 		if err != nil {
 			return errors.E(op, err)
 		}
 
-		transactions = append(transactions, resp.Transactions...)
-
-		fmt.Printf("\r[GATHERING REGISTRY TXNS]: %v", len(transactions))
+		checkpoint = &db.CatchupCheckpoint{
+			ProviderType: p.Type(),
+			MinRound:     startingRound,
+			Round:        status.LastRound,
+		}
 
-		if resp.NextToken != "" {
-			loop = true
-			nextToken = resp.NextToken
+		err = db.SetCatchupCheckpoint(p.DB, checkpoint)
+		if err != nil {
+			return errors.E(op, err)
 		}
+	} else {
+		fmt.Printf("[CATCHUP] [NFD] resuming from round %v\n", checkpoint.MinRound)
 	}
 
-	fmt.Println()
-
-	status, err := algodClient.Status().Do(context.Background())
-	if err != nil {
+	// retry the NFDs that failed during earlier catch ups first
+	failures, err := db.GetSyncFailures(p.DB, p.Type())
+	if err != nil && !db.ErrNoRows(err) {
 		return errors.E(op, err)
-	}
-
-	syncQueue := []uint64{}
-
-	for _, transaction := range transactions {
-
-		if len(transaction.ApplicationTransaction.ApplicationArgs) > 0 {
-			arg := string(transaction.ApplicationTransaction.ApplicationArgs[0])
+	} else if failures != nil && len(*failures) > 0 {
+		retry := []uint64{}
+		for _, failure := range *failures {
+			retry = append(retry, failure.ID)
+		}
 
-			if arg == "mint" && len(transaction.InnerTxns) > 1 && transaction.InnerTxns[1].ApplicationTransaction.ApplicationId != 0 {
-				syncQueue = append(syncQueue, transaction.InnerTxns[1].ApplicationTransaction.ApplicationId)
-				fmt.Printf("\r[SYNC QUEUE]: %v", len(syncQueue))
-			}
+		synced, err := pool.RunRecorded(ctx, p.DB, p.Type(), retry, syncApp(checkpoint.Round))
+		if err != nil {
+			return errors.E(op, err)
 		}
+		fmt.Printf("[CATCHUP] [NFD] [RETRIED]: %v [SYNCED]: %v\n", len(retry), synced)
 	}
 
-	fmt.Println()
-
-	ticker := time.NewTicker(300 * time.Millisecond)
+	pages := 0
 	syncCount := 0
-	for _, appID := range misc.UniqueSlice(syncQueue) {
-		<-ticker.C
-		fmt.Printf("\r[SYNCING NFD]: %v [COUNT]: %v", appID, syncCount)
-		err = p.SyncNFDByAppID(appID, status.LastRound)
+	for {
+		resp, err := p.searchRegistry(ctx, indexerClient, registry, checkpoint)
 		if err != nil {
-			fmt.Println("[CATCHUP] [NFD] [ERROR]: ", err)
 			return errors.E(op, err)
 		}
-		syncCount++
-	}
+		pages++
 
-	fmt.Println()
-
-	return nil
-}
-
-func (p *NFDProvider) ProcessBlock(stxn types.SignedTxnInBlock, round uint64) error {
-	const op errors.Op = "NFDProvider.ProcessBlock"
-
-	txnsToProcess := append([]types.SignedTxnWithAD{stxn.SignedTxnWithAD}, misc.ListInner(&stxn.SignedTxnWithAD)...)
-
-	for i := range txnsToProcess {
-		txn := txnsToProcess[i].Txn
-		txAppID := uint64(txn.ApplicationFields.ApplicationID)
-
-		_, exists := p.SyncMap.Load(txAppID)
-		if exists {
-			// update on existing NFD
-			err := p.SyncNFDByAppID(uint64(txn.ApplicationFields.ApplicationID), round)
-			if err != nil {
-				return errors.E(op, err)
-			}
-			return nil
+		minted := []uint64{}
+		for _, transaction := range resp.Transactions {
+			minted = append(minted, MintedAppIDsFromIndexer(transaction, registry)...)
 		}
 
-		isMint := txn.Type == types.ApplicationCallTx && txAppID == NFDMainNetRegistryAppID && len(txn.ApplicationFields.ApplicationArgs) > 0 && string(txn.ApplicationFields.ApplicationArgs[0]) == "mint"
-		if isMint {
-
-			var appID uint64
-
-			if len(stxn.EvalDelta.InnerTxns) > 1 {
-				appID = uint64(stxn.EvalDelta.InnerTxns[1].Txn.ApplicationID)
-				break
-			}
-
-			// new NFD
-			p.SyncMap.Store(appID, struct{}{})
-
-			_, err := db.Insert(p.DB, &db.Provider{ID: appID, Type: "nfd", Round: round})
-			if err != nil {
-				return errors.E(op, err)
-			}
-
-			err = p.SyncNFDByAppID(appID, round)
-			if err != nil {
-				return err
-			}
+		synced, err := pool.RunRecorded(ctx, p.DB, p.Type(), misc.UniqueSlice(minted), syncApp(checkpoint.Round))
+		if err != nil {
+			return errors.E(op, err)
 		}
-	}
-
-	return nil
-}
+		syncCount += synced
 
-func (p *NFDProvider) Process(appID uint64) error {
-	const op errors.Op = "NFDProvider.Process"
-	var err error
+		fmt.Printf("[CATCHUP] [NFD] [PAGE]: %v [SYNCED]: %v\n", pages, syncCount)
 
-	if !p.IsProviderApp(appID) {
-		return errors.E(op, fmt.Errorf("appID is not an NFD"))
-	}
-
-	// get current block
-	status, err := p.Algod.Status().Do(context.Background())
-	if err != nil {
-		return errors.E(op, err)
-	}
+		if resp.NextToken == "" {
+			break
+		}
 
-	_, err = db.Insert(p.DB, &db.Provider{ID: appID, Type: "nfd", Round: status.LastRound})
-	if err != nil {
-		return errors.E(op, err)
+		checkpoint.NextToken = resp.NextToken
+		err = db.SetCatchupCheckpoint(p.DB, checkpoint)
+		if err != nil {
+			return errors.E(op, err)
+		}
 	}
 
-	err = p.SyncNFDByAppID(appID, status.LastRound)
+	err = db.DeleteCatchupCheckpoint(p.DB, p.Type())
 	if err != nil {
 		return errors.E(op, err)
 	}
@@ -208,831 +218,296 @@ func (p *NFDProvider) Process(appID uint64) error {
 	return nil
 }
 
-func (p *NFDProvider) IsProviderApp(appID uint64) bool {
-	_, exists := p.SyncMap.Load(appID)
-	if exists {
-		return true
-	}
-
-	// check if its an NFD
-	resp, err := http.Get(fmt.Sprintf("https://api.nf.domains/nfd/%v?view=tiny&poll=false&nocache=false", appID))
-	if err != nil {
-		return false
-	}
-
-	return resp.StatusCode == 200
-}
-
-// helpers
-func (p *NFDProvider) SyncNFDByAppID(appID uint64, currentBlock uint64) error {
-	const op errors.Op = "SyncNFDByAppID"
-	var new bool = false
-	var communitySet bool = false
-
-	_, err := db.GetProvider(p.DB, appID)
-	if err != nil && !db.ErrNoRows(err) {
-		return errors.E(op, err)
-	} else if db.ErrNoRows(err) {
-		new = true
-	}
-
-	nfdProperties, err := GetNFDData(p.Algod, context.Background(), appID)
-	if err != nil {
-		return errors.E(op, err)
-	}
-
-	tx, err := p.DB.Beginx()
-	if err != nil {
-		return errors.E(op, err)
-	}
-
-	// jsonDataIndent, err := json.MarshalIndent(nfdProperties, "", "  ")
-	// if err != nil {
-	// 	tx.Rollback()
-	// 	return errors.E(op, err)
-	// }
-	// fmt.Println(string(jsonDataIndent))
-
-	dniAddresses := []string{}
-	addresses := map[string]db.ProviderAddress{}
-
-	if !new {
-		preexistingAddresses, err := db.GetProviderAddresses(p.DB, appID)
-		if err != nil && !db.ErrNoRows(err) {
-			tx.Rollback()
-			return errors.E(op, err)
+// searchRegistry fetches the page of registry transactions a checkpoint points at,
+// retrying with a backoff so a flaky indexer doesn't end the catch up
+func (p *NFDProvider) searchRegistry(ctx context.Context, indexerClient *indexer.Client, registry uint64, checkpoint *db.CatchupCheckpoint) (models.TransactionsResponse, error) {
+	const op errors.Op = "searchRegistry"
+
+	pageSize := p.settings.CatchupPageSize
+	if pageSize == 0 {
+		pageSize = defaultCatchupPageSize
+	}
+
+	wait := time.Second
+	for attempt := 1; ; attempt++ {
+		resp, err := indexerClient.SearchForTransactions().
+			ApplicationId(registry).
+			MinRound(checkpoint.MinRound).
+			NextToken(checkpoint.NextToken).
+			Limit(pageSize).
+			Do(ctx)
+		if err == nil {
+			return resp, nil
 		}
 
-		if preexistingAddresses != nil {
-			for _, address := range *preexistingAddresses {
-				addresses[address.Address] = address
-			}
+		if attempt == maxSearchAttempts {
+			return resp, errors.E(op, errors.Network, err)
 		}
-	}
 
-	for key, value := range nfdProperties.UserDefined {
-		switch key {
-		case "akitacommunity", "project":
-			{
-				communitySet = true
-				err = p.processCommunity(tx, appID, []byte(value))
-				if err != nil {
-					tx.Rollback()
-					return errors.E(op, err)
-				}
-			}
-		}
+		fmt.Println("[CATCHUP] [NFD] [RETRYING]: ", err)
+		time.Sleep(wait)
+		wait *= 2
 	}
+}
 
-	for key, value := range nfdProperties.Verified {
-		switch key {
-		case "caAlgo":
-			{
-				vaddresses := misc.UniqueSlice(strings.Split(value, ","))
-
-				for _, address := range vaddresses {
-					dniAddresses = append(dniAddresses, address)
-					nfdWallet := &db.ProviderAddress{ID: appID, Address: address}
-
-					_, walletExists := addresses[address]
-					if !walletExists {
-						_, err = db.Insert(tx, nfdWallet)
-						if err != nil {
-							tx.Rollback()
-							return errors.E(op, err)
-						}
-					}
-				}
-			}
-		}
+func (p *NFDProvider) syncInterval() time.Duration {
+	interval := p.settings.SyncIntervalMs
+	if interval <= 0 {
+		interval = defaultSyncIntervalMs
 	}
+	return time.Duration(interval) * time.Millisecond
+}
+
+func (p *NFDProvider) ProcessBlock(btx *community.BlockTx, stxn types.SignedTxnInBlock, round uint64) error {
+	const op errors.Op = "NFDProvider.ProcessBlock"
 
-	// delete wallets not in list
-	err = db.DeleteProviderAddressNotIn(tx, appID, dniAddresses...)
+	err := p.processTxns(btx, []types.SignedTxnWithAD{stxn.SignedTxnWithAD}, round)
 	if err != nil {
-		tx.Rollback()
 		return errors.E(op, err)
 	}
 
-	if !communitySet {
-		_, err = db.GetCommunity(p.DB, appID)
-		if err != nil && !db.ErrNoRows(err) {
-			tx.Rollback()
-			return errors.E(op, err)
-		} else if !db.ErrNoRows(err) {
-			err = compound.DeleteCommunity(tx, appID)
-			if err != nil {
-				tx.Rollback()
-				return errors.E(op, err)
-			}
-		}
-	}
+	return nil
+}
 
-	// insert or update db with NFD data
-	if new {
-		_, err = db.Insert(tx, &db.Provider{ID: appID, Type: "nfd", Round: currentBlock})
-		if err != nil {
-			tx.Rollback()
-			return errors.E(op, err)
-		}
+func (p *NFDProvider) ProcessBlockBatch(btx *community.BlockTx, block *types.Block, round uint64) error {
+	const op errors.Op = "NFDProvider.ProcessBlockBatch"
+
+	stxns := make([]types.SignedTxnWithAD, len(block.Payset))
+	for i := range block.Payset {
+		stxns[i] = block.Payset[i].SignedTxnWithAD
 	}
 
-	err = tx.Commit()
+	err := p.processTxns(btx, stxns, round)
 	if err != nil {
-		tx.Rollback()
 		return errors.E(op, err)
 	}
 
 	return nil
 }
 
-func (p *NFDProvider) processCommunity(tx *sqlx.Tx, nfdID uint64, data []byte) error {
-	const op errors.Op = "ProcessCommunity"
+// processTxns syncs every NFD minted or called by the transactions & their inner
+// transactions exactly once, a failed sync doesn't stop the others & every failed
+// app is returned in SyncFailures
+func (p *NFDProvider) processTxns(btx *community.BlockTx, stxns []types.SignedTxnWithAD, round uint64) error {
+	const op errors.Op = "processTxns"
 
-	if strings.HasPrefix(string(data), "ipfs://") {
-		ipfsdata, err := GetIPFSData(string(data))
-		if err != nil {
-			fmt.Println(err)
-			return nil
-		}
-		data = ipfsdata
-	}
+	registry := p.registryAppID()
 
-	commJson := &db.CommunityJson{
-		ID:   nfdID,
-		Data: string(data),
+	// new NFDs
+	minted := []uint64{}
+	for i := range stxns {
+		minted = append(minted, MintedAppIDs(stxns[i], registry)...)
 	}
+	minted = misc.UniqueSlice(minted)
 
-	prevJson, err := db.GetCommunityJson(p.DB, nfdID)
-	if err != nil && !db.ErrNoRows(err) {
-		return errors.E(op, err)
-	} else if db.ErrNoRows(err) {
-		_, err = db.Insert(tx, commJson)
-		if err != nil {
-			return nil
-		}
-	} else {
-		if prevJson.Data == string(data) {
-			return nil
+	// new NFDs are only tracked once the block that minted them commits
+	btx.AfterCommit(func() {
+		for _, appID := range minted {
+			p.SyncMap.Store(appID, struct{}{})
 		}
-
-		_, err = db.Update(tx, commJson, map[string]interface{}{"id": nfdID})
-		if err != nil {
-			return errors.E(op, err)
+	})
+
+	// updates on existing NFDs
+	calls := collectAppCalls(stxns)
+	toSync := []uint64{}
+	for appID := range calls {
+		if _, exists := p.SyncMap.Load(appID); exists && !misc.InSlice(appID, minted) {
+			toSync = append(toSync, appID)
 		}
 	}
+	sort.Slice(toSync, func(i, j int) bool { return toSync[i] < toSync[j] })
 
-	communityData := compound.Community{}
-	err = json.Unmarshal(data, &communityData)
-	if err != nil {
-		fmt.Println(err)
-
-		commJson.Malformed = misc.PointerBool(true)
-		_, err := db.Update(tx, commJson, map[string]interface{}{"id": nfdID})
+	failures := providers.SyncFailures{}
+	for _, appID := range minted {
+		_, err := p.syncNFD(btx, appID, round)
 		if err != nil {
-			return errors.E(op, err)
+			fmt.Println("[NFD] [ERROR]: ", err)
+			failures[appID] = err
 		}
-
-		return nil
 	}
 
-	comm := communityData.Community
-	comm.ID = nfdID
-
-	_, err = db.GetCommunity(p.DB, nfdID)
-	if err != nil && !db.ErrNoRows(err) {
-		return errors.E(op, err)
-	} else if db.ErrNoRows(err) {
-		_, err = db.Insert(tx, comm)
+	for _, appID := range toSync {
+		_, err := p.syncNFDFromCalls(btx, appID, round, calls[appID])
 		if err != nil {
-			return errors.E(op, err)
+			fmt.Println("[NFD] [ERROR]: ", err)
+			failures[appID] = err
 		}
 	}
 
-	err = p.processTokens(tx, nfdID, communityData.Tokens)
-	if err != nil {
-		return errors.E(op, err)
-	}
-
-	err = p.processAssociates(tx, nfdID, communityData.Associates)
-	if err != nil {
-		return errors.E(op, err)
-	}
-
-	err = p.processCollections(tx, nfdID, communityData.Collections)
-	if err != nil {
-		return errors.E(op, err)
-	}
-
-	err = p.processFaq(tx, nfdID, communityData.Faq)
-	if err != nil {
-		return errors.E(op, err)
-	}
-
-	err = p.processExtras(tx, nfdID, communityData.Extras)
-	if err != nil {
-		return errors.E(op, err)
+	if len(failures) > 0 {
+		return errors.E(op, failures)
 	}
 
 	return nil
 }
 
-func (p *NFDProvider) processTokens(tx *sqlx.Tx, nfdID uint64, tokensData []db.CommunityToken) error {
-	const op errors.Op = "ProcessTokens"
-
-	tokenKeys := map[uint64]db.CommunityToken{}
-	tokens, err := db.GetCommunityTokens(p.DB, nfdID)
-	if err != nil && !db.ErrNoRows(err) {
-		return errors.E(op, err)
-	}
+func (p *NFDProvider) Process(appID uint64) (*community.Changes, error) {
+	const op errors.Op = "NFDProvider.Process"
+	var err error
 
-	if tokens != nil {
-		for _, token := range *tokens {
-			tokenKeys[token.AssetID] = token
-		}
+	if !p.IsProviderApp(appID) {
+		return nil, errors.E(op, fmt.Errorf("appID is not an NFD"))
 	}
 
-	dniKeys := []uint64{}
-	for _, t := range tokensData {
-		token := t
-
-		// we dont need to trigger the asset cache processor here because *if*
-		// the asset is actually created by this community it will be picked up while
-		// the system processes the verified wallets created assets
-
-		_, exists := tokenKeys[token.AssetID]
-		dniKeys = append(dniKeys, token.AssetID)
-		token.ID = nfdID
-		if !exists {
-			_, err = db.Insert(tx, &token)
-			if err != nil {
-				return errors.E(op, err)
-			}
-		} else {
-			_, err = db.Update(tx, &token, map[string]interface{}{"id": nfdID, "asset_id": token.AssetID})
-			if err != nil {
-				return errors.E(op, err)
-			}
-		}
+	// get current block
+	status, err := p.Algod.Status().Do(context.Background())
+	if err != nil {
+		return nil, errors.E(op, err)
 	}
 
-	err = db.DeleteCommunityTokensNotIn(tx, nfdID, dniKeys...)
+	changes, err := p.SyncNFDByAppID(appID, status.LastRound)
 	if err != nil {
-		return errors.E(op, err)
+		return nil, errors.E(op, err)
 	}
 
-	return nil
+	return changes, nil
 }
 
-func (p *NFDProvider) processAssociates(tx *sqlx.Tx, nfdID uint64, associateData []db.CommunityAssociate) error {
-	const op errors.Op = "ProcessTokens"
-
-	associateKeys := map[string]db.CommunityAssociate{}
-	associates, err := db.GetCommunityAssociates(p.DB, nfdID)
-	if err != nil && !db.ErrNoRows(err) {
-		return errors.E(op, err)
-	}
-
-	for _, associate := range *associates {
-		associateKeys[associate.Address] = associate
+func (p *NFDProvider) IsProviderApp(appID uint64) bool {
+	_, exists := p.SyncMap.Load(appID)
+	if exists {
+		return true
 	}
 
-	dniKeys := []string{}
-	for _, a := range associateData {
-		associate := a
-
-		_, exists := associateKeys[associate.Address]
-		dniKeys = append(dniKeys, associate.Address)
-		if !exists {
-			associate.ID = nfdID
-			_, err = db.Insert(tx, &associate)
-			if err != nil {
-				return errors.E(op, err)
-			}
-		}
+	// check on chain that it's an NFD of our registry
+	isNFD, err := IsNFDApp(p.Algod, context.Background(), p.registryAppID(), appID)
+	if err != nil {
+		fmt.Println("[NFD] [ERROR]: ", err)
+		return false
 	}
 
-	err = db.DeleteCommunityAssociatesNotIn(tx, nfdID, dniKeys...)
-	if err != nil {
-		return errors.E(op, err)
+	// an NFD stays one, so later checks are answered without going to algod
+	if isNFD {
+		p.SyncMap.Store(appID, struct{}{})
 	}
 
-	return nil
+	return isNFD
 }
 
-func (p *NFDProvider) processCollections(tx *sqlx.Tx, nfdID uint64, collectionsData []compound.Collection) error {
-	const op errors.Op = "ProcessCollections"
-
-	collectionKeys := map[string]compound.Collection{}
-	collections, err := compound.GetCollectionsByProviderID(p.DB, nfdID)
-	if err != nil {
-		if err.(*errors.Error).Kind != errors.DatabaseResultNotFound {
-			return errors.E(op, err)
+// ObserveRound records a round algod has reached, state read after it holds every change up to it
+func (p *NFDProvider) ObserveRound(round uint64) {
+	for {
+		last := p.lastRound.Load()
+		if round <= last || p.lastRound.CompareAndSwap(last, round) {
+			return
 		}
-
-		// even if we get no results back, the map will be empty
-		// and will be caught by the check for pre-existing collections
 	}
+}
 
-	for i := range *collections {
-		col := (*collections)[i]
-		collectionKeys[col.Name] = col
+// helpers
+func (p *NFDProvider) registryAppID() uint64 {
+	if p.settings.RegistryAppID != 0 {
+		return p.settings.RegistryAppID
 	}
 
-	dniCollection := []string{}
-	for _, col := range collectionsData {
-		pre, exists := collectionKeys[col.Name]
-		if exists {
-			dniCollection = append(dniCollection, pre.ID)
-
-			preJson, err := json.Marshal(pre)
-			if err != nil {
-				return errors.E(op, err)
-			}
-
-			colJson, err := json.Marshal(col)
-			if err != nil {
-				return errors.E(op, err)
-			}
-
-			if string(preJson) == string(colJson) {
-				continue
-			}
-
-			// update
-			_, err = db.Update(tx, col.Collection, map[string]interface{}{"id": pre.ID})
-			if err != nil {
-				return errors.E(op, err)
-			}
-
-			// insert or update prefixes
-			// dni = Delete not in
-			dniPrefixes := []string{}
-			for _, prefix := range col.Prefixes {
-				dniPrefixes = append(dniPrefixes, prefix)
-
-				if !misc.InSlice(prefix, pre.Prefixes) {
-					_, err = db.Insert(tx, &db.CollectionPrefix{ID: pre.ID, Prefix: prefix})
-					if err != nil {
-						return errors.E(op, err)
-					}
-				}
-			}
-
-			// delete not in prefix list
-			err = db.DeleteCollectionPrefixesNotIn(tx, pre.ID, dniPrefixes...)
-			if err != nil {
-				return errors.E(op, err)
-			}
-
-			dniAddresses := []string{}
-			for _, address := range col.Addresses {
-				dniAddresses = append(dniAddresses, address)
-
-				if !misc.InSlice(address, pre.Addresses) {
-					_, err = db.Insert(tx, &db.CollectionAddress{ID: pre.ID, Address: address})
-					if err != nil {
-						return errors.E(op, err)
-					}
-				}
-			}
-
-			// delete not in address list
-			err = db.DeleteCollectionAddressesNotIn(tx, pre.ID, dniAddresses...)
-			if err != nil {
-				return errors.E(op, err)
-			}
-
-			// insert or update assets
-			// dni = Delete not in
-			dniAssets := []uint64{}
-			for _, asset := range col.Assets {
-				dniAssets = append(dniAssets, asset)
-
-				if !misc.InSlice(asset, pre.Assets) {
-					_, err = db.Insert(tx, &db.CollectionAsset{ID: pre.ID, AsaID: asset})
-					if err != nil {
-						return errors.E(op, err)
-					}
-				}
-			}
-
-			// delete not in asset list
-			err = db.DeleteCollectionAssetsNotIn(tx, pre.ID, dniAssets...)
-			if err != nil {
-				return errors.E(op, err)
-			}
-
-			// insert or update excluded assets
-			// dni = Delete not in
-			dniExcludedAssets := []uint64{}
-			for _, asset := range col.ExcludedAssets {
-				dniExcludedAssets = append(dniExcludedAssets, asset)
-
-				if !misc.InSlice(asset, pre.ExcludedAssets) {
-					_, err = db.Insert(tx, &db.CollectionExcludedAsset{ID: pre.ID, AsaID: asset})
-					if err != nil {
-						return errors.E(op, err)
-					}
-				}
-			}
-
-			// delete not in excluded asset list
-			err = db.DeleteCollectionExcludedAssetsNotIn(tx, pre.ID, dniExcludedAssets...)
-			if err != nil {
-				return errors.E(op, err)
-			}
-
-			dniArtists := []string{}
-			for _, address := range col.Artists {
-				dniArtists = append(dniArtists, address)
-
-				if !misc.InSlice(address, pre.Artists) {
-					_, err = db.Insert(tx, &db.CollectionArtist{ID: pre.ID, Address: address})
-					if err != nil {
-						return errors.E(op, err)
-					}
-				}
-			}
-
-			// delete not in artist list
-			err = db.DeleteCollectionArtistsNotIn(tx, pre.ID, dniArtists...)
-			if err != nil {
-				return errors.E(op, err)
-			}
-
-			propertyKeys := map[string]compound.Property{}
-			for _, property := range pre.Properties {
-				propertyKeys[property.Name] = property
-			}
-
-			// dni = Delete not in
-			dniProperties := []string{}
-			for _, prop := range col.Properties {
-				preProp, propExists := propertyKeys[prop.Name]
-				if propExists {
-					prop.ID = preProp.ID
-					prop.CollectionID = preProp.CollectionID
-
-					dniProperties = append(dniProperties, prop.ID)
-
-					_, err = db.Update(tx, prop.Property, map[string]interface{}{"id": preProp.ID})
-					if err != nil {
-						return errors.E(op, err)
-					}
-
-					propValueNames := []string{}
-					for _, value := range preProp.Values {
-						propValueNames = append(propValueNames, value.Name)
-					}
-
-					// insert or update properties values
-					dniPropertiesValues := []string{}
-					for _, value := range prop.Values {
-						dniPropertiesValues = append(dniPropertiesValues, value.Name)
-						value.ID = preProp.ID
-
-						if misc.InSlice(value.Name, propValueNames) {
-							_, err = db.Update(tx, value.PropertyValue, map[string]interface{}{"id": preProp.ID, "name": value.Name})
-							if err != nil {
-								return errors.E(op, err)
-							}
-						} else {
-							_, err = db.Insert(tx, value.PropertyValue)
-							if err != nil {
-								return errors.E(op, err)
-							}
-						}
-
-						propValueExtrasKeys := []string{}
-						for _, pvalue := range preProp.Values {
-							if pvalue.Name == value.Name {
-								for extraKey := range pvalue.Extras {
-									propValueExtrasKeys = append(propValueExtrasKeys, extraKey)
-								}
-							}
-						}
-
-						// fmt.Println("propValueExtrasKeys: ", propValueExtrasKeys)
-
-						// update or insert properties values extras
-						dniPropertiesValuesExtras := []string{}
-						for extraKey, extraValue := range value.Extras {
-							dniPropertiesValuesExtras = append(dniPropertiesValuesExtras, extraKey)
-
-							extra := &db.PropertyValueExtras{
-								ID:    preProp.ID,
-								Name:  value.Name,
-								Key:   extraKey,
-								Value: extraValue,
-							}
-
-							if misc.InSlice(extraKey, propValueExtrasKeys) {
-								_, err = db.Update(tx, extra, map[string]interface{}{"id": preProp.ID, "name": value.Name, "mkey": extra.Key})
-								if err != nil {
-									return errors.E(op, err)
-								}
-							} else {
-								_, err = db.Insert(tx, extra)
-								if err != nil {
-									return errors.E(op, err)
-								}
-							}
-						}
-
-						// delete not in properties values extras list
-						err = db.DeletePropertyValueExtrasNotIn(tx, preProp.ID, value.Name, dniPropertiesValuesExtras...)
-						if err != nil {
-							return errors.E(op, err)
-						}
-					}
-
-					// delete not in properties values list
-					err = db.DeletePropertyValueNotIn(tx, preProp.ID, dniPropertiesValues...)
-					if err != nil {
-						return errors.E(op, err)
-					}
-
-				} else {
-					// insert prop that didnt exist before
-					prop.ID = uuid.New(uuid.Property)
-					prop.CollectionID = pre.ID
-
-					dniProperties = append(dniProperties, prop.ID)
-
-					_, err = db.Insert(tx, prop.Property)
-					if err != nil {
-						return errors.E(op, err)
-					}
-
-					for _, value := range prop.Values {
-						value.ID = prop.ID
-
-						_, err = db.Insert(tx, value.PropertyValue)
-						if err != nil {
-							return errors.E(op, err)
-						}
-
-						for extraKey, extraValue := range value.Extras {
-							// fmt.Println("inserting extra: ", prop.ID, " - ", extraKey, " - ", extraValue)
-
-							extra := &db.PropertyValueExtras{
-								ID:    prop.ID,
-								Name:  value.Name,
-								Key:   extraKey,
-								Value: extraValue,
-							}
-
-							_, err = db.Insert(tx, extra)
-							if err != nil {
-								return errors.E(op, err)
-							}
-						}
-					}
-				}
-			}
-
-			for _, prop := range pre.Properties {
-				if !misc.InSlice(prop.ID, dniProperties) {
-					// delete prop values & meta that should no longer exist
-					err = db.DeletePropertyValues(tx, prop.ID)
-					if err != nil {
-						return errors.E(op, err)
-					}
-
-					err = db.DeletePropertyValueExtras(tx, prop.ID)
-					if err != nil {
-						return errors.E(op, err)
-					}
-				}
-			}
-
-			err = db.DeletePropertyNotIn(tx, pre.ID, dniProperties...)
-			if err != nil {
-				return errors.E(op, err)
-			}
-
-			preExtras := []string{}
-			for extraKey := range pre.Extras {
-				preExtras = append(preExtras, extraKey)
-			}
-
-			// insert or update extras
-			// dni = Delete not in
-			dniExtras := []string{}
-			for extraKey, extraValue := range col.Extras {
-				dniExtras = append(dniExtras, extraKey)
-
-				extra := &db.CollectionExtras{
-					ID:    pre.ID,
-					Key:   extraKey,
-					Value: extraValue,
-				}
-
-				if misc.InSlice(extraKey, preExtras) {
-					_, err = db.Update(tx, extra, map[string]interface{}{"id": pre.ID, "mkey": extra.Key})
-					if err != nil {
-						return errors.E(op, err)
-					}
-				} else {
-					_, err = db.Insert(tx, extra)
-					if err != nil {
-						return errors.E(op, err)
-					}
-				}
-			}
-
-			// delete not in extras list
-			err = db.DeleteCollectionExtrasNotIn(tx, pre.ID, dniExtras...)
-			if err != nil {
-				return errors.E(op, err)
-			}
-		} else {
-			// insert
-			col.ID = uuid.New(uuid.Collection)
-			col.ProviderID = nfdID
-			dniCollection = append(dniCollection, col.ID)
-
-			// collection
-			_, err = db.Insert(tx, col.Collection)
-			if err != nil {
-				return errors.E(op, err)
-			}
-
-			// prefixes
-			for _, prefix := range col.Prefixes {
-				_, err = db.Insert(tx, &db.CollectionPrefix{ID: col.ID, Prefix: prefix})
-				if err != nil {
-					return errors.E(op, err)
-				}
-			}
-
-			// asset
-			for _, asset := range col.Assets {
-				_, err = db.Insert(tx, &db.CollectionAsset{ID: col.ID, AsaID: asset})
-				if err != nil {
-					return errors.E(op, err)
-				}
-			}
-
-			// excluded_asset
-			for _, asset := range col.ExcludedAssets {
-				_, err = db.Insert(tx, &db.CollectionExcludedAsset{ID: col.ID, AsaID: asset})
-				if err != nil {
-					return errors.E(op, err)
-				}
-			}
-
-			// artist
-			for _, artist := range col.Artists {
-				_, err = db.Insert(tx, &db.CollectionArtist{ID: col.ID, Address: artist})
-				if err != nil {
-					return errors.E(op, err)
-				}
-			}
+	return registryAppIDs[p.network]
+}
 
-			// properties
-			for _, prop := range col.Properties {
-				prop.ID = uuid.New(uuid.Property)
-				prop.CollectionID = col.ID
-
-				_, err = db.Insert(tx, prop.Property)
-				if err != nil {
-					return errors.E(op, err)
-				}
-
-				// property values
-				for _, value := range prop.Values {
-					value.ID = prop.ID
-
-					_, err = db.Insert(tx, value.PropertyValue)
-					if err != nil {
-						return errors.E(op, err)
-					}
-
-					for extraKey, extraValue := range value.Extras {
-
-						extra := &db.PropertyValueExtras{
-							ID:    prop.ID,
-							Name:  value.Name,
-							Key:   extraKey,
-							Value: extraValue,
-						}
-
-						_, err = db.Insert(tx, extra)
-						if err != nil {
-							return errors.E(op, err)
-						}
-					}
-				}
-			}
+// SyncNFDByAppID refetches the full state of an NFD from algod & syncs it
+func (p *NFDProvider) SyncNFDByAppID(appID uint64, currentBlock uint64) (*community.Changes, error) {
+	return p.syncNFD(nil, appID, currentBlock)
+}
 
-			// extras
-			for extraKey, extraValue := range col.Extras {
-				_, err = db.Insert(tx, &db.CollectionExtras{ID: col.ID, Key: extraKey, Value: extraValue})
-				if err != nil {
-					return errors.E(op, err)
-				}
-			}
-		}
-	}
+// syncNFD is SyncNFDByAppID as part of btx when it's set
+func (p *NFDProvider) syncNFD(btx *community.BlockTx, appID uint64, currentBlock uint64) (*community.Changes, error) {
+	const op errors.Op = "SyncNFDByAppID"
 
-	err = db.DeleteCollectionNotIn(tx, nfdID, dniCollection...)
+	p.ObserveRound(currentBlock)
+	state, err := fetchNFDState(p.Algod, context.Background(), appID, p.lastRound.Load())
 	if err != nil {
-		return errors.E(op, err)
+		p.states.delete(appID)
+		return nil, errors.E(op, err)
 	}
+	state.round = currentBlock
 
-	return nil
-}
-
-func (p *NFDProvider) processFaq(tx *sqlx.Tx, nfdID uint64, faqData []db.CommunityFaq) error {
-	const op errors.Op = "ProcessFaq"
-	var err error
-
-	err = db.DeleteCommunityFaq(tx, nfdID)
+	changes, err := p.syncer.SyncIn(btx, NFDState(state.properties(appID), currentBlock))
 	if err != nil {
-		return errors.E(op, err)
+		p.states.delete(appID)
+		return nil, errors.E(op, err)
 	}
 
-	for i, faq := range faqData {
-		nfaq := faq
+	btx.AfterCommit(func() { p.states.set(appID, state) })
 
-		nfaq.ID = nfdID
-		nfaq.Ordering = misc.Pointer(uint64(i))
-
-		_, err = db.Insert(tx, &nfaq)
-		if err != nil {
-			return errors.E(op, err)
-		}
-	}
-
-	return nil
+	return changes, nil
 }
 
-func (p *NFDProvider) processExtras(tx *sqlx.Tx, nfdID uint64, extrasData []db.CommunityExtras) error {
-	const op errors.Op = "ProcessExtras"
-
-	extraKeys := map[string]db.CommunityExtras{}
-	extras, err := db.GetCommunityExtras(p.DB, nfdID)
-	if err != nil && !db.ErrNoRows(err) {
-		return errors.E(op, err)
-	}
+// syncNFDFromCalls applies the global state deltas of a block's calls to the cached state
+// of an NFD & only goes to algod for the boxes the calls could have written, falling back
+// to a full refetch when there's no usable cached state or a delta can't be applied.
+// Blocks at or before the round the cached state was read at are already part of it, so
+// while catching up, scanning or replaying nothing is written until a block passes it
+func (p *NFDProvider) syncNFDFromCalls(btx *community.BlockTx, appID uint64, round uint64, calls *appCalls) (*community.Changes, error) {
+	const op errors.Op = "syncNFDFromCalls"
+
+	state := p.states.get(appID)
+	if state == nil || round <= state.round {
+		// nothing cached or the watcher was rewound past the cached state
+		return p.syncNFD(btx, appID, round)
+	}
+
+	if round <= state.fetched {
+		// the block's deltas would only replay history over newer state & write it as if it
+		// were the block's, the state the NFD was synced with already holds them
+		state.round = round
+		btx.AfterCommit(func() { p.states.set(appID, state) })
+		return &community.Changes{}, nil
+	}
+
+	for i := range calls.txns {
+		if calls.txns[i].Txn.OnCompletion == types.DeleteApplicationOC {
+			// a deleted NFD has no state left, syncing the empty state clears its community
+			state = newNFDState(round)
+			break
+		}
 
-	for _, extra := range *extras {
-		extraKeys[extra.Key] = extra
+		if !state.applyDelta(calls.txns[i].EvalDelta.GlobalDelta) {
+			return p.syncNFD(btx, appID, round)
+		}
 	}
 
-	dniKeys := []string{}
-	for _, extra := range extrasData {
-		nextra := extra
-
-		dniKeys = append(dniKeys, extra.Key)
-		pre, exists := extraKeys[extra.Key]
-		if exists {
-			_, err = db.Update(tx, &nextra, map[string]interface{}{"id": pre.ID})
-			if err != nil {
-				return errors.E(op, err)
-			}
-		} else {
-
-			nextra.ID = nfdID
-			_, err = db.Insert(tx, &nextra)
-			if err != nil {
-				return errors.E(op, err)
-			}
+	for _, name := range calls.boxes {
+		box, err := p.Algod.GetApplicationBoxByName(appID, []byte(name)).Do(context.Background())
+		if err != nil && misc.IsAlgodNotFound(err) {
+			delete(state.boxes, name)
+			continue
+		} else if err != nil {
+			return p.syncNFD(btx, appID, round)
 		}
+		state.boxes[name] = box.Value
 	}
+	state.round = round
 
-	err = db.DeleteCommunityExtrasNotIn(tx, nfdID, dniKeys...)
+	changes, err := p.syncer.SyncIn(btx, NFDState(state.properties(appID), round))
 	if err != nil {
-		return errors.E(op, err)
+		p.states.delete(appID)
+		return nil, errors.E(op, err)
 	}
 
-	return nil
-}
+	btx.AfterCommit(func() { p.states.set(appID, state) })
 
-func GetIPFSData(url string) ([]byte, error) {
-	const op errors.Op = "GetIPFSData"
-	if !strings.HasPrefix(url, "ipfs://") {
-		fmt.Printf("invalid ipfs url: %s\n", url)
-		// return nil, errors.E(op, fmt.Errorf("invalid ipfs url: %s", url))
-	}
+	return changes, nil
+}
 
-	url = strings.Replace(url, "ipfs://", "https://ipfs.algonode.xyz/ipfs/", 1)
-	resp, err := http.Get(url)
-	if err != nil {
-		return nil, errors.E(op, err)
+// NFDState maps the properties of an NFD onto the ARC53 state of its app
+func NFDState(properties *NFDProperties, round uint64) community.State {
+	state := community.State{
+		ID:    properties.AppID,
+		Type:  "nfd",
+		Round: round,
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, errors.E(op, err)
+	for _, key := range []string{"project", "akitacommunity"} {
+		value, ok := properties.UserDefined[key]
+		if ok {
+			state.Metadata = &value
+			break
+		}
 	}
 
-	if resp.StatusCode != http.StatusOK || len(body) == 0 {
-		return nil, errors.E(op, fmt.Errorf("ipfs request failed: %s", resp.Status))
+	caAlgo, ok := properties.Verified["caAlgo"]
+	if ok {
+		state.Addresses = misc.UniqueSlice(strings.Split(caAlgo, ","))
 	}
 
-	return body, nil
+	return state
 }
@@ -0,0 +1,37 @@
+package nfd
+
+import (
+	"testing"
+
+	"github.com/algorand/go-algorand-sdk/v2/types"
+)
+
+func TestSyncNFDFromCallsSkipsRoundsInFetchedState(t *testing.T) {
+	state := newNFDState(50)
+	state.fetched = 100
+
+	// without algod or a syncer anything but the skip would panic
+	p := &NFDProvider{states: newStateCache()}
+	p.states.set(1001, state)
+
+	calls := &appCalls{txns: []types.SignedTxnWithAD{{}}}
+	calls.txns[0].EvalDelta.GlobalDelta = types.StateDelta{"u.project": {Action: types.SetBytesAction, Bytes: "ipfs://old"}}
+
+	for _, round := range []uint64{60, 100} {
+		changes, err := p.syncNFDFromCalls(nil, 1001, round, calls)
+		if err != nil {
+			t.Fatalf("syncNFDFromCalls(%d): %v", round, err)
+		}
+		if changes.Changed() {
+			t.Fatalf("syncNFDFromCalls(%d) changed %+v, want nothing written before the fetched round", round, changes)
+		}
+
+		cached := p.states.get(1001)
+		if cached.round != round {
+			t.Fatalf("cached round = %d, want %d", cached.round, round)
+		}
+		if _, ok := cached.global["u.project"]; ok {
+			t.Fatalf("the historical delta of round %d was applied to the cached state", round)
+		}
+	}
+}
@@ -0,0 +1,251 @@
+package nfd
+
+import (
+	"context"
+	"encoding/base64"
+	"sync"
+
+	"github.com/algorand/go-algorand-sdk/v2/client/v2/algod"
+	"github.com/algorand/go-algorand-sdk/v2/client/v2/common/models"
+	"github.com/algorand/go-algorand-sdk/v2/types"
+	"github.com/kylebeee/arc53-watcher-go/errors"
+	"github.com/kylebeee/arc53-watcher-go/misc"
+)
+
+// maxCachedStates bounds the number of NFDs whose raw state is kept in memory
+const maxCachedStates = 20000
+
+// nfdState is the raw key/value state of an NFD app as of a round
+type nfdState struct {
+	round uint64
+	// fetched is algod's last round when the state was read, the state holds every change
+	// made up to it no matter which block it was read for
+	fetched uint64
+	global  map[string]models.TealValue
+	boxes   map[string][]byte
+}
+
+func newNFDState(round uint64) *nfdState {
+	return &nfdState{
+		round:  round,
+		global: map[string]models.TealValue{},
+		boxes:  map[string][]byte{},
+	}
+}
+
+// fetchNFDState loads the full global state & every box of an NFD app from algod,
+// fetched is a round algod is known to have reached before the state is read
+func fetchNFDState(algoClient *algod.Client, ctx context.Context, appID uint64, fetched uint64) (*nfdState, error) {
+	const op errors.Op = "fetchNFDState"
+
+	appData, err := algoClient.GetApplicationByID(appID).Do(ctx)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	// Now load all the box data (V2) in parallel
+	boxData, err := GetApplicationBoxes(algoClient, ctx, appID)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	state := newNFDState(0)
+	state.fetched = fetched
+	for _, kv := range appData.Params.GlobalState {
+		key, err := base64.StdEncoding.DecodeString(kv.Key)
+		if err != nil {
+			return nil, errors.E(op, err)
+		}
+
+		value := kv.Value
+		if value.Type == uint64(types.TealBytesType) {
+			raw, err := base64.StdEncoding.DecodeString(value.Bytes)
+			if err != nil {
+				return nil, errors.E(op, err)
+			}
+			value.Bytes = string(raw)
+		}
+		state.global[string(key)] = value
+	}
+	state.boxes = boxData
+
+	return state, nil
+}
+
+// properties decodes the raw state the same way a fresh GetNFDData would
+func (s *nfdState) properties(appID uint64) *NFDProperties {
+	globalState := make([]models.TealKeyValue, 0, len(s.global))
+	for key, value := range s.global {
+		if value.Type == uint64(types.TealBytesType) {
+			value.Bytes = base64.StdEncoding.EncodeToString([]byte(value.Bytes))
+		}
+		globalState = append(globalState, models.TealKeyValue{
+			Key:   base64.StdEncoding.EncodeToString([]byte(key)),
+			Value: value,
+		})
+	}
+
+	properties := FetchAllStateAsNFDProperties(globalState, s.boxes)
+	properties.AppID = appID
+	properties.UserDefined = MergeNFDProperties(properties.UserDefined)
+	return &properties
+}
+
+func (s *nfdState) clone() *nfdState {
+	c := newNFDState(s.round)
+	c.fetched = s.fetched
+	for key, value := range s.global {
+		c.global[key] = value
+	}
+	for name, value := range s.boxes {
+		c.boxes[name] = value
+	}
+	return c
+}
+
+// applyDelta applies a global state delta from a block, it reports false when the
+// delta holds an action it doesn't know & the state has to be refetched instead
+func (s *nfdState) applyDelta(delta types.StateDelta) bool {
+	for key, change := range delta {
+		switch change.Action {
+		case types.SetBytesAction:
+			s.global[key] = models.TealValue{Type: uint64(types.TealBytesType), Bytes: change.Bytes}
+		case types.SetUintAction:
+			s.global[key] = models.TealValue{Type: uint64(types.TealUintType), Uint: change.Uint}
+		case types.DeleteAction:
+			delete(s.global, key)
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// stateCache keeps the raw state of tracked NFDs so block deltas can be applied to it
+type stateCache struct {
+	lock   sync.Mutex
+	states map[uint64]*nfdState
+}
+
+func newStateCache() *stateCache {
+	return &stateCache{states: map[uint64]*nfdState{}}
+}
+
+// get returns a copy of the cached state of an NFD, nil when it isn't cached
+func (c *stateCache) get(appID uint64) *nfdState {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	state, ok := c.states[appID]
+	if !ok {
+		return nil
+	}
+	return state.clone()
+}
+
+func (c *stateCache) set(appID uint64, state *nfdState) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if _, exists := c.states[appID]; !exists && len(c.states) >= maxCachedStates {
+		// evict an arbitrary NFD, it's refetched in full the next time it's touched
+		for evict := range c.states {
+			delete(c.states, evict)
+			break
+		}
+	}
+	c.states[appID] = state
+}
+
+func (c *stateCache) delete(appID uint64) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	delete(c.states, appID)
+}
+
+// appCalls are the calls made to a single app within a block & the boxes they could have written
+type appCalls struct {
+	txns  []types.SignedTxnWithAD
+	boxes []string
+}
+
+// collectAppCalls groups the transactions & inner transactions of a block by the app they call,
+// along with the names of that app's boxes referenced anywhere in the same group since
+// box references are shared across a group
+func collectAppCalls(stxns []types.SignedTxnWithAD) map[uint64]*appCalls {
+	calls := map[uint64]*appCalls{}
+	groupBoxes := map[types.Digest]map[uint64][]string{}
+
+	txnsOf := make([][]types.SignedTxnWithAD, len(stxns))
+	for i := range stxns {
+		txnsOf[i] = append([]types.SignedTxnWithAD{stxns[i]}, misc.ListInner(&stxns[i])...)
+
+		group := stxns[i].Txn.Group
+		if _, ok := groupBoxes[group]; !ok || group == (types.Digest{}) {
+			groupBoxes[group] = map[uint64][]string{}
+		}
+
+		for j := range txnsOf[i] {
+			txn := txnsOf[i][j].Txn
+			for _, ref := range txn.BoxReferences {
+				// an empty name only adds to the box io budget
+				if len(ref.Name) == 0 {
+					continue
+				}
+
+				appID := uint64(txn.ApplicationID)
+				if ref.ForeignAppIdx > 0 {
+					if int(ref.ForeignAppIdx) > len(txn.ForeignApps) {
+						continue
+					}
+					appID = uint64(txn.ForeignApps[ref.ForeignAppIdx-1])
+				}
+
+				groupBoxes[group][appID] = append(groupBoxes[group][appID], string(ref.Name))
+			}
+		}
+
+		for j := range txnsOf[i] {
+			txn := txnsOf[i][j].Txn
+			if txn.Type != types.ApplicationCallTx || txn.ApplicationID == 0 {
+				continue
+			}
+
+			appID := uint64(txn.ApplicationID)
+			if _, ok := calls[appID]; !ok {
+				calls[appID] = &appCalls{}
+			}
+			calls[appID].txns = append(calls[appID].txns, txnsOf[i][j])
+		}
+
+		// ungrouped txns only share references within themselves
+		if group == (types.Digest{}) {
+			for appID, names := range groupBoxes[group] {
+				if _, ok := calls[appID]; ok {
+					calls[appID].boxes = append(calls[appID].boxes, names...)
+				}
+			}
+		}
+	}
+
+	for i := range stxns {
+		group := stxns[i].Txn.Group
+		if group == (types.Digest{}) {
+			continue
+		}
+
+		for appID, names := range groupBoxes[group] {
+			if _, ok := calls[appID]; ok {
+				calls[appID].boxes = append(calls[appID].boxes, names...)
+			}
+		}
+		delete(groupBoxes, group)
+	}
+
+	for appID := range calls {
+		calls[appID].boxes = misc.UniqueSlice(calls[appID].boxes)
+	}
+
+	return calls
+}
@@ -0,0 +1,158 @@
+package nfd
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/algorand/go-algorand-sdk/v2/client/v2/common/models"
+	"github.com/algorand/go-algorand-sdk/v2/types"
+)
+
+func TestApplyDelta(t *testing.T) {
+	tests := []struct {
+		name string
+		// deltas are applied in order, the way the calls of a block are
+		deltas []types.StateDelta
+		want   map[string]models.TealValue
+		ok     bool
+	}{
+		{
+			name:   "set bytes & uint",
+			deltas: []types.StateDelta{{"u.project": {Action: types.SetBytesAction, Bytes: "ipfs://new"}, "i.ver": {Action: types.SetUintAction, Uint: 3}}},
+			want: map[string]models.TealValue{
+				"u.project": {Type: uint64(types.TealBytesType), Bytes: "ipfs://new"},
+				"i.ver":     {Type: uint64(types.TealUintType), Uint: 3},
+				"v.caAlgo":  {Type: uint64(types.TealBytesType), Bytes: "addresses"},
+			},
+			ok: true,
+		},
+		{
+			name:   "delete",
+			deltas: []types.StateDelta{{"u.project": {Action: types.DeleteAction}}},
+			want: map[string]models.TealValue{
+				"v.caAlgo": {Type: uint64(types.TealBytesType), Bytes: "addresses"},
+			},
+			ok: true,
+		},
+		{
+			name: "several calls in one block",
+			deltas: []types.StateDelta{
+				{"u.project": {Action: types.SetBytesAction, Bytes: "ipfs://first"}},
+				{"u.project": {Action: types.DeleteAction}, "v.caAlgo": {Action: types.SetBytesAction, Bytes: "others"}},
+				{"u.project": {Action: types.SetBytesAction, Bytes: "ipfs://last"}},
+			},
+			want: map[string]models.TealValue{
+				"u.project": {Type: uint64(types.TealBytesType), Bytes: "ipfs://last"},
+				"v.caAlgo":  {Type: uint64(types.TealBytesType), Bytes: "others"},
+			},
+			ok: true,
+		},
+		{
+			name:   "unknown action",
+			deltas: []types.StateDelta{{"u.project": {Action: 9}}},
+			ok:     false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := newNFDState(10)
+			state.global["u.project"] = models.TealValue{Type: uint64(types.TealBytesType), Bytes: "ipfs://old"}
+			state.global["v.caAlgo"] = models.TealValue{Type: uint64(types.TealBytesType), Bytes: "addresses"}
+
+			ok := true
+			for _, delta := range tt.deltas {
+				ok = ok && state.applyDelta(delta)
+			}
+			if ok != tt.ok {
+				t.Fatalf("applyDelta = %v, want %v", ok, tt.ok)
+			}
+			if ok && fmt.Sprint(state.global) != fmt.Sprint(tt.want) {
+				t.Fatalf("global state %v, want %v", state.global, tt.want)
+			}
+		})
+	}
+}
+
+func appCall(appID uint64, group byte, boxes ...types.BoxReference) types.SignedTxnWithAD {
+	var stxn types.SignedTxnWithAD
+	stxn.Txn.Type = types.ApplicationCallTx
+	stxn.Txn.ApplicationID = types.AppIndex(appID)
+	stxn.Txn.BoxReferences = boxes
+	if group != 0 {
+		stxn.Txn.Group = types.Digest{group}
+	}
+	return stxn
+}
+
+func TestCollectAppCalls(t *testing.T) {
+	foreign := appCall(1, 0, types.BoxReference{ForeignAppIdx: 1, Name: []byte("foreign")}, types.BoxReference{ForeignAppIdx: 2, Name: []byte("out of range")})
+	foreign.Txn.ForeignApps = []types.AppIndex{2}
+
+	inner := appCall(3, 0)
+	inner.EvalDelta.InnerTxns = []types.SignedTxnWithAD{appCall(2, 0, types.BoxReference{Name: []byte("inner")})}
+
+	tests := []struct {
+		name  string
+		stxns []types.SignedTxnWithAD
+		// want maps each called app to how many calls it got & the boxes they could have written
+		want map[uint64]string
+	}{
+		{
+			name:  "box references of the called app",
+			stxns: []types.SignedTxnWithAD{appCall(1, 0, types.BoxReference{Name: []byte("a")}, types.BoxReference{Name: []byte("")})},
+			want:  map[uint64]string{1: "1 [a]"},
+		},
+		{
+			name:  "box references of a foreign app",
+			stxns: []types.SignedTxnWithAD{foreign, appCall(2, 0)},
+			want:  map[uint64]string{1: "1 []", 2: "1 []"},
+		},
+		{
+			name:  "box references of a foreign app it's grouped with",
+			stxns: []types.SignedTxnWithAD{func() types.SignedTxnWithAD { g := foreign; g.Txn.Group = types.Digest{1}; return g }(), appCall(2, 1)},
+			want:  map[uint64]string{1: "1 []", 2: "1 [foreign]"},
+		},
+		{
+			name:  "box references shared across a group",
+			stxns: []types.SignedTxnWithAD{appCall(1, 1, types.BoxReference{Name: []byte("a")}), appCall(1, 1, types.BoxReference{Name: []byte("b")}), appCall(1, 2, types.BoxReference{Name: []byte("c")})},
+			want:  map[uint64]string{1: "3 [a b c]"},
+		},
+		{
+			name:  "ungrouped calls don't share box references",
+			stxns: []types.SignedTxnWithAD{appCall(1, 0, types.BoxReference{Name: []byte("a")}), appCall(2, 0), appCall(2, 0, types.BoxReference{ForeignAppIdx: 0, Name: []byte("b")})},
+			want:  map[uint64]string{1: "1 [a]", 2: "2 [b]"},
+		},
+		{
+			name:  "several calls in one block with inner calls",
+			stxns: []types.SignedTxnWithAD{inner, appCall(2, 0), appCall(3, 0)},
+			want:  map[uint64]string{2: "2 [inner]", 3: "2 []"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := collectAppCalls(tt.stxns)
+
+			got := map[uint64]string{}
+			for appID, c := range calls {
+				sort.Strings(c.boxes)
+				got[appID] = fmt.Sprint(len(c.txns), " ", c.boxes)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Fatalf("calls %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestObserveRound(t *testing.T) {
+	p := &NFDProvider{}
+	for _, round := range []uint64{10, 30, 20} {
+		p.ObserveRound(round)
+	}
+	if p.lastRound.Load() != 30 {
+		t.Fatalf("last round %d, want 30", p.lastRound.Load())
+	}
+}
@@ -0,0 +1,138 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/kylebeee/arc53-watcher-go/db"
+	"github.com/kylebeee/arc53-watcher-go/errors"
+	"github.com/kylebeee/arc53-watcher-go/misc"
+)
+
+const defaultSyncWorkers = 4
+
+// SyncPool runs app syncs through a bounded number of workers, starting at most
+// one sync per interval so catch up doesn't overrun algod
+type SyncPool struct {
+	workers  int
+	interval time.Duration
+}
+
+// NewSyncPool creates a pool, workers below one use the default & an interval
+// of zero doesn't limit the rate
+func NewSyncPool(workers int, interval time.Duration) *SyncPool {
+	if workers < 1 {
+		workers = defaultSyncWorkers
+	}
+
+	return &SyncPool{
+		workers:  workers,
+		interval: interval,
+	}
+}
+
+// Run syncs every app ID & waits for all of them to finish, the errors of the
+// syncs that failed are returned by app ID
+func (p *SyncPool) Run(ctx context.Context, appIDs []uint64, fn func(uint64) error) map[uint64]error {
+	var (
+		wg       sync.WaitGroup
+		lock     sync.Mutex
+		failures = map[uint64]error{}
+		queue    = make(chan uint64)
+	)
+
+	for i := 0; i < p.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for appID := range queue {
+				err := fn(appID)
+				if err != nil {
+					lock.Lock()
+					failures[appID] = err
+					lock.Unlock()
+				}
+			}
+		}()
+	}
+
+	var ticker *time.Ticker
+	if p.interval > 0 {
+		ticker = time.NewTicker(p.interval)
+		defer ticker.Stop()
+	}
+
+dispatch:
+	for _, appID := range appIDs {
+		if ticker != nil {
+			select {
+			case <-ctx.Done():
+				break dispatch
+			case <-ticker.C:
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			break dispatch
+		case queue <- appID:
+		}
+	}
+
+	close(queue)
+	wg.Wait()
+
+	return failures
+}
+
+// RunRecorded is Run for a provider type's apps, apps that fail are recorded as sync
+// failures & apps that sync are cleared of earlier failures, it returns how many synced
+func (p *SyncPool) RunRecorded(ctx context.Context, dbConn *sqlx.DB, providerType string, appIDs []uint64, fn func(uint64) error) (int, error) {
+	const op errors.Op = "SyncPool.RunRecorded"
+
+	failures := p.Run(ctx, appIDs, fn)
+
+	synced := 0
+	for _, appID := range appIDs {
+		err, failed := failures[appID]
+		if failed {
+			fmt.Printf("[CATCHUP] [%s] [ERROR]: %v %s\n", providerType, appID, err)
+			err = db.RecordSyncFailure(dbConn, providerType, appID, err)
+			if err != nil {
+				return synced, errors.E(op, err)
+			}
+			continue
+		}
+
+		synced++
+	}
+
+	// failures are only cleared for apps that actually ran, a cancelled run leaves the rest
+	if ctx.Err() != nil {
+		return synced, nil
+	}
+
+	previous, err := db.GetSyncFailures(dbConn, providerType)
+	if err != nil && !db.ErrNoRows(err) {
+		return synced, errors.E(op, err)
+	}
+
+	if previous != nil {
+		for _, failure := range *previous {
+			_, failed := failures[failure.ID]
+			if failed || !misc.InSlice(failure.ID, appIDs) {
+				continue
+			}
+
+			err = db.DeleteSyncFailure(dbConn, providerType, failure.ID)
+			if err != nil {
+				return synced, errors.E(op, err)
+			}
+		}
+	}
+
+	return synced, nil
+}
@@ -1,22 +1,147 @@
 package providers
 
 import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+
 	"github.com/algorand/go-algorand-sdk/v2/client/v2/algod"
 	"github.com/algorand/go-algorand-sdk/v2/client/v2/indexer"
 	"github.com/algorand/go-algorand-sdk/v2/types"
 	"github.com/jmoiron/sqlx"
-	"github.com/kylebeee/arc53-watcher-go/providers/nfd"
+	"github.com/kylebeee/arc53-watcher-go/errors"
+	"github.com/kylebeee/arc53-watcher-go/misc"
+	"github.com/kylebeee/arc53-watcher-go/providers/community"
 )
 
 type ProviderType interface {
 	Type() string
 	Init(string, *sqlx.DB, *algod.Client) error
 	CatchUp(*sqlx.DB, *algod.Client, uint64, *indexer.Client) error
-	ProcessBlock(stxn types.SignedTxnInBlock, round uint64) error
-	Process(uint64) error
+	// ProcessBlock syncs in btx when it's set so the syncs commit along with the block
+	ProcessBlock(btx *community.BlockTx, stxn types.SignedTxnInBlock, round uint64) error
+	Process(uint64) (*community.Changes, error)
 	IsProviderApp(uint64) bool
 }
 
-var ProviderTypes = []ProviderType{
-	&nfd.NFDProvider{},
+// BlockProcessor is implemented by provider types that handle a block as a whole,
+// ProcessBlockBatch is called once per block in place of ProcessBlock for each transaction
+// so work for apps touched by several transactions can be done once
+type BlockProcessor interface {
+	ProcessBlockBatch(btx *community.BlockTx, block *types.Block, round uint64) error
+}
+
+// RoundObserver is implemented by provider types that want to know how far algod has got,
+// ObserveRound is called with the last round the block stream's nodes report
+type RoundObserver interface {
+	ObserveRound(round uint64)
+}
+
+// Factory builds a provider type from the settings in its config section,
+// settings are nil when the section or its settings are left out
+type Factory func(settings json.RawMessage) (ProviderType, error)
+
+// Config is the config section of a single provider type
+type Config struct {
+	// Enabled turns the provider type on or off, provider types are on unless disabled
+	Enabled *bool `json:"enabled"`
+	// Networks limits the provider type to the listed networks, empty runs it on all of them
+	Networks []string `json:"networks"`
+	// Settings are handed to the provider type's factory as is
+	Settings json.RawMessage `json:"settings"`
+}
+
+// IsEnabled reports whether the provider type should run on the given network
+func (c Config) IsEnabled(network string) bool {
+	if c.Enabled != nil && !*c.Enabled {
+		return false
+	}
+	return len(c.Networks) == 0 || misc.InSlice(network, c.Networks)
+}
+
+var (
+	registry     = map[string]Factory{}
+	registryLock sync.RWMutex
+)
+
+// Register makes a provider type available by name, it's meant to be called from
+// the init function of the provider type's package & panics on duplicate names
+func Register(name string, factory Factory) {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+
+	if factory == nil {
+		panic("providers: Register factory is nil")
+	}
+
+	if _, exists := registry[name]; exists {
+		panic(fmt.Sprintf("providers: Register called twice for provider type %s", name))
+	}
+
+	registry[name] = factory
+}
+
+// Registered lists the names of every registered provider type
+func Registered() []string {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// New builds a registered provider type
+func New(name string, settings json.RawMessage) (ProviderType, error) {
+	const op errors.Op = "New"
+
+	registryLock.RLock()
+	factory, ok := registry[name]
+	registryLock.RUnlock()
+
+	if !ok {
+		return nil, errors.E(op, fmt.Errorf("unknown provider type %s", name))
+	}
+
+	provider, err := factory(settings)
+	if err != nil {
+		return nil, errors.E(op, fmt.Errorf("provider type %s: %w", name, err))
+	}
+
+	return provider, nil
+}
+
+// Build creates every registered provider type enabled for the network,
+// provider types without a config section run with their defaults
+func Build(network string, configs map[string]Config) ([]ProviderType, error) {
+	const op errors.Op = "Build"
+
+	names := Registered()
+	for name := range configs {
+		if !misc.InSlice(name, names) {
+			return nil, errors.E(op, fmt.Errorf("config for unknown provider type %s", name))
+		}
+	}
+
+	providerTypes := []ProviderType{}
+	for _, name := range names {
+		cfg := configs[name]
+		if !cfg.IsEnabled(network) {
+			continue
+		}
+
+		provider, err := New(name, cfg.Settings)
+		if err != nil {
+			return nil, errors.E(op, err)
+		}
+
+		providerTypes = append(providerTypes, provider)
+	}
+
+	return providerTypes, nil
 }
@@ -0,0 +1,393 @@
+package server
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/algorand/go-algorand-sdk/v2/crypto"
+	"github.com/algorand/go-algorand-sdk/v2/types"
+	"github.com/gin-gonic/gin"
+	"github.com/kylebeee/arc53-watcher-go/db"
+	"github.com/kylebeee/arc53-watcher-go/errors"
+	"github.com/kylebeee/arc53-watcher-go/internal/config"
+	"github.com/kylebeee/arc53-watcher-go/misc"
+)
+
+const (
+	apiKeyHeader         = "X-API-Key"
+	timestampHeader      = "X-Timestamp"
+	signatureHeader      = "X-Signature"
+	challengeHeader      = "X-Challenge"
+	ownerAddressHeader   = "X-Owner-Address"
+	ownerSignatureHeader = "X-Owner-Signature"
+)
+
+const (
+	// signatureWindow is how far the timestamp of an HMAC signed request may be from now
+	signatureWindow = 5 * time.Minute
+	// challengeTTL is how long an owner has to sign a challenge
+	challengeTTL = 5 * time.Minute
+	// maxChallenges bounds the challenges waiting to be signed
+	maxChallenges = 10000
+	// maxAppChallenges bounds the challenges waiting to be signed for a single app, asking for
+	// more drops its oldest so requests for one app can't crowd out the others
+	maxAppChallenges = 5
+	// maxSignatures bounds the HMAC signatures remembered to refuse replays
+	maxSignatures = 100000
+)
+
+// authGuard checks who's asking for the routes that make the watcher work, admins hold an
+// API key or the HMAC secret & owners sign a challenge with a verified address of their app
+type authGuard struct {
+	apiKeys    [][]byte
+	hmacSecret []byte
+	ipLimit    *rateLimiter
+	appLimit   *rateLimiter
+	// origins are the lowercased origins allowed to open websockets, anyOrigin allows every one
+	origins   []string
+	anyOrigin bool
+
+	lock       sync.Mutex
+	challenges map[string]ownerChallenge
+	// appChallenges are the pending challenges of each app, oldest first
+	appChallenges map[string][]string
+	// signatures are the HMAC signatures already used & when they fall out of the window
+	signatures map[string]time.Time
+}
+
+// ownerChallenge is a challenge handed out for syncing a single app
+type ownerChallenge struct {
+	providerType string
+	appID        uint64
+	expires      time.Time
+}
+
+func newAuthGuard(cfg config.AuthConfig) *authGuard {
+	g := &authGuard{
+		ipLimit:       newRateLimiter(cfg.RateLimit.PerIP),
+		appLimit:      newRateLimiter(cfg.RateLimit.PerApp),
+		challenges:    map[string]ownerChallenge{},
+		appChallenges: map[string][]string{},
+		signatures:    map[string]time.Time{},
+	}
+	for _, key := range cfg.APIKeys {
+		if key != "" {
+			g.apiKeys = append(g.apiKeys, []byte(key))
+		}
+	}
+	if cfg.HMACSecret != "" {
+		g.hmacSecret = []byte(cfg.HMACSecret)
+	}
+	for _, origin := range cfg.AllowedOrigins {
+		origin = strings.ToLower(strings.TrimRight(strings.TrimSpace(origin), "/"))
+		if origin == "*" {
+			g.anyOrigin = true
+		} else if origin != "" {
+			g.origins = append(g.origins, origin)
+		}
+	}
+
+	return g
+}
+
+// allowOrigin reports whether a websocket may be opened from origin, requests without one
+// don't come from a browser page so there's no other site's page to protect users from
+func (g *authGuard) allowOrigin(origin string) bool {
+	if origin == "" || g.anyOrigin {
+		return true
+	}
+	return misc.InSlice(strings.ToLower(origin), g.origins)
+}
+
+// open reports whether no API key or secret is configured, nobody can be an admin then
+func (g *authGuard) open() bool {
+	return len(g.apiKeys) == 0 && len(g.hmacSecret) == 0
+}
+
+// isAdmin checks a request for a known API key or a valid HMAC signature, the signature is
+// the hex HMAC-SHA256 of the timestamp, method, request uri & body joined by newlines & is
+// only accepted once
+func (g *authGuard) isAdmin(c *gin.Context) bool {
+	if key := c.GetHeader(apiKeyHeader); key != "" {
+		for i := range g.apiKeys {
+			if subtle.ConstantTimeCompare([]byte(key), g.apiKeys[i]) == 1 {
+				return true
+			}
+		}
+		return false
+	}
+
+	signature := c.GetHeader(signatureHeader)
+	if signature == "" || len(g.hmacSecret) == 0 {
+		return false
+	}
+
+	ts, err := strconv.ParseInt(c.GetHeader(timestampHeader), 10, 64)
+	if err != nil {
+		return false
+	}
+	drift := time.Since(time.Unix(ts, 0))
+	if drift > signatureWindow || drift < -signatureWindow {
+		return false
+	}
+
+	expected, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+
+	var body []byte
+	if c.Request.Body != nil {
+		body, err = io.ReadAll(c.Request.Body)
+		if err != nil {
+			return false
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	mac := hmac.New(sha256.New, g.hmacSecret)
+	fmt.Fprintf(mac, "%d\n%s\n%s\n", ts, c.Request.Method, c.Request.URL.RequestURI())
+	mac.Write(body)
+
+	if !hmac.Equal(mac.Sum(nil), expected) {
+		return false
+	}
+
+	return g.useSignature(hex.EncodeToString(expected), time.Unix(ts, 0).Add(signatureWindow))
+}
+
+// useSignature remembers a signature until it expires, reporting whether it wasn't used before
+func (g *authGuard) useSignature(signature string, expires time.Time) bool {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	if _, ok := g.signatures[signature]; ok {
+		return false
+	}
+
+	if len(g.signatures) >= maxSignatures {
+		now := time.Now()
+		for sig, until := range g.signatures {
+			if now.After(until) {
+				delete(g.signatures, sig)
+			}
+		}
+		// a signature that can't be remembered could be replayed, so it's refused
+		if len(g.signatures) >= maxSignatures {
+			return false
+		}
+	}
+
+	g.signatures[signature] = expires
+	return true
+}
+
+// newChallenge hands out a challenge for an owner to sign before syncing their app
+func (g *authGuard) newChallenge(providerType string, appID uint64) (string, time.Time, error) {
+	nonce := make([]byte, 16)
+	_, err := rand.Read(nonce)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	challenge := fmt.Sprintf("arc53-watcher sync %s %d %s", providerType, appID, hex.EncodeToString(nonce))
+	expires := time.Now().Add(challengeTTL)
+	app := challengeApp(providerType, appID)
+
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	if pending := g.appChallenges[app]; len(pending) >= maxAppChallenges {
+		g.dropChallenge(pending[0])
+	}
+
+	if len(g.challenges) >= maxChallenges {
+		now := time.Now()
+		for c, pending := range g.challenges {
+			if now.After(pending.expires) {
+				g.dropChallenge(c)
+			}
+		}
+		if len(g.challenges) >= maxChallenges {
+			return "", time.Time{}, fmt.Errorf("too many pending challenges")
+		}
+	}
+
+	g.challenges[challenge] = ownerChallenge{
+		providerType: providerType,
+		appID:        appID,
+		expires:      expires,
+	}
+	g.appChallenges[app] = append(g.appChallenges[app], challenge)
+
+	return challenge, expires, nil
+}
+
+// challengeApp keys the pending challenges of an app
+func challengeApp(providerType string, appID uint64) string {
+	return fmt.Sprintf("%s:%d", providerType, appID)
+}
+
+// dropChallenge forgets a pending challenge, the lock is held by the caller
+func (g *authGuard) dropChallenge(challenge string) {
+	pending, ok := g.challenges[challenge]
+	if !ok {
+		return
+	}
+	delete(g.challenges, challenge)
+
+	app := challengeApp(pending.providerType, pending.appID)
+	remaining := []string{}
+	for _, c := range g.appChallenges[app] {
+		if c != challenge {
+			remaining = append(remaining, c)
+		}
+	}
+	if len(remaining) == 0 {
+		delete(g.appChallenges, app)
+	} else {
+		g.appChallenges[app] = remaining
+	}
+}
+
+// takeChallenge removes a challenge so it can only be used once, reporting whether it was
+// handed out for the app & hasn't expired
+func (g *authGuard) takeChallenge(challenge string, providerType string, appID uint64) bool {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	pending, ok := g.challenges[challenge]
+	if !ok {
+		return false
+	}
+	g.dropChallenge(challenge)
+
+	return pending.providerType == providerType && pending.appID == appID && time.Now().Before(pending.expires)
+}
+
+// isOwner checks that a request carries a challenge for its app signed by one of the app's
+// verified addresses, the signature is over the challenge as algosdk's signBytes makes it
+func (s *Arc53WatcherServer) isOwner(c *gin.Context) (bool, error) {
+	const op errors.Op = "isOwner"
+
+	challenge := c.GetHeader(challengeHeader)
+	address := c.GetHeader(ownerAddressHeader)
+	signature, err := base64.StdEncoding.DecodeString(c.GetHeader(ownerSignatureHeader))
+	if challenge == "" || address == "" || err != nil || len(signature) == 0 {
+		return false, nil
+	}
+
+	providerType := c.Param("providerType")
+	appID, err := strconv.ParseUint(c.Param("appID"), 10, 64)
+	if err != nil {
+		return false, nil
+	}
+
+	if !s.auth.takeChallenge(challenge, providerType, appID) {
+		return false, nil
+	}
+
+	provider, err := db.GetProvider(s.DB, appID)
+	if err != nil && !db.ErrNoRows(err) {
+		return false, errors.E(op, err)
+	} else if db.ErrNoRows(err) || provider.Type != providerType {
+		return false, nil
+	}
+
+	addresses, err := db.GetProviderAddresses(s.DB, appID)
+	if err != nil && !db.ErrNoRows(err) {
+		return false, errors.E(op, err)
+	} else if db.ErrNoRows(err) {
+		return false, nil
+	}
+
+	verified := false
+	for _, a := range *addresses {
+		if a.Address == address && a.Verified != nil && *a.Verified {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return false, nil
+	}
+
+	decoded, err := types.DecodeAddress(address)
+	if err != nil {
+		return false, nil
+	}
+
+	return crypto.VerifyBytes(decoded[:], []byte(challenge), signature), nil
+}
+
+// requireAdmin only lets admin requests through
+func (s *Arc53WatcherServer) requireAdmin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if s.auth.isAdmin(c) {
+			c.Next()
+			return
+		}
+
+		c.AbortWithStatusJSON(401, gin.H{
+			"ok":    false,
+			"error": "unauthorized",
+		})
+	}
+}
+
+// requireAdminOrOwner lets admin requests through along with owners of the requested app
+func (s *Arc53WatcherServer) requireAdminOrOwner() gin.HandlerFunc {
+	const op errors.Op = "requireAdminOrOwner"
+
+	return func(c *gin.Context) {
+		if s.auth.isAdmin(c) {
+			c.Next()
+			return
+		}
+
+		owner, err := s.isOwner(c)
+		if err != nil {
+			err = errors.E(op, err)
+			fmt.Print(err)
+			c.AbortWithStatusJSON(500, gin.H{
+				"ok":    false,
+				"error": "internal server error",
+			})
+			return
+		}
+		if !owner {
+			c.AbortWithStatusJSON(401, gin.H{
+				"ok":    false,
+				"error": "unauthorized",
+			})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// limitIP rate limits requests by client IP
+func (s *Arc53WatcherServer) limitIP() gin.HandlerFunc {
+	return limit(s.auth.ipLimit, func(c *gin.Context) string {
+		return c.ClientIP()
+	})
+}
+
+// limitApp rate limits requests by the app they're for
+func (s *Arc53WatcherServer) limitApp() gin.HandlerFunc {
+	return limit(s.auth.appLimit, func(c *gin.Context) string {
+		return c.Param("providerType") + ":" + c.Param("appID")
+	})
+}
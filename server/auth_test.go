@@ -0,0 +1,171 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kylebeee/arc53-watcher-go/internal/config"
+)
+
+// signedRequest signs a request the way the README tells admins to
+func signedRequest(secret string, ts time.Time, method, uri string) *http.Request {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d\n%s\n%s\n", ts.Unix(), method, uri)
+
+	req := httptest.NewRequest(method, uri, nil)
+	req.Header.Set(timestampHeader, strconv.FormatInt(ts.Unix(), 10))
+	req.Header.Set(signatureHeader, hex.EncodeToString(mac.Sum(nil)))
+	return req
+}
+
+func TestRequireAdmin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	s := &Arc53WatcherServer{auth: newAuthGuard(config.AuthConfig{APIKeys: []string{"key"}, HMACSecret: "secret"})}
+	r := gin.New()
+	r.POST("/cursor/rewind/:round", s.requireAdmin(), func(c *gin.Context) { c.Status(200) })
+
+	replayed := signedRequest("secret", time.Now(), "POST", "/cursor/rewind/10")
+
+	tests := []struct {
+		name string
+		req  func() *http.Request
+		want int
+	}{
+		{
+			name: "no credentials",
+			req:  func() *http.Request { return httptest.NewRequest("POST", "/cursor/rewind/10", nil) },
+			want: 401,
+		},
+		{
+			name: "api key",
+			req: func() *http.Request {
+				req := httptest.NewRequest("POST", "/cursor/rewind/10", nil)
+				req.Header.Set(apiKeyHeader, "key")
+				return req
+			},
+			want: 200,
+		},
+		{
+			name: "wrong api key",
+			req: func() *http.Request {
+				req := httptest.NewRequest("POST", "/cursor/rewind/10", nil)
+				req.Header.Set(apiKeyHeader, "nope")
+				return req
+			},
+			want: 401,
+		},
+		{
+			name: "signed",
+			req:  func() *http.Request { return replayed.Clone(replayed.Context()) },
+			want: 200,
+		},
+		{
+			name: "same signature replayed",
+			req:  func() *http.Request { return replayed.Clone(replayed.Context()) },
+			want: 401,
+		},
+		{
+			name: "signed with the wrong secret",
+			req:  func() *http.Request { return signedRequest("other", time.Now(), "POST", "/cursor/rewind/10") },
+			want: 401,
+		},
+		{
+			name: "signed outside the window",
+			req: func() *http.Request {
+				return signedRequest("secret", time.Now().Add(-2*signatureWindow), "POST", "/cursor/rewind/10")
+			},
+			want: 401,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, tt.req())
+			if w.Code != tt.want {
+				t.Fatalf("status %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestAdminRoutesNotServedWithoutCredentials(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	s := &Arc53WatcherServer{auth: newAuthGuard(config.AuthConfig{})}
+	r := gin.New()
+	s.routes(r)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("POST", "/cursor/rewind/10", nil))
+	if w.Code != 404 {
+		t.Fatalf("rewind status %d without credentials configured, want 404", w.Code)
+	}
+}
+
+func TestChallengesPerApp(t *testing.T) {
+	g := newAuthGuard(config.AuthConfig{})
+
+	// asking for more challenges than an app may hold drops its oldest
+	issued := []string{}
+	for i := 0; i < maxAppChallenges+2; i++ {
+		challenge, _, err := g.newChallenge("nfd", 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		issued = append(issued, challenge)
+	}
+	other, _, err := g.newChallenge("nfd", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(g.appChallenges["nfd:1"]) != maxAppChallenges {
+		t.Fatalf("app 1 holds %d challenges, want %d", len(g.appChallenges["nfd:1"]), maxAppChallenges)
+	}
+	for i, challenge := range issued {
+		dropped := i < 2
+		if g.takeChallenge(challenge, "nfd", 1) == dropped {
+			t.Fatalf("challenge %d taken = %v, want %v", i, dropped, !dropped)
+		}
+	}
+	if !g.takeChallenge(other, "nfd", 2) {
+		t.Fatal("app 2's challenge was dropped by app 1's")
+	}
+	if len(g.challenges) != 0 || len(g.appChallenges) != 0 {
+		t.Fatalf("%d challenges & %d apps left after taking them all", len(g.challenges), len(g.appChallenges))
+	}
+}
+
+func TestLookupAssetsLimits(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	s := &Arc53WatcherServer{auth: newAuthGuard(config.AuthConfig{RateLimit: config.RateLimitConfig{PerIP: &config.QuotaConfig{Requests: 1, Seconds: 60}}})}
+	r := gin.New()
+	s.routes(r)
+
+	ids := make([]string, 101)
+	for i := range ids {
+		ids[i] = strconv.Itoa(i + 1)
+	}
+	body := `{"asset_ids": [` + strings.Join(ids, ",") + `]}`
+
+	// the oversized batch is turned away before any lookups & still counts against the client's quota
+	for _, want := range []int{400, 429} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("POST", "/v1/assets/lookup", strings.NewReader(body)))
+		if w.Code != want {
+			t.Fatalf("status %d, want %d", w.Code, want)
+		}
+	}
+}
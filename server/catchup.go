@@ -0,0 +1,98 @@
+package server
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/algorand/go-algorand-sdk/v2/client/v2/algod"
+	"github.com/kylebeee/arc53-watcher-go/errors"
+	streamer "github.com/kylebeee/arc53-watcher-go/internal/algod"
+	"github.com/kylebeee/arc53-watcher-go/internal/config"
+)
+
+// scanCatchUp catches providers up without the indexer by running every block from
+// fromRound through processBlock, read from algod or from a block archive, it returns
+// the round the watcher should continue from
+func (s *Arc53WatcherServer) scanCatchUp(cfg config.CatchupConfig, fromRound uint64) (uint64, error) {
+	const op errors.Op = "scanCatchUp"
+
+	ctx := context.Background()
+
+	process := func(b *streamer.BlockWrap) error {
+		return s.processBlock(ctx, b)
+	}
+
+	if fromRound == 0 {
+		fromRound = cfg.FirstRound
+	}
+
+	if cfg.Archive != "" {
+		rounds, err := streamer.ArchiveRounds(cfg.Archive)
+		if err != nil {
+			return fromRound, errors.E(op, err)
+		}
+		if len(rounds) == 0 {
+			return fromRound, errors.E(op, fmt.Errorf("block archive %s is empty", cfg.Archive))
+		}
+
+		if fromRound == 0 {
+			fromRound = rounds[0]
+		}
+
+		lastRound := cfg.LastRound
+		if lastRound == 0 {
+			lastRound = rounds[len(rounds)-1]
+		}
+
+		if fromRound > lastRound {
+			return fromRound, nil
+		}
+
+		fmt.Printf("[CATCHUP] scanning blocks %d - %d from %s\n", fromRound, lastRound, cfg.Archive)
+		err = streamer.ScanArchive(ctx, cfg.Archive, fromRound, lastRound, process)
+		if err != nil {
+			return fromRound, errors.E(op, err)
+		}
+
+		return lastRound + 1, nil
+	}
+
+	if fromRound == 0 {
+		return fromRound, errors.E(op, fmt.Errorf("block catch up needs a first_round when there's no cursor or provider round"))
+	}
+
+	nodes := cfg.Nodes
+	if len(nodes) == 0 {
+		nodes = s.algodConfig.ANodes
+	}
+
+	lastRound := cfg.LastRound
+	if lastRound == 0 {
+		client, err := algod.MakeClient(nodes[0].Address, nodes[0].Token)
+		if err != nil {
+			return fromRound, errors.E(op, err)
+		}
+
+		status, err := client.Status().Do(ctx)
+		if err != nil {
+			return fromRound, errors.E(op, errors.Network, err)
+		}
+		lastRound = status.LastRound
+	}
+
+	if fromRound > lastRound {
+		return fromRound, nil
+	}
+
+	fmt.Printf("[CATCHUP] scanning blocks %d - %d from algod\n", fromRound, lastRound)
+	err := streamer.ScanBlocks(ctx, &streamer.AlgoConfig{
+		FRound: int64(fromRound),
+		LRound: int64(lastRound),
+		ANodes: nodes,
+	}, process)
+	if err != nil {
+		return fromRound, errors.E(op, err)
+	}
+
+	return lastRound + 1, nil
+}
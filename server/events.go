@@ -0,0 +1,369 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kylebeee/arc53-watcher-go/db"
+	"github.com/kylebeee/arc53-watcher-go/errors"
+	"golang.org/x/net/websocket"
+)
+
+const (
+	// eventPollInterval is how often the outbox is checked for new events
+	eventPollInterval = 500 * time.Millisecond
+	// eventPage is how many events are read from the outbox at once
+	eventPage = 500
+	// subscriberBuffer is how many events a subscriber can fall behind before it's dropped
+	subscriberBuffer = 256
+	// maxSubscribers bounds the open event streams
+	maxSubscribers = 1000
+	// eventKeepAlive is how often an idle stream is pinged
+	eventKeepAlive = 15 * time.Second
+)
+
+// errSlowSubscriber ends a stream that fell too far behind, the client resumes from the last event it got
+var errSlowSubscriber = fmt.Errorf("subscriber fell behind")
+
+// eventBus fans the events written to the outbox out to the open streams
+type eventBus struct {
+	lock        sync.Mutex
+	subscribers map[chan db.Event]eventFilter
+	// last is the ID of the last event published
+	last uint64
+}
+
+func newEventBus() *eventBus {
+	return &eventBus{subscribers: map[chan db.Event]eventFilter{}}
+}
+
+// subscribe opens a stream of the published events matching filter, along with the ID of the
+// last event published before it so the outbox can be read up to there without a gap
+func (b *eventBus) subscribe(filter eventFilter) (chan db.Event, uint64, bool) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if len(b.subscribers) >= maxSubscribers {
+		return nil, 0, false
+	}
+
+	events := make(chan db.Event, subscriberBuffer)
+	b.subscribers[events] = filter
+
+	return events, b.last, true
+}
+
+func (b *eventBus) unsubscribe(events chan db.Event) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if _, ok := b.subscribers[events]; ok {
+		delete(b.subscribers, events)
+		close(events)
+	}
+}
+
+// publish hands an event to every subscriber it matches, subscribers that are full are dropped
+func (b *eventBus) publish(event db.Event) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	for events, filter := range b.subscribers {
+		if !filter.match(&event) {
+			continue
+		}
+
+		select {
+		case events <- event:
+		default:
+			delete(b.subscribers, events)
+			close(events)
+		}
+	}
+	b.last = event.ID
+}
+
+// publishEvents reads new events from the outbox & publishes them until ctx is done
+func (s *Arc53WatcherServer) publishEvents(ctx context.Context) {
+	ticker := time.NewTicker(eventPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		err := s.publishNewEvents()
+		if err != nil {
+			fmt.Println(err)
+		}
+	}
+}
+
+// publishNewEvents publishes a page of the events after the last one published, events are
+// committed in ID order so the newest visible event is older than any still uncommitted &
+// nothing published can be followed by an older event
+func (s *Arc53WatcherServer) publishNewEvents() error {
+	const op errors.Op = "publishNewEvents"
+
+	s.events.lock.Lock()
+	last := s.events.last
+	s.events.lock.Unlock()
+
+	events, err := db.GetEventsAfter(s.DB, last, eventPage)
+	if err != nil {
+		return errors.E(op, err)
+	}
+
+	for i := range *events {
+		s.events.publish((*events)[i])
+	}
+
+	return nil
+}
+
+// streamEvents sends a subscriber the events after lastID that match filter, first from the outbox
+// up to the last event published when it subscribed & then as they're published, a nil event is a
+// keep alive. It returns once ctx is done or sending fails
+func (s *Arc53WatcherServer) streamEvents(ctx context.Context, live chan db.Event, published uint64, lastID *uint64, filter eventFilter, send func(*db.Event) error) error {
+	const op errors.Op = "streamEvents"
+
+	// without a last event ID the stream starts from what's published next
+	after := published
+	if lastID != nil {
+		after = *lastID
+	}
+
+	// catch up from the outbox, events read here that are also published are skipped below
+	for after < published {
+		events, err := db.GetEventsAfter(s.DB, after, eventPage)
+		if err != nil && !db.ErrNoRows(err) {
+			return errors.E(op, err)
+		}
+		if events == nil || len(*events) == 0 {
+			break
+		}
+
+		for i := range *events {
+			event := (*events)[i]
+			after = event.ID
+			if !filter.match(&event) {
+				continue
+			}
+
+			err = send(&event)
+			if err != nil {
+				return errors.E(op, err)
+			}
+		}
+	}
+
+	keepAlive := time.NewTicker(eventKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-live:
+			if !ok {
+				return errors.E(op, errSlowSubscriber)
+			}
+			if event.ID <= after {
+				continue
+			}
+			after = event.ID
+
+			err := send(&event)
+			if err != nil {
+				return errors.E(op, err)
+			}
+		case <-keepAlive.C:
+			err := send(nil)
+			if err != nil {
+				return errors.E(op, err)
+			}
+		}
+	}
+}
+
+// eventStreamRequest holds the filters & resume point of an event stream
+type eventStreamRequest struct {
+	ProviderID  string `form:"provider_id"`
+	Type        string `form:"type"`
+	LastEventID string `form:"last_event_id"`
+}
+
+// parse reads the stream's filter & last event ID, a Last-Event-ID header wins over the query
+func (r eventStreamRequest) parse(lastEventHeader string) (eventFilter, *uint64, error) {
+	filter, err := parseEventFilter(r.ProviderID, r.Type)
+	if err != nil {
+		return filter, nil, err
+	}
+
+	raw := r.LastEventID
+	if lastEventHeader != "" {
+		raw = lastEventHeader
+	}
+	lastID, err := parseLastEventID(raw)
+	if err != nil {
+		return filter, nil, err
+	}
+
+	return filter, lastID, nil
+}
+
+// handleStreamEvents streams events as server-sent events, browsers resume with Last-Event-ID
+func (s *Arc53WatcherServer) handleStreamEvents() gin.HandlerFunc {
+	const op errors.Op = "handleStreamEvents"
+
+	type response struct {
+		Ok    bool   `json:"ok"`
+		Error string `json:"error,omitempty"`
+	}
+
+	return func(c *gin.Context) {
+		var (
+			req  eventStreamRequest
+			resp response
+			err  error
+		)
+
+		err = c.ShouldBindQuery(&req)
+		if err != nil {
+			resp.Error = "bad request"
+			c.JSON(400, resp)
+			return
+		}
+
+		filter, lastID, err := req.parse(c.GetHeader("Last-Event-ID"))
+		if err != nil {
+			resp.Error = err.Error()
+			c.JSON(400, resp)
+			return
+		}
+
+		live, published, ok := s.events.subscribe(filter)
+		if !ok {
+			resp.Error = "too many subscribers"
+			c.JSON(503, resp)
+			return
+		}
+		defer s.events.unsubscribe(live)
+
+		c.Header("Content-Type", "text/event-stream")
+		c.Header("Cache-Control", "no-cache")
+		c.Header("Connection", "keep-alive")
+		// stops nginx from buffering the stream
+		c.Header("X-Accel-Buffering", "no")
+		c.Status(200)
+		c.Writer.Flush()
+
+		ctx := c.Request.Context()
+		err = s.streamEvents(ctx, live, published, lastID, filter, func(event *db.Event) error {
+			if event == nil {
+				_, err := io.WriteString(c.Writer, ": ping\n\n")
+				c.Writer.Flush()
+				return err
+			}
+
+			data, err := json.Marshal(event)
+			if err != nil {
+				return err
+			}
+			_, err = fmt.Fprintf(c.Writer, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+			c.Writer.Flush()
+			return err
+		})
+		if err != nil && ctx.Err() == nil {
+			fmt.Println(errors.E(op, err))
+		}
+	}
+}
+
+// handleStreamEventsWS streams events over a websocket as json messages, resuming from last_event_id
+func (s *Arc53WatcherServer) handleStreamEventsWS() gin.HandlerFunc {
+	const op errors.Op = "handleStreamEventsWS"
+
+	type response struct {
+		Ok    bool   `json:"ok"`
+		Error string `json:"error,omitempty"`
+	}
+
+	return func(c *gin.Context) {
+		var (
+			req  eventStreamRequest
+			resp response
+			err  error
+		)
+
+		err = c.ShouldBindQuery(&req)
+		if err != nil {
+			resp.Error = "bad request"
+			c.JSON(400, resp)
+			return
+		}
+
+		filter, lastID, err := req.parse("")
+		if err != nil {
+			resp.Error = err.Error()
+			c.JSON(400, resp)
+			return
+		}
+
+		// websockets aren't bound by CORS, any page a browser has open could stream otherwise
+		if !s.auth.allowOrigin(c.GetHeader("Origin")) {
+			resp.Error = "origin not allowed"
+			c.JSON(403, resp)
+			return
+		}
+
+		live, published, ok := s.events.subscribe(filter)
+		if !ok {
+			resp.Error = "too many subscribers"
+			c.JSON(503, resp)
+			return
+		}
+		defer s.events.unsubscribe(live)
+
+		ws := websocket.Server{
+			// the origin is checked against the allowlist above
+			Handshake: func(*websocket.Config, *http.Request) error { return nil },
+			Handler: func(conn *websocket.Conn) {
+				ctx, cancel := context.WithCancel(c.Request.Context())
+				defer cancel()
+
+				// nothing is read from clients, reading only notices when they go away
+				go func() {
+					var discard []byte
+					for {
+						err := websocket.Message.Receive(conn, &discard)
+						if err != nil {
+							cancel()
+							return
+						}
+					}
+				}()
+
+				err := s.streamEvents(ctx, live, published, lastID, filter, func(event *db.Event) error {
+					if event == nil {
+						return websocket.JSON.Send(conn, gin.H{"type": "ping"})
+					}
+					return websocket.JSON.Send(conn, event)
+				})
+				if err != nil && ctx.Err() == nil {
+					fmt.Println(errors.E(op, err))
+				}
+			},
+		}
+		ws.ServeHTTP(c.Writer, c.Request)
+	}
+}
@@ -0,0 +1,204 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/jmoiron/sqlx"
+	"github.com/kylebeee/arc53-watcher-go/db"
+	"github.com/kylebeee/arc53-watcher-go/internal/config"
+)
+
+const eventsAfterQuery = "select id,provider_id,type,subject,round,created from event where id > ? order by id asc limit ?"
+
+func eventRows(ids ...uint64) *sqlmock.Rows {
+	rows := sqlmock.NewRows(db.EventTableKeys())
+	for _, id := range ids {
+		rows.AddRow(id, 1, db.EventCommunityUpdated, "", 10, 1000)
+	}
+	return rows
+}
+
+func mockServer(t *testing.T) (*Arc53WatcherServer, sqlmock.Sqlmock) {
+	t.Helper()
+
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return &Arc53WatcherServer{DB: sqlx.NewDb(conn, "mysql"), events: newEventBus()}, mock
+}
+
+func TestStreamEventsResume(t *testing.T) {
+	u := func(id uint64) *uint64 { return &id }
+
+	tests := []struct {
+		name      string
+		published uint64
+		lastID    *uint64
+		// outbox is what the outbox holds after lastID when the stream catches up, nil when it doesn't read it
+		outbox []uint64
+		// live is what's published while the stream is open
+		live []uint64
+		want []uint64
+	}{
+		{
+			name:      "resumes from the outbox & hands over to live events",
+			published: 6,
+			lastID:    u(3),
+			outbox:    []uint64{4, 5, 6},
+			live:      []uint64{7, 8},
+			want:      []uint64{4, 5, 6, 7, 8},
+		},
+		{
+			name:      "events committed while catching up aren't sent twice",
+			published: 6,
+			lastID:    u(3),
+			outbox:    []uint64{4, 5, 6, 7},
+			live:      []uint64{7, 8},
+			want:      []uint64{4, 5, 6, 7, 8},
+		},
+		{
+			name:      "without a last event ID starts from what's published next",
+			published: 6,
+			live:      []uint64{7, 8},
+			want:      []uint64{7, 8},
+		},
+		{
+			name:      "up to date with what's published",
+			published: 6,
+			lastID:    u(6),
+			live:      []uint64{7},
+			want:      []uint64{7},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, mock := mockServer(t)
+			if tt.outbox != nil {
+				mock.ExpectQuery(regexp.QuoteMeta(eventsAfterQuery)).WithArgs(*tt.lastID, eventPage).WillReturnRows(eventRows(tt.outbox...))
+			}
+
+			live := make(chan db.Event, len(tt.live))
+			for _, id := range tt.live {
+				live <- db.Event{ID: id, ProviderID: 1, Type: db.EventCommunityUpdated}
+			}
+			close(live)
+
+			got := []uint64{}
+			err := s.streamEvents(context.Background(), live, tt.published, tt.lastID, eventFilter{}, func(event *db.Event) error {
+				if event != nil {
+					got = append(got, event.ID)
+				}
+				return nil
+			})
+			// the closed live channel ends the stream the way a dropped subscriber's does
+			if err == nil {
+				t.Fatal("stream ended without an error once live events stopped")
+			}
+
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Fatalf("sent %v, want %v", got, tt.want)
+			}
+			err = mock.ExpectationsWereMet()
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestPublishNewEvents(t *testing.T) {
+	s, mock := mockServer(t)
+	s.events.last = 5
+
+	mock.ExpectQuery(regexp.QuoteMeta(eventsAfterQuery)).WithArgs(5, eventPage).WillReturnRows(eventRows(6, 7))
+	mock.ExpectQuery(regexp.QuoteMeta(eventsAfterQuery)).WithArgs(7, eventPage).WillReturnRows(eventRows())
+	mock.ExpectQuery(regexp.QuoteMeta(eventsAfterQuery)).WithArgs(7, eventPage).WillReturnRows(eventRows(8))
+
+	live, published, ok := s.events.subscribe(eventFilter{})
+	if !ok {
+		t.Fatal("subscribing failed")
+	}
+	if published != 5 {
+		t.Fatalf("subscribed after event %d, want 5", published)
+	}
+
+	for i := 0; i < 3; i++ {
+		err := s.publishNewEvents()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	s.events.unsubscribe(live)
+
+	got := []uint64{}
+	for event := range live {
+		got = append(got, event.ID)
+	}
+	if fmt.Sprint(got) != fmt.Sprint([]uint64{6, 7, 8}) {
+		t.Fatalf("published %v, want 6 - 8", got)
+	}
+	if s.events.last != 8 {
+		t.Fatalf("last published %d, want 8", s.events.last)
+	}
+	err := mock.ExpectationsWereMet()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStreamEventsWSOrigins(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name    string
+		allowed []string
+		origin  string
+		// want is 403 when the origin is refused, the plain requests are otherwise refused by the websocket handshake
+		want int
+	}{
+		{name: "listed origin", allowed: []string{"https://example.com/"}, origin: "https://EXAMPLE.com", want: 400},
+		{name: "unlisted origin", allowed: []string{"https://example.com"}, origin: "https://evil.example", want: 403},
+		{name: "no origins allowed", origin: "https://example.com", want: 403},
+		{name: "any origin", allowed: []string{"*"}, origin: "https://evil.example", want: 400},
+		{name: "not from a browser", origin: "", want: 400},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _ := mockServer(t)
+			s.auth = newAuthGuard(config.AuthConfig{AllowedOrigins: tt.allowed})
+			r := gin.New()
+			r.GET("/events/ws", s.handleStreamEventsWS())
+			// the handshake takes over the connection, so it has to be a real one
+			srv := httptest.NewServer(r)
+			defer srv.Close()
+
+			req, err := http.NewRequest("GET", srv.URL+"/events/ws", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			res, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res.Body.Close()
+			if res.StatusCode != tt.want {
+				t.Fatalf("status %d, want %d", res.StatusCode, tt.want)
+			}
+		})
+	}
+}
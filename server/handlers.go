@@ -1,14 +1,23 @@
 package server
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"net/url"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/kylebeee/arc53-watcher-go/arc53"
 	"github.com/kylebeee/arc53-watcher-go/db"
 	"github.com/kylebeee/arc53-watcher-go/db/compound"
 	"github.com/kylebeee/arc53-watcher-go/errors"
+	streamer "github.com/kylebeee/arc53-watcher-go/internal/algod"
+	"github.com/kylebeee/arc53-watcher-go/misc"
 	"github.com/kylebeee/arc53-watcher-go/providers"
+	"github.com/kylebeee/arc53-watcher-go/uuid"
 )
 
 func (s *Arc53WatcherServer) handleHealthCheck() gin.HandlerFunc {
@@ -19,8 +28,8 @@ func (s *Arc53WatcherServer) handleHealthCheck() gin.HandlerFunc {
 	}
 }
 
-func (s *Arc53WatcherServer) handleGetARC53Data() gin.HandlerFunc {
-	const op errors.Op = "handleGetARC53Data"
+func (s *Arc53WatcherServer) handleGetCommunity() gin.HandlerFunc {
+	const op errors.Op = "handleGetCommunity"
 
 	type request struct {
 		AppID string `uri:"appID" binding:"required"`
@@ -56,7 +65,14 @@ func (s *Arc53WatcherServer) handleGetARC53Data() gin.HandlerFunc {
 			return
 		}
 
-		resp.Community, err = compound.GetCommunity(s.DB, appID)
+		exclude, err := parseExclude[compound.CommunityGetExclude](c.Query("exclude"))
+		if err != nil {
+			resp.Error = err.Error()
+			c.JSON(400, resp)
+			return
+		}
+
+		resp.Community, err = compound.GetCommunity(s.DB, appID, exclude...)
 		if err != nil && !db.ErrNoRows(err) {
 			err = errors.E(op, err)
 			fmt.Print(err)
@@ -68,20 +84,88 @@ func (s *Arc53WatcherServer) handleGetARC53Data() gin.HandlerFunc {
 			c.JSON(404, resp)
 			return
 		}
+
+		c.JSON(200, resp)
 	}
 }
 
-func (s *Arc53WatcherServer) handleSyncByProviderID() gin.HandlerFunc {
-	const op errors.Op = "handleSyncByProviderID"
+func (s *Arc53WatcherServer) handleListCommunities() gin.HandlerFunc {
+	const op errors.Op = "handleListCommunities"
 
 	type request struct {
-		ProviderType string `uri:"providerType" binding:"required"`
-		AppID        string `uri:"appID" binding:"required"`
+		Page    uint64 `form:"page"`
+		Limit   uint64 `form:"limit"`
+		Exclude string `form:"exclude"`
 	}
 
 	type response struct {
-		Ok    bool   `json:"ok"`
-		Error string `json:"error,omitempty"`
+		Communities []compound.Community `json:"communities"`
+		Page        uint64               `json:"page,omitempty"`
+		Limit       uint64               `json:"limit,omitempty"`
+		Error       string               `json:"error,omitempty"`
+	}
+
+	return func(c *gin.Context) {
+		var (
+			req  request
+			resp response
+			err  error
+		)
+
+		err = c.ShouldBindQuery(&req)
+		if err != nil {
+			resp.Error = "bad request"
+			c.JSON(400, resp)
+			return
+		}
+
+		exclude, err := parseExclude[compound.CommunityGetExclude](req.Exclude)
+		if err != nil {
+			resp.Error = err.Error()
+			c.JSON(400, resp)
+			return
+		}
+
+		resp.Page, resp.Limit = pagination(req.Page, req.Limit)
+
+		communities, err := db.GetCommunities(s.DB, (resp.Page-1)*resp.Limit, resp.Limit)
+		if err != nil && !db.ErrNoRows(err) {
+			err = errors.E(op, err)
+			fmt.Print(err)
+			resp.Error = "internal server error"
+			c.JSON(500, resp)
+			return
+		}
+
+		resp.Communities = []compound.Community{}
+		if communities != nil {
+			resp.Communities, err = compound.GetCommunities(s.DB, *communities, exclude...)
+			if err != nil {
+				err = errors.E(op, err)
+				fmt.Print(err)
+				resp.Error = "internal server error"
+				c.JSON(500, resp)
+				return
+			}
+		}
+
+		c.JSON(200, resp)
+	}
+}
+
+func (s *Arc53WatcherServer) handleGetCommunityStatus() gin.HandlerFunc {
+	const op errors.Op = "handleGetCommunityStatus"
+
+	type request struct {
+		AppID string `uri:"appID" binding:"required"`
+	}
+
+	type response struct {
+		ID               uint64                  `json:"id,omitempty"`
+		Malformed        bool                    `json:"malformed"`
+		CIDMismatch      bool                    `json:"cid_mismatch"`
+		ValidationErrors []arc53.ValidationError `json:"validation_errors"`
+		Error            string                  `json:"error,omitempty"`
 	}
 
 	return func(c *gin.Context) {
@@ -100,19 +184,75 @@ func (s *Arc53WatcherServer) handleSyncByProviderID() gin.HandlerFunc {
 			return
 		}
 
-		providerTypeMap := map[string]providers.ProviderType{}
-
-		for i := range s.ProviderTypes {
-			providerTypeMap[s.ProviderTypes[i].Type()] = s.ProviderTypes[i]
+		appID, err := strconv.ParseUint(req.AppID, 10, 64)
+		if err != nil {
+			err = errors.E(op, err)
+			fmt.Print(err)
+			resp.Error = "bad request"
+			c.JSON(400, resp)
+			return
 		}
 
-		provider, ok := providerTypeMap[req.ProviderType]
-		if !ok {
-			resp.Error = "provider not found"
+		commJson, err := db.GetCommunityJson(s.DB, appID)
+		if err != nil && !db.ErrNoRows(err) {
+			err = errors.E(op, err)
+			fmt.Print(err)
+			resp.Error = "internal server error"
+			c.JSON(500, resp)
+			return
+		} else if db.ErrNoRows(err) {
+			resp.Error = "not found"
 			c.JSON(404, resp)
 			return
 		}
 
+		resp.ID = commJson.ID
+		resp.Malformed = commJson.Malformed != nil && *commJson.Malformed
+		resp.CIDMismatch = commJson.CIDMismatch != nil && *commJson.CIDMismatch
+		resp.ValidationErrors = []arc53.ValidationError{}
+		if commJson.ValidationErrors != nil {
+			err = json.Unmarshal([]byte(*commJson.ValidationErrors), &resp.ValidationErrors)
+			if err != nil {
+				err = errors.E(op, err)
+				fmt.Print(err)
+				resp.Error = "internal server error"
+				c.JSON(500, resp)
+				return
+			}
+		}
+
+		c.JSON(200, resp)
+	}
+}
+
+func (s *Arc53WatcherServer) handleGetCommunityCollections() gin.HandlerFunc {
+	const op errors.Op = "handleGetCommunityCollections"
+
+	type request struct {
+		AppID string `uri:"appID" binding:"required"`
+	}
+
+	type response struct {
+		Collections []compound.Collection `json:"collections"`
+		Error       string                `json:"error,omitempty"`
+	}
+
+	return func(c *gin.Context) {
+		var (
+			req  request
+			resp response
+			err  error
+		)
+
+		err = c.ShouldBindUri(&req)
+		if err != nil {
+			c.JSON(400, gin.H{
+				"ok":    false,
+				"error": err.Error(),
+			})
+			return
+		}
+
 		appID, err := strconv.ParseUint(req.AppID, 10, 64)
 		if err != nil {
 			err = errors.E(op, err)
@@ -122,10 +262,1063 @@ func (s *Arc53WatcherServer) handleSyncByProviderID() gin.HandlerFunc {
 			return
 		}
 
-		c.JSON(200, gin.H{
-			"ok": true,
-		})
+		exclude, err := parseExclude[compound.CollectionGetExclude](c.Query("exclude"))
+		if err != nil {
+			resp.Error = err.Error()
+			c.JSON(400, resp)
+			return
+		}
+
+		exists, err := db.IsCommunity(s.DB, appID)
+		if err != nil {
+			err = errors.E(op, err)
+			fmt.Print(err)
+			resp.Error = "internal server error"
+			c.JSON(500, resp)
+			return
+		} else if !exists {
+			resp.Error = "not found"
+			c.JSON(404, resp)
+			return
+		}
+
+		collections, err := compound.GetCollectionsByProviderID(s.DB, appID, exclude...)
+		if err != nil && !db.ErrNoRows(err) {
+			err = errors.E(op, err)
+			fmt.Print(err)
+			resp.Error = "internal server error"
+			c.JSON(500, resp)
+			return
+		}
+
+		resp.Collections = []compound.Collection{}
+		if collections != nil {
+			resp.Collections = append(resp.Collections, *collections...)
+		}
+
+		c.JSON(200, resp)
+	}
+}
+
+func (s *Arc53WatcherServer) handleGetCollection() gin.HandlerFunc {
+	const op errors.Op = "handleGetCollection"
+
+	type request struct {
+		CollectionID string `uri:"collectionID" binding:"required"`
+	}
+
+	type response struct {
+		*compound.Collection `json:"collection,omitempty"`
+		Error                string `json:"error,omitempty"`
+	}
+
+	return func(c *gin.Context) {
+		var (
+			req  request
+			resp response
+			err  error
+		)
+
+		err = c.ShouldBindUri(&req)
+		if err != nil {
+			c.JSON(400, gin.H{
+				"ok":    false,
+				"error": err.Error(),
+			})
+			return
+		}
+
+		exclude, err := parseExclude[compound.CollectionGetExclude](c.Query("exclude"))
+		if err != nil {
+			resp.Error = err.Error()
+			c.JSON(400, resp)
+			return
+		}
+
+		resp.Collection, err = compound.GetCollection(s.DB, req.CollectionID, exclude...)
+		if err != nil && !errors.HasKind(err, errors.DatabaseResultNotFound) {
+			err = errors.E(op, err)
+			fmt.Print(err)
+			resp.Error = "internal server error"
+			c.JSON(500, resp)
+			return
+		} else if err != nil {
+			resp.Error = "not found"
+			c.JSON(404, resp)
+			return
+		}
+
+		c.JSON(200, resp)
+	}
+}
+
+func (s *Arc53WatcherServer) handleGetCollectionProperties() gin.HandlerFunc {
+	const op errors.Op = "handleGetCollectionProperties"
+
+	type request struct {
+		CollectionID string `uri:"collectionID" binding:"required"`
+	}
+
+	type response struct {
+		Properties []compound.Property `json:"properties"`
+		Error      string              `json:"error,omitempty"`
+	}
+
+	return func(c *gin.Context) {
+		var (
+			req  request
+			resp response
+			err  error
+		)
+
+		err = c.ShouldBindUri(&req)
+		if err != nil {
+			c.JSON(400, gin.H{
+				"ok":    false,
+				"error": err.Error(),
+			})
+			return
+		}
+
+		_, err = db.GetCollection(s.DB, req.CollectionID)
+		if err != nil && !db.ErrNoRows(err) {
+			err = errors.E(op, err)
+			fmt.Print(err)
+			resp.Error = "internal server error"
+			c.JSON(500, resp)
+			return
+		} else if db.ErrNoRows(err) {
+			resp.Error = "not found"
+			c.JSON(404, resp)
+			return
+		}
+
+		properties, err := compound.GetProperties(s.DB, req.CollectionID)
+		if err != nil && !db.ErrNoRows(err) {
+			err = errors.E(op, err)
+			fmt.Print(err)
+			resp.Error = "internal server error"
+			c.JSON(500, resp)
+			return
+		}
+
+		resp.Properties = []compound.Property{}
+		if properties != nil {
+			resp.Properties = append(resp.Properties, *properties...)
+		}
+
+		c.JSON(200, resp)
+	}
+}
+
+func (s *Arc53WatcherServer) handleLookupAsset() gin.HandlerFunc {
+	const op errors.Op = "handleLookupAsset"
+
+	type request struct {
+		AssetID string `uri:"assetID" binding:"required"`
+	}
+
+	type response struct {
+		*assetLookup
+		Error string `json:"error,omitempty"`
+	}
+
+	return func(c *gin.Context) {
+		var (
+			req  request
+			resp response
+			err  error
+		)
+
+		err = c.ShouldBindUri(&req)
+		if err != nil {
+			c.JSON(400, gin.H{
+				"ok":    false,
+				"error": err.Error(),
+			})
+			return
+		}
+
+		assetID, err := strconv.ParseUint(req.AssetID, 10, 64)
+		if err != nil {
+			err = errors.E(op, err)
+			fmt.Print(err)
+			resp.Error = "bad request"
+			c.JSON(400, resp)
+			return
+		}
+
+		exclude, err := parseExclude[compound.CollectionGetExclude](c.Query("exclude"))
+		if err != nil {
+			resp.Error = err.Error()
+			c.JSON(400, resp)
+			return
+		}
+
+		cache := &assetLookupCache{
+			communities: map[uint64]*compound.Community{},
+			collections: map[string]*compound.Collection{},
+		}
+
+		resp.assetLookup, err = s.lookupAsset(c.Request.Context(), assetID, cache, exclude)
+		if err != nil && !errors.HasKind(err, errors.DatabaseResultNotFound) {
+			err = errors.E(op, err)
+			fmt.Print(err)
+			resp.Error = "internal server error"
+			c.JSON(500, resp)
+			return
+		} else if err != nil {
+			resp.Error = "not found"
+			c.JSON(404, resp)
+			return
+		}
+
+		c.JSON(200, resp)
+	}
+}
+
+func (s *Arc53WatcherServer) handleLookupAssets() gin.HandlerFunc {
+	const maxAssets = 100
+
+	type request struct {
+		AssetIDs []uint64 `json:"asset_ids" binding:"required"`
+	}
+
+	type response struct {
+		Assets []assetLookup `json:"assets"`
+		Error  string        `json:"error,omitempty"`
+	}
+
+	return func(c *gin.Context) {
+		var (
+			req  request
+			resp response
+			err  error
+		)
+
+		err = c.ShouldBindJSON(&req)
+		if err != nil {
+			resp.Error = "bad request"
+			c.JSON(400, resp)
+			return
+		}
+
+		if len(req.AssetIDs) > maxAssets {
+			resp.Error = fmt.Sprintf("too many assets, the limit is %d per request", maxAssets)
+			c.JSON(400, resp)
+			return
+		}
+
+		exclude, err := parseExclude[compound.CollectionGetExclude](c.Query("exclude"))
+		if err != nil {
+			resp.Error = err.Error()
+			c.JSON(400, resp)
+			return
+		}
+
+		resp.Assets = s.lookupAssets(c.Request.Context(), misc.UniqueSlice(req.AssetIDs), exclude)
+
+		c.JSON(200, resp)
+	}
+}
+
+func (s *Arc53WatcherServer) handleSyncByProviderID() gin.HandlerFunc {
+	const op errors.Op = "handleSyncByProviderID"
+
+	type request struct {
+		ProviderType string `uri:"providerType" binding:"required"`
+		AppID        string `uri:"appID" binding:"required"`
+	}
+
+	type response struct {
+		Ok    bool   `json:"ok"`
+		JobID string `json:"job_id,omitempty"`
+		// Deduplicated is set when the app already had a sync queued or running
+		Deduplicated bool   `json:"deduplicated,omitempty"`
+		Error        string `json:"error,omitempty"`
+	}
+
+	return func(c *gin.Context) {
+		var (
+			req  request
+			resp response
+			err  error
+		)
+
+		err = c.ShouldBindUri(&req)
+		if err != nil {
+			c.JSON(400, gin.H{
+				"ok":    false,
+				"error": err.Error(),
+			})
+			return
+		}
+
+		providerTypeMap := map[string]providers.ProviderType{}
+
+		for i := range s.ProviderTypes {
+			providerTypeMap[s.ProviderTypes[i].Type()] = s.ProviderTypes[i]
+		}
+
+		provider, ok := providerTypeMap[req.ProviderType]
+		if !ok {
+			resp.Error = "provider not found"
+			c.JSON(404, resp)
+			return
+		}
+
+		appID, err := strconv.ParseUint(req.AppID, 10, 64)
+		if err != nil {
+			err = errors.E(op, err)
+			fmt.Print(err)
+			resp.Error = "bad request"
+			c.JSON(400, resp)
+			return
+		}
+
+		jobID, queued, err := s.QueueSync(provider, appID)
+		if err != nil && errors.HasKind(err, errors.Type) {
+			resp.Error = fmt.Sprintf("app %d isn't a %s app", appID, provider.Type())
+			c.JSON(404, resp)
+			return
+		} else if err != nil {
+			err = errors.E(op, err)
+			fmt.Print(err)
+			resp.Error = "internal server error"
+			c.JSON(500, resp)
+			return
+		}
+
+		resp.Ok = true
+		resp.JobID = jobID
+		resp.Deduplicated = !queued
+		c.JSON(200, resp)
+	}
+}
+
+func (s *Arc53WatcherServer) handleGetJob() gin.HandlerFunc {
+	const op errors.Op = "handleGetJob"
+
+	type request struct {
+		ID string `uri:"id" binding:"required"`
+	}
+
+	type response struct {
+		Job   *db.SyncJob `json:"job,omitempty"`
+		Error string      `json:"error,omitempty"`
+	}
+
+	return func(c *gin.Context) {
+		var (
+			req  request
+			resp response
+			err  error
+		)
+
+		err = c.ShouldBindUri(&req)
+		if err != nil {
+			resp.Error = "bad request"
+			c.JSON(400, resp)
+			return
+		}
+
+		resp.Job, err = db.GetSyncJob(s.DB, req.ID)
+		if err != nil && !db.ErrNoRows(err) {
+			err = errors.E(op, err)
+			fmt.Print(err)
+			resp.Error = "internal server error"
+			c.JSON(500, resp)
+			return
+		} else if db.ErrNoRows(err) {
+			resp.Error = "not found"
+			c.JSON(404, resp)
+			return
+		}
+
+		c.JSON(200, resp)
+	}
+}
+
+func (s *Arc53WatcherServer) handleGetCursor() gin.HandlerFunc {
+	const op errors.Op = "handleGetCursor"
+
+	type response struct {
+		*db.Cursor `json:"cursor,omitempty"`
+		Error      string `json:"error,omitempty"`
+	}
+
+	return func(c *gin.Context) {
+		var (
+			resp response
+			err  error
+		)
+
+		resp.Cursor, err = db.GetCursor(s.DB, db.CursorWatcher)
+		if err != nil && !db.ErrNoRows(err) {
+			err = errors.E(op, err)
+			fmt.Print(err)
+			resp.Error = "internal server error"
+			c.JSON(500, resp)
+			return
+		} else if db.ErrNoRows(err) {
+			resp.Error = "not found"
+			c.JSON(404, resp)
+			return
+		}
+
+		c.JSON(200, resp)
+	}
+}
+
+func (s *Arc53WatcherServer) handleRewindCursor() gin.HandlerFunc {
+	const op errors.Op = "handleRewindCursor"
+
+	type request struct {
+		Round string `uri:"round" binding:"required"`
+	}
+
+	type response struct {
+		Ok    bool   `json:"ok"`
+		Round uint64 `json:"round,omitempty"`
+		Error string `json:"error,omitempty"`
+	}
+
+	return func(c *gin.Context) {
+		var (
+			req  request
+			resp response
+			err  error
+		)
+
+		err = c.ShouldBindUri(&req)
+		if err != nil {
+			c.JSON(400, gin.H{
+				"ok":    false,
+				"error": err.Error(),
+			})
+			return
+		}
+
+		round, err := strconv.ParseUint(req.Round, 10, 64)
+		if err != nil {
+			err = errors.E(op, err)
+			fmt.Print(err)
+			resp.Error = "bad request"
+			c.JSON(400, resp)
+			return
+		}
+
+		err = s.Rewind(round)
+		if err != nil {
+			err = errors.E(op, err)
+			fmt.Print(err)
+			resp.Error = "internal server error"
+			c.JSON(500, resp)
+			return
+		}
+
+		resp.Ok = true
+		resp.Round = round
+		c.JSON(200, resp)
+	}
+}
+
+func (s *Arc53WatcherServer) handleGetNodes() gin.HandlerFunc {
+	type response struct {
+		Source  string                `json:"source"`
+		Primary string                `json:"primary,omitempty"`
+		Nodes   []streamer.NodeHealth `json:"nodes"`
+	}
+
+	return func(c *gin.Context) {
+		resp := response{
+			Source: "algod",
+			Nodes:  []streamer.NodeHealth{},
+		}
+
+		if s.replay != nil {
+			resp.Source = "replay"
+			c.JSON(200, resp)
+			return
+		}
+
+		live := s.streamer.Load()
+		if live != nil {
+			resp.Primary = live.Primary()
+			resp.Nodes = live.Health()
+		}
+
+		c.JSON(200, resp)
+	}
+}
+
+func (s *Arc53WatcherServer) handleListDeadLetters() gin.HandlerFunc {
+	const op errors.Op = "handleListDeadLetters"
+
+	type request struct {
+		Page  uint64 `form:"page"`
+		Limit uint64 `form:"limit"`
+	}
+
+	type response struct {
+		DeadLetters []db.DeadLetter `json:"dead_letters"`
+		Page        uint64          `json:"page,omitempty"`
+		Limit       uint64          `json:"limit,omitempty"`
+		Error       string          `json:"error,omitempty"`
+	}
+
+	return func(c *gin.Context) {
+		var (
+			req  request
+			resp response
+			err  error
+		)
+
+		err = c.ShouldBindQuery(&req)
+		if err != nil {
+			resp.Error = "bad request"
+			c.JSON(400, resp)
+			return
+		}
+
+		resp.Page, resp.Limit = pagination(req.Page, req.Limit)
+
+		letters, err := db.GetDeadLetters(s.DB, (resp.Page-1)*resp.Limit, resp.Limit)
+		if err != nil && !db.ErrNoRows(err) {
+			err = errors.E(op, err)
+			fmt.Print(err)
+			resp.Error = "internal server error"
+			c.JSON(500, resp)
+			return
+		}
+
+		resp.DeadLetters = []db.DeadLetter{}
+		if letters != nil {
+			resp.DeadLetters = *letters
+		}
+
+		c.JSON(200, resp)
+	}
+}
+
+func (s *Arc53WatcherServer) handleRetryDeadLetter() gin.HandlerFunc {
+	const op errors.Op = "handleRetryDeadLetter"
+
+	type request struct {
+		ID string `uri:"id" binding:"required"`
+	}
+
+	type response struct {
+		Ok    bool   `json:"ok"`
+		Error string `json:"error,omitempty"`
+	}
+
+	return func(c *gin.Context) {
+		var (
+			req  request
+			resp response
+			err  error
+		)
+
+		err = c.ShouldBindUri(&req)
+		if err != nil {
+			resp.Error = "bad request"
+			c.JSON(400, resp)
+			return
+		}
+
+		id, err := strconv.ParseUint(req.ID, 10, 64)
+		if err != nil {
+			resp.Error = "bad request"
+			c.JSON(400, resp)
+			return
+		}
+
+		letter, err := db.GetDeadLetter(s.DB, id)
+		if err != nil && !db.ErrNoRows(err) {
+			err = errors.E(op, err)
+			fmt.Print(err)
+			resp.Error = "internal server error"
+			c.JSON(500, resp)
+			return
+		} else if db.ErrNoRows(err) {
+			resp.Error = "not found"
+			c.JSON(404, resp)
+			return
+		}
+
+		// a failed retry is rescheduled, the caller just learns it didn't go through
+		err = s.RetryDeadLetter(c.Request.Context(), letter)
+		if err != nil {
+			err = errors.E(op, err)
+			fmt.Print(err)
+			resp.Error = "retry failed"
+			c.JSON(502, resp)
+			return
+		}
+
+		resp.Ok = true
+		c.JSON(200, resp)
+	}
+}
+
+func (s *Arc53WatcherServer) handleDiscardDeadLetter() gin.HandlerFunc {
+	const op errors.Op = "handleDiscardDeadLetter"
+
+	type request struct {
+		ID string `uri:"id" binding:"required"`
+	}
+
+	type response struct {
+		Ok    bool   `json:"ok"`
+		Error string `json:"error,omitempty"`
+	}
+
+	return func(c *gin.Context) {
+		var (
+			req  request
+			resp response
+			err  error
+		)
+
+		err = c.ShouldBindUri(&req)
+		if err != nil {
+			resp.Error = "bad request"
+			c.JSON(400, resp)
+			return
+		}
+
+		id, err := strconv.ParseUint(req.ID, 10, 64)
+		if err != nil {
+			resp.Error = "bad request"
+			c.JSON(400, resp)
+			return
+		}
+
+		_, err = db.GetDeadLetter(s.DB, id)
+		if err != nil && !db.ErrNoRows(err) {
+			err = errors.E(op, err)
+			fmt.Print(err)
+			resp.Error = "internal server error"
+			c.JSON(500, resp)
+			return
+		} else if db.ErrNoRows(err) {
+			resp.Error = "not found"
+			c.JSON(404, resp)
+			return
+		}
+
+		err = db.DeleteDeadLetter(s.DB, id)
+		if err != nil {
+			err = errors.E(op, err)
+			fmt.Print(err)
+			resp.Error = "internal server error"
+			c.JSON(500, resp)
+			return
+		}
+
+		resp.Ok = true
+		c.JSON(200, resp)
+	}
+}
+
+func (s *Arc53WatcherServer) handleSyncChallenge() gin.HandlerFunc {
+	const op errors.Op = "handleSyncChallenge"
+
+	type request struct {
+		ProviderType string `uri:"providerType" binding:"required"`
+		AppID        string `uri:"appID" binding:"required"`
+	}
+
+	type response struct {
+		Challenge string `json:"challenge,omitempty"`
+		// Expires is a unix timestamp
+		Expires int64  `json:"expires,omitempty"`
+		Error   string `json:"error,omitempty"`
+	}
+
+	return func(c *gin.Context) {
+		var (
+			req  request
+			resp response
+			err  error
+		)
+
+		err = c.ShouldBindUri(&req)
+		if err != nil {
+			resp.Error = "bad request"
+			c.JSON(400, resp)
+			return
+		}
+
+		appID, err := strconv.ParseUint(req.AppID, 10, 64)
+		if err != nil {
+			resp.Error = "bad request"
+			c.JSON(400, resp)
+			return
+		}
+
+		provider, err := db.GetProvider(s.DB, appID)
+		if err != nil && !db.ErrNoRows(err) {
+			err = errors.E(op, err)
+			fmt.Print(err)
+			resp.Error = "internal server error"
+			c.JSON(500, resp)
+			return
+		} else if db.ErrNoRows(err) || provider.Type != req.ProviderType {
+			resp.Error = "not found"
+			c.JSON(404, resp)
+			return
+		}
+
+		challenge, expires, err := s.auth.newChallenge(req.ProviderType, appID)
+		if err != nil {
+			err = errors.E(op, err)
+			fmt.Print(err)
+			resp.Error = "try again later"
+			c.JSON(503, resp)
+			return
+		}
+
+		resp.Challenge = challenge
+		resp.Expires = expires.Unix()
+		c.JSON(200, resp)
+	}
+}
+
+func (s *Arc53WatcherServer) handleCreateWebhook() gin.HandlerFunc {
+	const op errors.Op = "handleCreateWebhook"
+
+	type request struct {
+		URL string `json:"url" binding:"required"`
+		// Secret is generated when left out
+		Secret      string `json:"secret"`
+		ProviderIDs string `json:"provider_ids"`
+		Types       string `json:"types"`
+	}
+
+	type response struct {
+		Webhook *db.Webhook `json:"webhook,omitempty"`
+		// Secret is only returned here, deliveries are signed with it
+		Secret string `json:"secret,omitempty"`
+		Error  string `json:"error,omitempty"`
+	}
+
+	return func(c *gin.Context) {
+		var (
+			req  request
+			resp response
+			err  error
+		)
+
+		err = c.ShouldBindJSON(&req)
+		if err != nil {
+			resp.Error = "bad request"
+			c.JSON(400, resp)
+			return
+		}
+
+		target, err := url.Parse(req.URL)
+		if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" || len(req.URL) > maxWebhookURLLength {
+			resp.Error = "invalid url"
+			c.JSON(400, resp)
+			return
+		}
+
+		if len(req.Secret) > maxWebhookSecretLength {
+			resp.Error = fmt.Sprintf("secret is longer than %d characters", maxWebhookSecretLength)
+			c.JSON(400, resp)
+			return
+		}
+
+		_, err = parseEventFilter(req.ProviderIDs, req.Types)
+		if err != nil || len(req.ProviderIDs) > maxWebhookFilterLength || len(req.Types) > maxWebhookFilterLength {
+			resp.Error = "invalid filter"
+			c.JSON(400, resp)
+			return
+		}
+
+		if req.Secret == "" {
+			secret := make([]byte, 32)
+			_, err = rand.Read(secret)
+			if err != nil {
+				err = errors.E(op, err)
+				fmt.Print(err)
+				resp.Error = "internal server error"
+				c.JSON(500, resp)
+				return
+			}
+			req.Secret = hex.EncodeToString(secret)
+		}
+
+		// webhooks get the events committed after they're created
+		latest, err := db.GetLatestEventID(s.DB)
+		if err != nil {
+			err = errors.E(op, err)
+			fmt.Print(err)
+			resp.Error = "internal server error"
+			c.JSON(500, resp)
+			return
+		}
+
+		webhook := &db.Webhook{
+			ID:          uuid.New(uuid.Webhook),
+			URL:         req.URL,
+			Secret:      req.Secret,
+			ProviderIDs: req.ProviderIDs,
+			Types:       req.Types,
+			LastEventID: latest,
+			Created:     time.Now().UnixMilli(),
+		}
+
+		_, err = db.Insert(s.DB, webhook)
+		if err != nil {
+			err = errors.E(op, err)
+			fmt.Print(err)
+			resp.Error = "internal server error"
+			c.JSON(500, resp)
+			return
+		}
+
+		resp.Webhook = webhook
+		resp.Secret = webhook.Secret
+		c.JSON(201, resp)
+	}
+}
+
+func (s *Arc53WatcherServer) handleListWebhooks() gin.HandlerFunc {
+	const op errors.Op = "handleListWebhooks"
+
+	type response struct {
+		Webhooks []db.Webhook `json:"webhooks"`
+		Error    string       `json:"error,omitempty"`
+	}
+
+	return func(c *gin.Context) {
+		var resp response
+
+		webhooks, err := db.GetWebhooks(s.DB)
+		if err != nil && !db.ErrNoRows(err) {
+			err = errors.E(op, err)
+			fmt.Print(err)
+			resp.Error = "internal server error"
+			c.JSON(500, resp)
+			return
+		}
+
+		resp.Webhooks = []db.Webhook{}
+		if webhooks != nil {
+			resp.Webhooks = *webhooks
+		}
+
+		c.JSON(200, resp)
+	}
+}
+
+func (s *Arc53WatcherServer) handleDeleteWebhook() gin.HandlerFunc {
+	const op errors.Op = "handleDeleteWebhook"
+
+	type request struct {
+		ID string `uri:"id" binding:"required"`
+	}
+
+	type response struct {
+		Ok    bool   `json:"ok"`
+		Error string `json:"error,omitempty"`
+	}
+
+	return func(c *gin.Context) {
+		var (
+			req  request
+			resp response
+			err  error
+		)
+
+		err = c.ShouldBindUri(&req)
+		if err != nil {
+			resp.Error = "bad request"
+			c.JSON(400, resp)
+			return
+		}
+
+		_, err = db.GetWebhook(s.DB, req.ID)
+		if err != nil && !db.ErrNoRows(err) {
+			err = errors.E(op, err)
+			fmt.Print(err)
+			resp.Error = "internal server error"
+			c.JSON(500, resp)
+			return
+		} else if db.ErrNoRows(err) {
+			resp.Error = "not found"
+			c.JSON(404, resp)
+			return
+		}
+
+		tx, err := s.DB.Beginx()
+		if err != nil {
+			err = errors.E(op, err)
+			fmt.Print(err)
+			resp.Error = "internal server error"
+			c.JSON(500, resp)
+			return
+		}
+
+		err = db.DeleteWebhookDeliveries(tx, req.ID)
+		if err == nil {
+			_, err = db.Delete(tx, &db.Webhook{ID: req.ID})
+		}
+		if err == nil {
+			err = tx.Commit()
+		}
+		if err != nil {
+			tx.Rollback()
+			err = errors.E(op, err)
+			fmt.Print(err)
+			resp.Error = "internal server error"
+			c.JSON(500, resp)
+			return
+		}
+
+		resp.Ok = true
+		c.JSON(200, resp)
+	}
+}
+
+func (s *Arc53WatcherServer) handleListWebhookDeliveries() gin.HandlerFunc {
+	const op errors.Op = "handleListWebhookDeliveries"
+
+	type request struct {
+		ID    string `uri:"id" binding:"required"`
+		Page  uint64 `form:"page"`
+		Limit uint64 `form:"limit"`
+	}
+
+	type response struct {
+		Deliveries []db.WebhookDelivery `json:"deliveries"`
+		Page       uint64               `json:"page,omitempty"`
+		Limit      uint64               `json:"limit,omitempty"`
+		Error      string               `json:"error,omitempty"`
+	}
+
+	return func(c *gin.Context) {
+		var (
+			req  request
+			resp response
+			err  error
+		)
+
+		err = c.ShouldBindUri(&req)
+		if err == nil {
+			err = c.ShouldBindQuery(&req)
+		}
+		if err != nil {
+			resp.Error = "bad request"
+			c.JSON(400, resp)
+			return
+		}
+
+		_, err = db.GetWebhook(s.DB, req.ID)
+		if err != nil && !db.ErrNoRows(err) {
+			err = errors.E(op, err)
+			fmt.Print(err)
+			resp.Error = "internal server error"
+			c.JSON(500, resp)
+			return
+		} else if db.ErrNoRows(err) {
+			resp.Error = "not found"
+			c.JSON(404, resp)
+			return
+		}
+
+		resp.Page, resp.Limit = pagination(req.Page, req.Limit)
+
+		deliveries, err := db.GetWebhookDeliveries(s.DB, req.ID, (resp.Page-1)*resp.Limit, resp.Limit)
+		if err != nil && !db.ErrNoRows(err) {
+			err = errors.E(op, err)
+			fmt.Print(err)
+			resp.Error = "internal server error"
+			c.JSON(500, resp)
+			return
+		}
+
+		resp.Deliveries = []db.WebhookDelivery{}
+		if deliveries != nil {
+			resp.Deliveries = *deliveries
+		}
+
+		c.JSON(200, resp)
+	}
+}
+
+func (s *Arc53WatcherServer) handleRedeliverWebhook() gin.HandlerFunc {
+	const op errors.Op = "handleRedeliverWebhook"
+
+	type request struct {
+		ID         string `uri:"id" binding:"required"`
+		DeliveryID string `uri:"deliveryID" binding:"required"`
+	}
+
+	type response struct {
+		Ok       bool                `json:"ok"`
+		Delivery *db.WebhookDelivery `json:"delivery,omitempty"`
+		Error    string              `json:"error,omitempty"`
+	}
+
+	return func(c *gin.Context) {
+		var (
+			req  request
+			resp response
+			err  error
+		)
+
+		err = c.ShouldBindUri(&req)
+		if err != nil {
+			resp.Error = "bad request"
+			c.JSON(400, resp)
+			return
+		}
+
+		id, err := strconv.ParseUint(req.DeliveryID, 10, 64)
+		if err != nil {
+			resp.Error = "bad request"
+			c.JSON(400, resp)
+			return
+		}
+
+		delivery, err := db.GetWebhookDelivery(s.DB, id)
+		if err != nil && !db.ErrNoRows(err) {
+			err = errors.E(op, err)
+			fmt.Print(err)
+			resp.Error = "internal server error"
+			c.JSON(500, resp)
+			return
+		} else if db.ErrNoRows(err) || delivery.WebhookID != req.ID {
+			resp.Error = "not found"
+			c.JSON(404, resp)
+			return
+		}
+
+		// a failed redelivery is rescheduled like any other attempt while it has attempts left
+		deliveryErr := s.DeliverWebhook(c.Request.Context(), delivery)
+
+		resp.Delivery, err = db.GetWebhookDelivery(s.DB, id)
+		if err != nil {
+			err = errors.E(op, err)
+			fmt.Print(err)
+			resp.Error = "internal server error"
+			c.JSON(500, resp)
+			return
+		}
+
+		if deliveryErr != nil {
+			deliveryErr = errors.E(op, deliveryErr)
+			fmt.Print(deliveryErr)
+			resp.Error = "redelivery failed"
+			c.JSON(502, resp)
+			return
+		}
 
-		go provider.Process(appID)
+		resp.Ok = true
+		c.JSON(200, resp)
 	}
 }
@@ -0,0 +1,130 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/kylebeee/arc53-watcher-go/db"
+	"github.com/kylebeee/arc53-watcher-go/errors"
+	"github.com/kylebeee/arc53-watcher-go/providers"
+	"github.com/kylebeee/arc53-watcher-go/uuid"
+)
+
+// syncJobWorkers is how many sync jobs run at once, the rest wait queued
+const syncJobWorkers = 4
+
+// syncJobs tracks the queued & running sync jobs so a request for an app that
+// already has one joins it rather than syncing the app twice
+type syncJobs struct {
+	lock sync.Mutex
+	// active maps a provider type & app ID to its unfinished job
+	active map[string]*activeJob
+	slots  chan struct{}
+}
+
+// activeJob is an unfinished job, requests joining it wait on inserted & only get its ID
+// once the job is stored
+type activeJob struct {
+	id       string
+	inserted chan struct{}
+	// err is why the job couldn't be stored, set before inserted is closed
+	err error
+}
+
+func newSyncJobs() *syncJobs {
+	return &syncJobs{
+		active: map[string]*activeJob{},
+		slots:  make(chan struct{}, syncJobWorkers),
+	}
+}
+
+// QueueSync queues a sync of an app as a job & returns the job ID, when the app already has
+// a queued or running job that job's ID is returned instead & queued is false. Apps that
+// aren't the provider type's are refused with a Type error before any job is stored
+func (s *Arc53WatcherServer) QueueSync(provider providers.ProviderType, appID uint64) (string, bool, error) {
+	const op errors.Op = "QueueSync"
+
+	if !provider.IsProviderApp(appID) {
+		return "", false, errors.E(op, errors.Type, fmt.Errorf("app %d isn't a %s app", appID, provider.Type()))
+	}
+
+	key := fmt.Sprintf("%s:%d", provider.Type(), appID)
+
+	// the app is claimed under the lock but stored outside of it so a slow insert
+	// doesn't hold up requests for other apps
+	s.jobs.lock.Lock()
+	active, exists := s.jobs.active[key]
+	if exists {
+		s.jobs.lock.Unlock()
+
+		<-active.inserted
+		if active.err != nil {
+			return "", false, errors.E(op, active.err)
+		}
+		return active.id, false, nil
+	}
+
+	active = &activeJob{id: uuid.New(uuid.SyncJob), inserted: make(chan struct{})}
+	s.jobs.active[key] = active
+	s.jobs.lock.Unlock()
+
+	job := &db.SyncJob{
+		ID:           active.id,
+		ProviderType: provider.Type(),
+		AppID:        appID,
+		Status:       db.SyncJobQueued,
+		Created:      time.Now().UnixMilli(),
+	}
+
+	_, err := db.Insert(s.DB, job)
+	if err != nil {
+		active.err = err
+
+		s.jobs.lock.Lock()
+		delete(s.jobs.active, key)
+		s.jobs.lock.Unlock()
+
+		close(active.inserted)
+		return "", false, errors.E(op, err)
+	}
+	close(active.inserted)
+
+	go s.runSyncJob(provider, job, key)
+
+	return job.ID, true, nil
+}
+
+// runSyncJob waits for a free worker, syncs the job's app & records the outcome
+func (s *Arc53WatcherServer) runSyncJob(provider providers.ProviderType, job *db.SyncJob, key string) {
+	const op errors.Op = "runSyncJob"
+
+	s.jobs.slots <- struct{}{}
+	defer func() { <-s.jobs.slots }()
+
+	_, err := db.Update(s.DB, &db.SyncJob{Status: db.SyncJobRunning, Started: time.Now().UnixMilli()}, map[string]interface{}{"id": job.ID})
+	if err != nil {
+		fmt.Println(errors.E(op, err))
+	}
+
+	changes, syncErr := provider.Process(job.AppID)
+
+	// requests from here on start a new sync rather than joining one that's done
+	s.jobs.lock.Lock()
+	delete(s.jobs.active, key)
+	s.jobs.lock.Unlock()
+
+	var summary json.RawMessage
+	if syncErr == nil && changes != nil {
+		summary, err = json.Marshal(changes)
+		if err != nil {
+			fmt.Println(errors.E(op, err))
+		}
+	}
+
+	err = db.FinishSyncJob(s.DB, job.ID, summary, syncErr)
+	if err != nil {
+		fmt.Println(errors.E(op, err))
+	}
+}
@@ -0,0 +1,169 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/kylebeee/arc53-watcher-go/db"
+	"github.com/kylebeee/arc53-watcher-go/db/compound"
+	"github.com/kylebeee/arc53-watcher-go/errors"
+	"github.com/kylebeee/arc53-watcher-go/misc"
+)
+
+// maxAssetCacheSize bounds the number of resolved assets kept in memory
+const maxAssetCacheSize = 100000
+
+// maxLookupConcurrency bounds the number of assets resolved in parallel for a batch
+const maxLookupConcurrency = 8
+
+// assetInfo is the part of an asset's params needed to match it to a collection,
+// neither can change after creation so they are safe to cache indefinitely
+type assetInfo struct {
+	Creator  string
+	UnitName string
+}
+
+type assetCache struct {
+	sync.RWMutex
+	assets map[uint64]assetInfo
+}
+
+func (ac *assetCache) get(assetID uint64) (assetInfo, bool) {
+	ac.RLock()
+	defer ac.RUnlock()
+	info, ok := ac.assets[assetID]
+	return info, ok
+}
+
+func (ac *assetCache) set(assetID uint64, info assetInfo) {
+	ac.Lock()
+	defer ac.Unlock()
+	if ac.assets == nil || len(ac.assets) >= maxAssetCacheSize {
+		ac.assets = map[uint64]assetInfo{}
+	}
+	ac.assets[assetID] = info
+}
+
+type assetLookup struct {
+	AssetID    uint64               `json:"asset_id"`
+	Community  *compound.Community  `json:"community,omitempty"`
+	Collection *compound.Collection `json:"collection,omitempty"`
+	Error      string               `json:"error,omitempty"`
+}
+
+// assetLookupCache shares communities & collections between the assets of a single batch,
+// gallery pages tend to render many assets from the same few collections
+type assetLookupCache struct {
+	sync.Mutex
+	communities map[uint64]*compound.Community
+	collections map[string]*compound.Collection
+}
+
+func (s *Arc53WatcherServer) resolveAsset(ctx context.Context, assetID uint64) (*assetInfo, error) {
+	const op errors.Op = "resolveAsset"
+
+	info, ok := s.assets.get(assetID)
+	if ok {
+		return &info, nil
+	}
+
+	asset, err := s.Algod.GetAssetByID(assetID).Do(ctx)
+	if err != nil {
+		if misc.IsAlgodNotFound(err) {
+			return nil, errors.E(op, errors.DatabaseResultNotFound, err, "Asset Not Found")
+		}
+		return nil, errors.E(op, errors.Network, err)
+	}
+
+	info = assetInfo{
+		Creator:  asset.Params.Creator,
+		UnitName: asset.Params.UnitName,
+	}
+	s.assets.set(assetID, info)
+
+	return &info, nil
+}
+
+// lookupAsset finds the community & collection an asset belongs to
+func (s *Arc53WatcherServer) lookupAsset(ctx context.Context, assetID uint64, cache *assetLookupCache, exclude []compound.CollectionGetExclude) (*assetLookup, error) {
+	const op errors.Op = "lookupAsset"
+	lookup := &assetLookup{AssetID: assetID}
+
+	info, err := s.resolveAsset(ctx, assetID)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	col, err := db.GetCollectionByAssetID(s.DB, assetID, info.Creator, info.UnitName)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	cache.Lock()
+	lookup.Collection = cache.collections[col.ID]
+	lookup.Community = cache.communities[col.ProviderID]
+	cache.Unlock()
+
+	if lookup.Collection == nil {
+		lookup.Collection, err = compound.GetCollection(s.DB, col.ID, exclude...)
+		if err != nil {
+			return nil, errors.E(op, err)
+		}
+	}
+
+	if lookup.Community == nil {
+		lookup.Community, err = compound.GetCommunity(s.DB, col.ProviderID, compound.CommunityGetExcludeCollections)
+		if err != nil {
+			return nil, errors.E(op, err)
+		}
+	}
+
+	cache.Lock()
+	cache.collections[col.ID] = lookup.Collection
+	cache.communities[col.ProviderID] = lookup.Community
+	cache.Unlock()
+
+	return lookup, nil
+}
+
+// lookupAssets resolves a batch of assets in parallel, failures are reported per asset
+func (s *Arc53WatcherServer) lookupAssets(ctx context.Context, assetIDs []uint64, exclude []compound.CollectionGetExclude) []assetLookup {
+	const op errors.Op = "lookupAssets"
+
+	cache := &assetLookupCache{
+		communities: map[uint64]*compound.Community{},
+		collections: map[string]*compound.Collection{},
+	}
+
+	results := make([]assetLookup, len(assetIDs))
+	sem := make(chan struct{}, maxLookupConcurrency)
+	var wg sync.WaitGroup
+
+	for i, assetID := range assetIDs {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, assetID uint64) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			lookup, err := s.lookupAsset(ctx, assetID, cache, exclude)
+			if err != nil {
+				results[i] = assetLookup{AssetID: assetID, Error: "not found"}
+				if !errors.HasKind(err, errors.DatabaseResultNotFound) {
+					fmt.Print(errors.E(op, err))
+					results[i].Error = "internal server error"
+				}
+				return
+			}
+
+			results[i] = *lookup
+		}(i, assetID)
+	}
+
+	wg.Wait()
+
+	return results
+}
@@ -0,0 +1,128 @@
+package server
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/kylebeee/arc53-watcher-go/db"
+	"github.com/kylebeee/arc53-watcher-go/misc"
+)
+
+const defaultPageLimit uint64 = 20
+const maxPageLimit uint64 = 100
+
+type excludeOption interface {
+	~string
+	Valid() bool
+}
+
+// parseExclude splits a comma separated exclude query parameter into its options
+func parseExclude[T excludeOption](raw string) ([]T, error) {
+	exclude := []T{}
+	if strings.TrimSpace(raw) == "" {
+		return exclude, nil
+	}
+
+	for _, part := range strings.Split(raw, ",") {
+		option := T(strings.TrimSpace(part))
+		if !option.Valid() {
+			return nil, fmt.Errorf("invalid exclude option: %s", string(option))
+		}
+		exclude = append(exclude, option)
+	}
+
+	return exclude, nil
+}
+
+// pagination normalizes 1-indexed page & limit query parameters
+func pagination(page, limit uint64) (uint64, uint64) {
+	if page == 0 {
+		page = 1
+	}
+
+	if limit == 0 {
+		limit = defaultPageLimit
+	} else if limit > maxPageLimit {
+		limit = maxPageLimit
+	}
+
+	return page, limit
+}
+
+// eventFilter picks the events a stream subscriber gets, empty lists match everything
+type eventFilter struct {
+	providerIDs []uint64
+	// types are event types or the thing they're about, ie collection for every collection event
+	types []string
+}
+
+func (f eventFilter) match(event *db.Event) bool {
+	if len(f.providerIDs) > 0 && !misc.InSlice(event.ProviderID, f.providerIDs) {
+		return false
+	}
+
+	if len(f.types) == 0 {
+		return true
+	}
+	for _, t := range f.types {
+		if t == event.Type || strings.HasPrefix(event.Type, t+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// parseEventFilter reads the comma separated provider_id & type query parameters of a stream
+func parseEventFilter(providerIDs string, types string) (eventFilter, error) {
+	filter := eventFilter{}
+
+	for _, part := range strings.Split(providerIDs, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		id, err := strconv.ParseUint(part, 10, 64)
+		if err != nil {
+			return filter, fmt.Errorf("invalid provider_id: %s", part)
+		}
+		filter.providerIDs = append(filter.providerIDs, id)
+	}
+
+	for _, part := range strings.Split(types, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		valid := false
+		for _, t := range db.EventTypes {
+			if t == part || strings.HasPrefix(t, part+".") {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return filter, fmt.Errorf("invalid event type: %s", part)
+		}
+		filter.types = append(filter.types, part)
+	}
+
+	return filter, nil
+}
+
+// parseLastEventID reads the ID of the last event a resuming stream got, nil when it isn't resuming
+func parseLastEventID(raw string) (*uint64, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, nil
+	}
+
+	id, err := strconv.ParseUint(raw, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid last event id: %s", raw)
+	}
+
+	return &id, nil
+}
@@ -1,16 +1,90 @@
 package server
 
 import (
+	"context"
 	"fmt"
 	"strings"
+	"time"
 
+	"github.com/kylebeee/arc53-watcher-go/db"
+	"github.com/kylebeee/arc53-watcher-go/errors"
 	"github.com/kylebeee/arc53-watcher-go/internal/algod"
+	"github.com/kylebeee/arc53-watcher-go/internal/utils"
 	"github.com/kylebeee/arc53-watcher-go/misc"
+	"github.com/kylebeee/arc53-watcher-go/providers"
+	"github.com/kylebeee/arc53-watcher-go/providers/community"
 )
 
-func (s *Arc53WatcherServer) ProcessBlock(b *algod.BlockWrap) {
+const (
+	// blockRetryBaseDelay doubles after every failed attempt to commit a block up to blockRetryMaxDelay
+	blockRetryBaseDelay = time.Second
+	blockRetryMaxDelay  = time.Minute
+)
+
+// processBlock records a block & processes it until it commits. A block that fails to commit is
+// retried with backoff rather than skipped, committing the next one would move the cursor past
+// it for good. watcherLock is held for each attempt & released between them so a rewind can
+// cancel ctx, which gives up on the block
+func (s *Arc53WatcherServer) processBlock(ctx context.Context, b *algod.BlockWrap) error {
+	const op errors.Op = "processBlock"
+
+	if s.BlocksJSON {
+		blockJSON, err := utils.EncodeJson(b)
+		if err != nil {
+			fmt.Println(err)
+		} else {
+			fmt.Println(string(blockJSON))
+		}
+	}
+
+	if s.Recorder != nil {
+		err := s.Recorder.Record(b)
+		if err != nil {
+			fmt.Println(err)
+		}
+	}
+
+	for attempt := uint64(1); ; attempt++ {
+		s.watcherLock.Lock()
+		// a rewind may have cancelled the stream while we were waiting
+		if ctx.Err() != nil {
+			s.watcherLock.Unlock()
+			return errors.E(op, ctx.Err())
+		}
+		if s.replayLookups != nil {
+			s.replayLookups.At(uint64(b.Block.Round))
+		}
+		err := s.ProcessBlock(b)
+		s.watcherLock.Unlock()
+
+		if err == nil {
+			return nil
+		}
+
+		delay := backoff(blockRetryBaseDelay, blockRetryMaxDelay, attempt)
+		fmt.Printf("[!ERR][BLK] block %d didn't commit, retrying in %s: %v\n", b.Block.Round, delay, err)
+
+		select {
+		case <-ctx.Done():
+			return errors.E(op, ctx.Err())
+		case <-time.After(delay):
+		}
+	}
+}
+
+// ProcessBlock hands a block to the provider types & moves the cursor past it in one transaction,
+// transactions that fail to decode & apps that fail to sync are queued in the dead letter queue.
+// An error means nothing was committed & the cursor is still at the block
+func (s *Arc53WatcherServer) ProcessBlock(b *algod.BlockWrap) error {
+	const op errors.Op = "ProcessBlock"
+
 	fmt.Printf("\n\n[BLK]: %v\n", b.Block.Round)
 
+	btx, err := community.BeginBlock(s.DB)
+	if err != nil {
+		return errors.E(op, err)
+	}
+
 	for i := range b.Block.Payset {
 		stxn := b.Block.Payset[i]
 		txn := b.Block.Payset[i].SignedTxnWithAD.SignedTxn.Txn
@@ -18,7 +92,11 @@ func (s *Arc53WatcherServer) ProcessBlock(b *algod.BlockWrap) {
 		id, err := algod.DecodeTxnId(b.Block.BlockHeader, &stxn)
 		if err != nil {
 			fmt.Println(err)
-			s.ProcessingFailures = append(s.ProcessingFailures, err)
+			s.deadLetter(&db.DeadLetter{
+				Kind:     db.DeadLetterDecode,
+				Round:    uint64(b.Block.Round),
+				TxnIndex: uint64(i),
+			}, errors.E(op, errors.Type, err))
 			continue
 		}
 
@@ -34,10 +112,53 @@ func (s *Arc53WatcherServer) ProcessBlock(b *algod.BlockWrap) {
 		}
 
 		for i := range s.ProviderTypes {
-			err := s.ProviderTypes[i].ProcessBlock(stxn, uint64(b.Block.Round))
+			if _, batched := s.ProviderTypes[i].(providers.BlockProcessor); batched {
+				continue
+			}
+
+			err := s.ProviderTypes[i].ProcessBlock(btx, stxn, uint64(b.Block.Round))
 			if err != nil {
 				fmt.Println(err)
+				s.deadLetterSyncs(s.ProviderTypes[i].Type(), uint64(b.Block.Round), err)
 			}
 		}
 	}
+
+	for i := range s.ProviderTypes {
+		batcher, ok := s.ProviderTypes[i].(providers.BlockProcessor)
+		if !ok {
+			continue
+		}
+
+		err := batcher.ProcessBlockBatch(btx, b.Block, uint64(b.Block.Round))
+		if err != nil {
+			fmt.Println(err)
+			s.deadLetterSyncs(s.ProviderTypes[i].Type(), uint64(b.Block.Round), err)
+		}
+	}
+
+	// the cursor moves past the block in the block's transaction, so a crash before the commit
+	// leaves both the block's writes & the cursor where they were
+	err = db.SetCursor(btx.Tx, db.CursorWatcher, uint64(b.Block.Round)+1)
+	if err != nil {
+		btx.Rollback()
+		return errors.E(op, err)
+	}
+
+	err = btx.Commit()
+	if err != nil {
+		return errors.E(op, err)
+	}
+
+	return nil
+}
+
+// observeRound hands the last round the block stream's nodes report to the provider types watching for it
+func (s *Arc53WatcherServer) observeRound(round uint64) {
+	for i := range s.ProviderTypes {
+		observer, ok := s.ProviderTypes[i].(providers.RoundObserver)
+		if ok {
+			observer.ObserveRound(round)
+		}
+	}
 }
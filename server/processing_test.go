@@ -0,0 +1,58 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/algorand/go-algorand-sdk/v2/types"
+	"github.com/kylebeee/arc53-watcher-go/db"
+	"github.com/kylebeee/arc53-watcher-go/internal/algod"
+)
+
+const setCursorQuery = "insert into `cursor` (id, round) values (?, ?) on duplicate key update round = values(round)"
+
+func TestProcessBlockRetriesUntilCommitted(t *testing.T) {
+	s, mock := mockServer(t)
+	b := &algod.BlockWrap{Block: &types.Block{BlockHeader: types.BlockHeader{Round: 10}}}
+
+	// the first attempt can't begin, the second has its commit fail & the third commits
+	mock.ExpectBegin().WillReturnError(fmt.Errorf("connection refused"))
+	mock.ExpectBegin()
+	mock.ExpectPrepare(regexp.QuoteMeta(setCursorQuery)).ExpectExec().WithArgs(db.CursorWatcher, 11).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit().WillReturnError(fmt.Errorf("deadlock"))
+	mock.ExpectBegin()
+	mock.ExpectPrepare(regexp.QuoteMeta(setCursorQuery)).ExpectExec().WithArgs(db.CursorWatcher, 11).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err := s.processBlock(context.Background(), b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestProcessBlockGivesUpWhenCancelled(t *testing.T) {
+	s, mock := mockServer(t)
+	b := &algod.BlockWrap{Block: &types.Block{BlockHeader: types.BlockHeader{Round: 10}}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	mock.ExpectBegin().WillReturnError(fmt.Errorf("connection refused"))
+	// cancelled while waiting to retry, the way a rewind cancels the stream
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	err := s.processBlock(ctx, b)
+	if err == nil {
+		t.Fatal("processBlock succeeded without the block committing")
+	}
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,96 @@
+package server
+
+import (
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kylebeee/arc53-watcher-go/internal/config"
+)
+
+// rateLimiterSweep is how often buckets that have filled back up are dropped
+const rateLimiterSweep = time.Minute
+
+// rateLimiter keeps a token bucket per key, each holds up to a quota's requests
+// & refills at that many requests every quota's seconds
+type rateLimiter struct {
+	lock    sync.Mutex
+	rate    float64
+	burst   float64
+	buckets map[string]*bucket
+	swept   time.Time
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// newRateLimiter returns nil for a quota that's turned off, a nil limiter allows everything
+func newRateLimiter(quota *config.QuotaConfig) *rateLimiter {
+	if quota == nil || quota.Requests <= 0 {
+		return nil
+	}
+
+	return &rateLimiter{
+		rate:    float64(quota.Requests) / float64(quota.Seconds),
+		burst:   float64(quota.Requests),
+		buckets: map[string]*bucket{},
+		swept:   time.Now(),
+	}
+}
+
+// allow takes a token from key's bucket, when it's empty it reports how long until it has one
+func (l *rateLimiter) allow(key string) (bool, time.Duration) {
+	if l == nil {
+		return true, 0
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	now := time.Now()
+	if now.Sub(l.swept) > rateLimiterSweep {
+		for k, b := range l.buckets {
+			if b.tokens+now.Sub(b.updated).Seconds()*l.rate >= l.burst {
+				delete(l.buckets, k)
+			}
+		}
+		l.swept = now
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, updated: now}
+		l.buckets[key] = b
+	}
+
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.updated).Seconds()*l.rate)
+	b.updated = now
+
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+	}
+	b.tokens--
+
+	return true, 0
+}
+
+// limit rejects requests once the bucket picked by key is empty
+func limit(l *rateLimiter, key func(c *gin.Context) string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ok, wait := l.allow(key(c))
+		if !ok {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			c.AbortWithStatusJSON(429, gin.H{
+				"ok":    false,
+				"error": "rate limited",
+			})
+			return
+		}
+
+		c.Next()
+	}
+}
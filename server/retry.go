@@ -0,0 +1,200 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/kylebeee/arc53-watcher-go/db"
+	"github.com/kylebeee/arc53-watcher-go/errors"
+	streamer "github.com/kylebeee/arc53-watcher-go/internal/algod"
+	"github.com/kylebeee/arc53-watcher-go/providers"
+)
+
+const (
+	// retryInterval is how often the dead letter queue is checked for due entries
+	retryInterval = 15 * time.Second
+	// retryBatch is the most entries retried per check
+	retryBatch = 50
+	// retryBaseDelay doubles after every failed attempt up to retryMaxDelay
+	retryBaseDelay = 30 * time.Second
+	retryMaxDelay  = time.Hour
+	// retryMaxAttempts parks an entry, it stays queued but is only retried by hand
+	retryMaxAttempts = 10
+)
+
+// retryDelay is how long to wait before retrying an entry that failed attempts times
+func retryDelay(attempts uint64) time.Duration {
+	return backoff(retryBaseDelay, retryMaxDelay, attempts)
+}
+
+// nextAttempt is when an entry that failed attempts times is retried next, decode entries
+// aren't scheduled since the same block fails to decode the same way every time
+func nextAttempt(kind string, attempts uint64) time.Time {
+	if kind == db.DeadLetterDecode {
+		return time.Unix(0, 0)
+	}
+	return time.Now().Add(retryDelay(attempts))
+}
+
+// backoff doubles base for every attempt after the first, up to max
+func backoff(base, max time.Duration, attempts uint64) time.Duration {
+	delay := base
+	for i := uint64(1); i < attempts && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max {
+		delay = max
+	}
+	return delay
+}
+
+// deadLetter queues a failure for the retry scheduler
+func (s *Arc53WatcherServer) deadLetter(letter *db.DeadLetter, failure error) {
+	err := db.RecordDeadLetter(s.DB, letter, failure, nextAttempt(letter.Kind, 1))
+	if err != nil {
+		fmt.Println(err)
+	}
+}
+
+// deadLetterSyncs queues every app in the SyncFailures a provider type returned for a round
+func (s *Arc53WatcherServer) deadLetterSyncs(providerType string, round uint64, err error) {
+	failures, ok := providers.AsSyncFailures(err)
+	if !ok {
+		return
+	}
+
+	for _, appID := range failures.AppIDs() {
+		s.deadLetter(&db.DeadLetter{
+			Kind:         db.DeadLetterSync,
+			ProviderType: providerType,
+			AppID:        appID,
+			Round:        round,
+		}, failures[appID])
+	}
+}
+
+// retryDeadLetters retries the due sync dead letters every retryInterval until ctx is done
+func (s *Arc53WatcherServer) retryDeadLetters(ctx context.Context) {
+	ticker := time.NewTicker(retryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		letters, err := db.GetDueDeadLetters(s.DB, db.DeadLetterSync, time.Now(), retryMaxAttempts, retryBatch)
+		if err != nil {
+			if !db.ErrNoRows(err) {
+				fmt.Println(err)
+			}
+			continue
+		}
+
+		for i := range *letters {
+			if ctx.Err() != nil {
+				return
+			}
+
+			err = s.RetryDeadLetter(ctx, &(*letters)[i])
+			if err != nil {
+				fmt.Printf("[RETRY] [ERROR]: %v\n", err)
+			}
+		}
+	}
+}
+
+// RetryDeadLetter retries a dead letter, it's removed once the retry succeeds &
+// rescheduled with a longer backoff when it fails again
+func (s *Arc53WatcherServer) RetryDeadLetter(ctx context.Context, letter *db.DeadLetter) error {
+	const op errors.Op = "RetryDeadLetter"
+
+	var failure error
+	switch letter.Kind {
+	case db.DeadLetterSync:
+		failure = s.retrySync(letter)
+	case db.DeadLetterDecode:
+		failure = s.retryDecode(ctx, letter)
+	default:
+		failure = fmt.Errorf("unknown dead letter kind %s", letter.Kind)
+	}
+
+	if failure != nil {
+		err := db.RescheduleDeadLetter(s.DB, letter.ID, failure, nextAttempt(letter.Kind, letter.Attempts+1))
+		if err != nil {
+			return errors.E(op, err)
+		}
+		return errors.E(op, failure)
+	}
+
+	err := db.DeleteDeadLetter(s.DB, letter.ID)
+	if err != nil {
+		return errors.E(op, err)
+	}
+
+	return nil
+}
+
+// retrySync syncs the app again from its current state
+func (s *Arc53WatcherServer) retrySync(letter *db.DeadLetter) error {
+	const op errors.Op = "retrySync"
+
+	for i := range s.ProviderTypes {
+		if s.ProviderTypes[i].Type() == letter.ProviderType {
+			_, err := s.ProviderTypes[i].Process(letter.AppID)
+			if err != nil {
+				return errors.E(op, err)
+			}
+			return nil
+		}
+	}
+
+	return errors.E(op, fmt.Errorf("provider type %s isn't running", letter.ProviderType))
+}
+
+// retryDecode refetches the block of a transaction that failed to decode & hands the
+// transaction to the provider types that process transactions one at a time, apps they
+// fail to sync are queued as their own dead letters
+func (s *Arc53WatcherServer) retryDecode(ctx context.Context, letter *db.DeadLetter) error {
+	const op errors.Op = "retryDecode"
+
+	raw, err := s.Algod.BlockRaw(letter.Round).Do(ctx)
+	if err != nil {
+		return errors.E(op, errors.Network, err)
+	}
+
+	block, err := streamer.DecodeBlock(raw)
+	if err != nil {
+		return errors.E(op, errors.Type, err)
+	}
+
+	if letter.TxnIndex >= uint64(len(block.Payset)) {
+		return errors.E(op, errors.Integrity, fmt.Errorf("round %d has no transaction %d", letter.Round, letter.TxnIndex))
+	}
+	stxn := block.Payset[letter.TxnIndex]
+
+	_, err = streamer.DecodeTxnId(block.BlockHeader, &stxn)
+	if err != nil {
+		return errors.E(op, errors.Type, err)
+	}
+
+	s.watcherLock.Lock()
+	defer s.watcherLock.Unlock()
+
+	for i := range s.ProviderTypes {
+		if _, batched := s.ProviderTypes[i].(providers.BlockProcessor); batched {
+			continue
+		}
+
+		err := s.ProviderTypes[i].ProcessBlock(nil, stxn, letter.Round)
+		if err != nil {
+			fmt.Println(err)
+			s.deadLetterSyncs(s.ProviderTypes[i].Type(), letter.Round, err)
+		}
+	}
+
+	return nil
+}
@@ -1,7 +1,45 @@
 package server
 
-func (s *Arc53WatcherServer) routes() {
-	s.GET("/", s.handleHealthCheck())
-	s.GET("/provider/:key", s.handleGetARC53Data())
-	s.GET("/sync/:providerType/:key", s.handleSyncByProviderID())
+import "github.com/gin-gonic/gin"
+
+func (s *Arc53WatcherServer) routes(r gin.IRouter) {
+	r.GET("/", s.handleHealthCheck())
+	r.GET("/jobs/:id", s.handleGetJob())
+	r.GET("/events", s.limitIP(), s.handleStreamEvents())
+	r.GET("/events/ws", s.limitIP(), s.handleStreamEventsWS())
+	r.GET("/cursor", s.handleGetCursor())
+	r.GET("/nodes", s.handleGetNodes())
+
+	// routes that make the watcher do work are rate limited & need an admin or the app's owner
+	r.POST("/sync/:providerType/:appID/challenge", s.limitIP(), s.handleSyncChallenge())
+	// the per app limit only counts authorized syncs so others can't use up an owner's quota
+	r.GET("/sync/:providerType/:appID", s.limitIP(), s.requireAdminOrOwner(), s.limitApp(), s.handleSyncByProviderID())
+
+	// without credentials nobody is an admin, so the admin routes aren't served at all
+	if !s.auth.open() {
+		admin := r.Group("/", s.limitIP(), s.requireAdmin())
+		admin.POST("/cursor/rewind/:round", s.handleRewindCursor())
+		admin.GET("/dead-letters", s.handleListDeadLetters())
+		admin.POST("/dead-letters/:id/retry", s.handleRetryDeadLetter())
+		admin.DELETE("/dead-letters/:id", s.handleDiscardDeadLetter())
+		admin.POST("/webhooks", s.handleCreateWebhook())
+		admin.GET("/webhooks", s.handleListWebhooks())
+		admin.DELETE("/webhooks/:id", s.handleDeleteWebhook())
+		admin.GET("/webhooks/:id/deliveries", s.handleListWebhookDeliveries())
+		admin.POST("/webhooks/:id/deliveries/:deliveryID/redeliver", s.handleRedeliverWebhook())
+	}
+
+	// kept for clients of the original unversioned route
+	r.GET("/provider/:appID", s.handleGetCommunity())
+
+	v1 := r.Group("/v1")
+	v1.GET("/communities", s.handleListCommunities())
+	v1.GET("/communities/:appID", s.handleGetCommunity())
+	v1.GET("/communities/:appID/status", s.handleGetCommunityStatus())
+	v1.GET("/communities/:appID/collections", s.handleGetCommunityCollections())
+	v1.GET("/collections/:collectionID", s.handleGetCollection())
+	v1.GET("/collections/:collectionID/properties", s.handleGetCollectionProperties())
+	v1.GET("/assets/:assetID", s.handleLookupAsset())
+	// each ID can take an algod lookup, so batches are limited per client IP like the guarded routes
+	v1.POST("/assets/lookup", s.limitIP(), s.handleLookupAssets())
 }
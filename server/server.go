@@ -4,7 +4,9 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"os"
+	"net/http"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/algorand/go-algorand-sdk/v2/client/v2/algod"
@@ -16,69 +18,174 @@ import (
 	streamer "github.com/kylebeee/arc53-watcher-go/internal/algod"
 	"github.com/kylebeee/arc53-watcher-go/internal/config"
 	"github.com/kylebeee/arc53-watcher-go/providers"
+
+	// provider types register themselves with the providers package
+	_ "github.com/kylebeee/arc53-watcher-go/providers/app"
+	_ "github.com/kylebeee/arc53-watcher-go/providers/nfd"
 )
 
-type Arc53WatcherServer struct {
+// Server runs a watcher for each configured network behind one API, every network's
+// routes are served under its name & the first network's are also served unprefixed
+type Server struct {
 	*gin.Engine
-	Name               errors.Sn
-	DB                 *sqlx.DB
-	LocalTime          *time.Location
-	Algod              *algod.Client
-	Indexer            *indexer.Client
-	WatcherCancelFn    context.CancelFunc
-	ProcessingFailures []interface{}
-	PrintTxns          bool
-	ProviderTypes      []providers.ProviderType
+	// ListenAddr is the address the API is served on
+	ListenAddr string
+	Watchers   []*Arc53WatcherServer
+}
+
+// Arc53WatcherServer watches a single network with its own streamer, provider types & database
+type Arc53WatcherServer struct {
+	Name            errors.Sn
+	DB              *sqlx.DB
+	LocalTime       *time.Location
+	Algod           *algod.Client
+	Indexer         *indexer.Client
+	WatcherCancelFn context.CancelFunc
+	PrintTxns       bool
+	// BlocksJSON prints each processed block as json
+	BlocksJSON    bool
+	ProviderTypes []providers.ProviderType
+	// Network is the network being watched
+	Network string
+
+	// algodConfig holds the nodes blocks are streamed from & the rounds to watch
+	algodConfig *streamer.AlgoConfig
+	// streamer is the live block stream, for reporting node health
+	streamer atomic.Pointer[streamer.Streamer]
+	// Recorder writes processed blocks to disk when recording is configured
+	Recorder *streamer.Recorder
+	// replay replaces algod as the block source when set
+	replay *config.ReplayConfig
+	// replayLookups serves the recorded algod lookups while replaying
+	replayLookups *streamer.ReplayTransport
+	assets        assetCache
+	// backgroundCancelFn stops the dead letter retry scheduler, the event publisher & the webhook dispatcher
+	backgroundCancelFn context.CancelFunc
+	jobs               *syncJobs
+	auth               *authGuard
+	events             *eventBus
+	webhookClient      *http.Client
+	// watcherLock serializes block processing against cursor rewinds
+	watcherLock sync.Mutex
 }
 
-const networkMainnet = "mainnet"
-const networkTestnet = "testnet"
+func New() *Server {
+	cfg, err := config.LoadWatcherConfig()
+	if err != nil {
+		log.Fatalf("[!ERR][_MAIN] error loading config: %s\n", err)
+	}
+
+	if cfg.Log.GinMode != "" {
+		gin.SetMode(cfg.Log.GinMode)
+	}
+
+	if len(cfg.Auth.APIKeys) == 0 && cfg.Auth.HMACSecret == "" {
+		fmt.Println("[WARN][_MAIN] no api keys or hmac secret configured, admin routes aren't served")
+	}
+
+	srv := &Server{
+		Engine:     gin.Default(),
+		ListenAddr: cfg.Listen,
+	}
+
+	// client IPs are rate limited, so forwarded ones are only believed from trusted proxies
+	err = srv.SetTrustedProxies(cfg.TrustedProxies)
+	if err != nil {
+		log.Fatalf("[!ERR][_MAIN] error setting trusted proxies: %s\n", err)
+	}
+
+	for i := range cfg.Networks {
+		s := newWatcher(cfg.Networks[i], cfg.Log, cfg.Auth)
+
+		// unprefixed routes keep serving clients from before networks were routed
+		if i == 0 {
+			s.routes(srv.Engine)
+		}
+		s.routes(srv.Group("/" + s.Network))
+
+		srv.Watchers = append(srv.Watchers, s)
+	}
 
-const indexerMainnetAPI = "https://mainnet-idx.algonode.cloud"
-const indexerTestnetAPI = "https://testnet-idx.algonode.cloud"
+	return srv
+}
 
-const algodMainnetAPI = "https://mainnet-api.algonode.cloud"
-const algodTestnetAPI = "https://testnet-api.algonode.cloud"
+// Close stops every network's watcher & closes its database
+func (srv *Server) Close() {
+	for _, s := range srv.Watchers {
+		if s.WatcherCancelFn != nil {
+			s.WatcherCancelFn()
+		}
+		s.Close()
+	}
+}
 
-func New() *Arc53WatcherServer {
+// newWatcher connects to a network, catches its provider types up & starts watching it
+func newWatcher(cfg config.NetworkConfig, logCfg config.LogConfig, authCfg config.AuthConfig) *Arc53WatcherServer {
 	var err error
 
 	s := &Arc53WatcherServer{
-		Engine:        gin.Default(),
-		PrintTxns:     true,
-		ProviderTypes: providers.ProviderTypes,
+		PrintTxns:   *logCfg.PrintTxns,
+		BlocksJSON:  logCfg.BlocksJSON,
+		Network:     cfg.Network,
+		algodConfig: cfg.Algod,
+		jobs:        newSyncJobs(),
+		auth:        newAuthGuard(authCfg),
+		events:      newEventBus(),
+		// attempts are bounded by webhookTimeout
+		webhookClient: &http.Client{},
 	}
 
-	s.routes()
-
-	network := networkTestnet
-	indexerURL := indexerTestnetAPI
-	algodURL := algodTestnetAPI
-	if s.IsProduction() {
-		network = networkMainnet
-		indexerURL = indexerMainnetAPI
-		algodURL = algodMainnetAPI
+	s.ProviderTypes, err = providers.Build(s.Network, cfg.Providers)
+	if err != nil {
+		log.Fatalf("[!ERR][_MAIN] error building providers: %s\n", err)
 	}
 
-	conn, err := db.Connect()
+	conn, err := db.Open(cfg.DB.DSN, cfg.DB.Database, cfg.DB.MaxOpenConns)
 	if err != nil {
 		log.Fatalln(err)
 	}
 	s.DB = conn
 
-	s.Indexer, err = indexer.MakeClient(indexerURL, "")
+	err = db.FailUnfinishedSyncJobs(s.DB)
+	if err != nil {
+		log.Fatalf("[!ERR][_MAIN] error failing unfinished sync jobs: %s\n", err)
+	}
+
+	s.Indexer, err = indexer.MakeClient(cfg.Indexer.Address, cfg.Indexer.Token)
 	if err != nil {
 		log.Fatalln(err)
 	}
 
-	s.Algod, err = algod.MakeClient(algodURL, "")
+	if cfg.Record != nil {
+		s.Recorder, err = streamer.NewRecorder(*cfg.Record)
+		if err != nil {
+			log.Fatalf("[!ERR][_MAIN] error opening block recorder: %s\n", err)
+		}
+	}
+	s.replay = cfg.Replay
+
+	// lookups go to the first node, the rest only add to the block stream, they're
+	// logged beside the blocks when recording & served from that log when replaying
+	var transport http.RoundTripper = http.DefaultTransport
+	if s.replay != nil {
+		s.replayLookups, err = streamer.NewReplayTransport(s.replay.Dir)
+		if err != nil {
+			log.Fatalf("[!ERR][_MAIN] error loading recorded lookups: %s\n", err)
+		}
+		transport = s.replayLookups
+	} else if s.Recorder != nil {
+		transport = s.Recorder.Transport(transport)
+	}
+
+	node := cfg.Algod.ANodes[0]
+	s.Algod, err = algod.MakeClientWithTransport(node.Address, node.Token, nil, transport)
 	if err != nil {
 		log.Fatalln(err)
 	}
 
 	var currentAsOfRound int64
 	for i := range s.ProviderTypes {
-		err = s.ProviderTypes[i].Init(network, s.DB, s.Algod)
+		err = s.ProviderTypes[i].Init(s.Network, s.DB, s.Algod)
 		if err != nil {
 			log.Fatalf("[!ERR][_MAIN] error initializing provider: %s\n", err)
 		}
@@ -88,9 +195,12 @@ func New() *Arc53WatcherServer {
 			log.Fatalf("[!ERR][_MAIN] error fetching provider latest round: %s\n", err)
 		}
 
-		err = s.ProviderTypes[i].CatchUp(s.DB, s.Algod, startAtRound, s.Indexer)
-		if err != nil {
-			log.Fatalf("[!ERR][_MAIN] error catching up provider: %s\n", err)
+		// a block scan catches every provider type up at once below & replays don't catch up
+		if cfg.Catchup.Mode != config.CatchupBlocks && s.replay == nil {
+			err = s.ProviderTypes[i].CatchUp(s.DB, s.Algod, startAtRound, s.Indexer)
+			if err != nil {
+				log.Fatalf("[!ERR][_MAIN] error catching up provider: %s\n", err)
+			}
 		}
 
 		if int64(startAtRound) < currentAsOfRound || currentAsOfRound == 0 {
@@ -98,53 +208,150 @@ func New() *Arc53WatcherServer {
 		}
 	}
 
-	go func() {
-		watchingConfig := config.StreamerConfig{
-			Algod: &streamer.AlgoConfig{
-				FRound: currentAsOfRound,
-				LRound: -1,
-				Queue:  1,
-				ANodes: []*streamer.AlgoNodeConfig{
-					{
-						Address: algodURL,
-						Id:      "public-node",
-					},
-				},
-			},
+	// resume from the last block the watcher actually processed when we have one
+	cursor, err := db.GetCursor(s.DB, db.CursorWatcher)
+	if err != nil && !db.ErrNoRows(err) {
+		log.Fatalf("[!ERR][_MAIN] error fetching watcher cursor: %s\n", err)
+	} else if cursor != nil {
+		currentAsOfRound = int64(cursor.Round)
+	}
+
+	// a first round from the config or -r wins over the cursor
+	if cfg.Algod.FRound >= 0 {
+		currentAsOfRound = cfg.Algod.FRound
+	}
+
+	if s.replay != nil {
+		if s.replay.FirstRound != 0 {
+			currentAsOfRound = int64(s.replay.FirstRound)
+		}
+	} else if cfg.Catchup.Mode == config.CatchupBlocks {
+		nextRound, err := s.scanCatchUp(cfg.Catchup, uint64(currentAsOfRound))
+		if err != nil {
+			log.Fatalf("[!ERR][_MAIN] error scanning blocks: %s\n", err)
 		}
+		currentAsOfRound = int64(nextRound)
+	}
+
+	s.watch(currentAsOfRound)
+
+	// streams start from the newest event, older ones are read from the outbox on resume
+	s.events.last, err = db.GetLatestEventID(s.DB)
+	if err != nil {
+		log.Fatalf("[!ERR][_MAIN] error fetching latest event: %s\n", err)
+	}
 
-		var ctx context.Context
-		ctx, s.WatcherCancelFn = context.WithCancel(context.Background())
+	var ctx context.Context
+	ctx, s.backgroundCancelFn = context.WithCancel(context.Background())
+	go s.retryDeadLetters(ctx)
+	go s.publishEvents(ctx)
+	go s.dispatchWebhooks(ctx)
 
-		blocks, status, err := streamer.AlgoStreamer(ctx, watchingConfig.Algod)
+	return s
+}
+
+// watch starts streaming blocks into ProcessBlock from the given round,
+// replacing any block stream that is already running
+func (s *Arc53WatcherServer) watch(fromRound int64) {
+	if s.WatcherCancelFn != nil {
+		s.WatcherCancelFn()
+	}
+
+	var ctx context.Context
+	ctx, s.WatcherCancelFn = context.WithCancel(context.Background())
+
+	go func() {
+		blocks, status, err := s.blockSource(fromRound).Stream(ctx)
 		if err != nil {
-			log.Fatalf("[!ERR][_MAIN] error getting algod stream: %s\n", err)
+			log.Fatalf("[!ERR][_MAIN] error getting block stream: %s\n", err)
 		}
 
-		go func() {
-			for {
-				select {
-				case <-status:
-					//noop
-				case b := <-blocks:
-					s.ProcessBlock(b)
-				case <-ctx.Done():
-					fmt.Println("DONE APPARENTLY")
+		for {
+			select {
+			case st, ok := <-status:
+				if !ok {
+					status = nil
+					continue
+				}
+				s.observeRound(st.LastRound)
+			case b, ok := <-blocks:
+				// the stream is done when cancelled, past its last round or out of recorded blocks
+				if !ok {
+					fmt.Println("BLOCK STREAM FINISHED")
+					return
 				}
+				// a block that doesn't commit holds the stream until it does, or a rewind cancels it
+				err := s.processBlock(ctx, b)
+				if err != nil {
+					fmt.Println("BLOCK WATCHER GOROUTINE FINISHED")
+					return
+				}
+			case <-ctx.Done():
+				fmt.Println("BLOCK WATCHER GOROUTINE FINISHED")
+				return
 			}
-		}()
-
-		<-ctx.Done()
-		fmt.Println("BLOCK WATCHER GOROUTINE FINISHED")
+		}
 	}()
+}
 
-	return s
+// blockSource is where the watcher reads blocks from starting at fromRound,
+// the recorded blocks when replaying & algod otherwise
+func (s *Arc53WatcherServer) blockSource(fromRound int64) streamer.BlockSource {
+	if s.replay != nil {
+		first := uint64(0)
+		if fromRound > 0 {
+			first = uint64(fromRound)
+		}
+
+		return &streamer.ReplaySource{
+			Dir:   s.replay.Dir,
+			First: first,
+			Last:  s.replay.LastRound,
+		}
+	}
+
+	watchingConfig := config.StreamerConfig{
+		Algod: &streamer.AlgoConfig{
+			FRound: fromRound,
+			LRound: s.algodConfig.LRound,
+			Queue:  s.algodConfig.Queue,
+			ANodes: s.algodConfig.ANodes,
+		},
+	}
+
+	live := streamer.NewStreamer(watchingConfig.Algod)
+	s.streamer.Store(live)
+	return live
+}
+
+// Rewind moves the watcher cursor back to round so the range from
+// round onwards gets reprocessed, restarting the block stream there
+func (s *Arc53WatcherServer) Rewind(round uint64) error {
+	const op errors.Op = "Rewind"
+
+	s.watcherLock.Lock()
+	defer s.watcherLock.Unlock()
+
+	err := db.SetCursor(s.DB, db.CursorWatcher, round)
+	if err != nil {
+		return errors.E(op, err)
+	}
+
+	s.watch(int64(round))
+
+	return nil
 }
 
 func (s *Arc53WatcherServer) IsProduction() bool {
-	return os.Getenv("ENV") == "production"
+	return s.Network == config.NetworkMainnet
 }
 
 func (s *Arc53WatcherServer) Close() {
+	if s.backgroundCancelFn != nil {
+		s.backgroundCancelFn()
+	}
+	if s.Recorder != nil {
+		s.Recorder.Close()
+	}
 	s.DB.Close()
 }
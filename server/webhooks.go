@@ -0,0 +1,352 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/kylebeee/arc53-watcher-go/db"
+	"github.com/kylebeee/arc53-watcher-go/errors"
+)
+
+const (
+	webhookIDHeader        = "X-Webhook-ID"
+	webhookDeliveryHeader  = "X-Webhook-Delivery"
+	webhookEventHeader     = "X-Webhook-Event"
+	webhookTimestampHeader = "X-Webhook-Timestamp"
+	webhookSignatureHeader = "X-Webhook-Signature"
+)
+
+const (
+	// webhookInterval is how often new events are queued & the queues with due deliveries drained
+	webhookInterval = 2 * time.Second
+	// webhookBatch is the most events queued, webhooks drained & deliveries read at once
+	webhookBatch = 100
+	// webhookTimeout bounds a single delivery attempt
+	webhookTimeout = 10 * time.Second
+	// webhookBaseDelay doubles after every failed attempt up to webhookMaxDelay
+	webhookBaseDelay = 10 * time.Second
+	webhookMaxDelay  = time.Hour
+	// webhookMaxAttempts fails a delivery, it's only attempted again by hand
+	webhookMaxAttempts = 10
+	// webhookWorkers bounds the webhooks delivered to at once
+	webhookWorkers = 8
+)
+
+// these match the sizes of the webhook columns
+const (
+	maxWebhookURLLength    = 2048
+	maxWebhookSecretLength = 128
+	maxWebhookFilterLength = 512
+)
+
+// webhookPayload is the body posted to a webhook
+type webhookPayload struct {
+	WebhookID  string    `json:"webhook_id"`
+	DeliveryID uint64    `json:"delivery_id"`
+	Event      *db.Event `json:"event"`
+}
+
+// signWebhook is the hex HMAC-SHA256 of the millisecond timestamp & body joined by a dot
+func signWebhook(secret string, ts int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", ts)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookQueues drains each webhook's due deliveries on a worker of its own, so a slow or
+// failing receiver only holds up its own deliveries. At most webhookWorkers queues are drained
+// at once & a webhook's queue is only ever drained by one worker
+type webhookQueues struct {
+	lock  sync.Mutex
+	busy  map[string]bool
+	slots chan struct{}
+	wg    sync.WaitGroup
+}
+
+func newWebhookQueues(workers int) *webhookQueues {
+	return &webhookQueues{busy: map[string]bool{}, slots: make(chan struct{}, workers)}
+}
+
+// start claims a worker for a webhook's queue, reporting false when the queue is already
+// being drained or every worker is busy & it has to wait for the next check
+func (q *webhookQueues) start(webhookID string) bool {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if q.busy[webhookID] {
+		return false
+	}
+
+	select {
+	case q.slots <- struct{}{}:
+	default:
+		return false
+	}
+
+	q.busy[webhookID] = true
+	q.wg.Add(1)
+	return true
+}
+
+func (q *webhookQueues) done(webhookID string) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	delete(q.busy, webhookID)
+	<-q.slots
+	q.wg.Done()
+}
+
+// dispatchWebhooks queues new events for the webhooks they match & drains the queues of the
+// webhooks with due deliveries every webhookInterval until ctx is done
+func (s *Arc53WatcherServer) dispatchWebhooks(ctx context.Context) {
+	ticker := time.NewTicker(webhookInterval)
+	defer ticker.Stop()
+
+	queues := newWebhookQueues(webhookWorkers)
+	defer queues.wg.Wait()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		err := s.queueWebhookDeliveries()
+		if err != nil {
+			fmt.Printf("[WEBHOOK] [ERROR]: %v\n", err)
+		}
+
+		err = s.drainDueWebhooks(ctx, queues)
+		if err != nil {
+			fmt.Printf("[WEBHOOK] [ERROR]: %v\n", err)
+		}
+	}
+}
+
+// drainDueWebhooks starts a worker for every webhook with due deliveries that isn't being drained
+func (s *Arc53WatcherServer) drainDueWebhooks(ctx context.Context, queues *webhookQueues) error {
+	const op errors.Op = "drainDueWebhooks"
+
+	ids, err := db.GetDueWebhookIDs(s.DB, time.Now(), webhookBatch)
+	if err != nil {
+		return errors.E(op, err)
+	}
+
+	for _, id := range ids {
+		if !queues.start(id) {
+			continue
+		}
+
+		go func(id string) {
+			defer queues.done(id)
+			s.drainWebhook(ctx, id)
+		}(id)
+	}
+
+	return nil
+}
+
+// drainWebhook delivers a webhook's due deliveries in event order until there are none left, a
+// failed attempt stops it so the rest wait for the next check rather than a receiver that's down
+func (s *Arc53WatcherServer) drainWebhook(ctx context.Context, webhookID string) {
+	for ctx.Err() == nil {
+		deliveries, err := db.GetDueWebhookDeliveries(s.DB, webhookID, time.Now(), webhookBatch)
+		if err != nil {
+			fmt.Printf("[WEBHOOK] [ERROR]: %v\n", err)
+			return
+		}
+		if len(*deliveries) == 0 {
+			return
+		}
+
+		for i := range *deliveries {
+			if ctx.Err() != nil {
+				return
+			}
+
+			err = s.DeliverWebhook(ctx, &(*deliveries)[i])
+			if err != nil {
+				fmt.Printf("[WEBHOOK] [ERROR]: %v\n", err)
+				return
+			}
+		}
+	}
+}
+
+// queueWebhookDeliveries queues the events committed after each webhook's last queued event for
+// the webhooks they match & moves them past those events in the same transaction, so an event is
+// only queued once. Events commit in ID order, so one committed later never has a lower ID
+func (s *Arc53WatcherServer) queueWebhookDeliveries() error {
+	const op errors.Op = "queueWebhookDeliveries"
+
+	webhooks, err := db.GetWebhooks(s.DB)
+	if err != nil {
+		return errors.E(op, err)
+	} else if len(*webhooks) == 0 {
+		return nil
+	}
+
+	after := (*webhooks)[0].LastEventID
+	for _, webhook := range *webhooks {
+		if webhook.LastEventID < after {
+			after = webhook.LastEventID
+		}
+	}
+
+	events, err := db.GetEventsAfter(s.DB, after, webhookBatch)
+	if err != nil {
+		return errors.E(op, err)
+	} else if len(*events) == 0 {
+		return nil
+	}
+	last := (*events)[len(*events)-1].ID
+
+	deliveries := []db.WebhookDelivery{}
+	behind := []string{}
+	now := time.Now().UnixMilli()
+	for _, webhook := range *webhooks {
+		if webhook.LastEventID >= last {
+			continue
+		}
+		behind = append(behind, webhook.ID)
+
+		filter, err := parseEventFilter(webhook.ProviderIDs, webhook.Types)
+		if err != nil {
+			fmt.Printf("[WEBHOOK] [ERROR]: webhook %s has an invalid filter: %v\n", webhook.ID, err)
+			continue
+		}
+
+		for i := range *events {
+			if (*events)[i].ID <= webhook.LastEventID || !filter.match(&(*events)[i]) {
+				continue
+			}
+
+			deliveries = append(deliveries, db.WebhookDelivery{
+				WebhookID:   webhook.ID,
+				EventID:     (*events)[i].ID,
+				NextAttempt: now,
+				Created:     now,
+			})
+		}
+	}
+
+	tx, err := s.DB.Beginx()
+	if err != nil {
+		return errors.E(op, err)
+	}
+
+	err = db.QueueWebhookDeliveries(tx, deliveries)
+	if err != nil {
+		tx.Rollback()
+		return errors.E(op, err)
+	}
+
+	for _, id := range behind {
+		err = db.AdvanceWebhook(tx, id, last)
+		if err != nil {
+			tx.Rollback()
+			return errors.E(op, err)
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		tx.Rollback()
+		return errors.E(op, err)
+	}
+
+	return nil
+}
+
+// DeliverWebhook attempts a delivery & records the outcome, a failed delivery is
+// rescheduled with a longer backoff until it runs out of attempts
+func (s *Arc53WatcherServer) DeliverWebhook(ctx context.Context, delivery *db.WebhookDelivery) error {
+	const op errors.Op = "DeliverWebhook"
+
+	webhook, err := db.GetWebhook(s.DB, delivery.WebhookID)
+	if err != nil {
+		return errors.E(op, err)
+	}
+
+	event, err := db.GetEvent(s.DB, delivery.EventID)
+	if err != nil {
+		return errors.E(op, err)
+	}
+
+	code, failure := s.postWebhook(ctx, webhook, delivery, event)
+
+	status := db.WebhookDeliveryDelivered
+	next := time.Now()
+	if failure != nil {
+		status = db.WebhookDeliveryPending
+		if delivery.Attempts+1 >= webhookMaxAttempts {
+			status = db.WebhookDeliveryFailed
+		}
+		next = next.Add(backoff(webhookBaseDelay, webhookMaxDelay, delivery.Attempts+1))
+	}
+
+	err = db.RecordWebhookAttempt(s.DB, delivery.ID, status, code, failure, next)
+	if err != nil {
+		return errors.E(op, err)
+	}
+
+	if failure != nil {
+		return errors.E(op, errors.Network, failure)
+	}
+
+	return nil
+}
+
+// postWebhook posts a signed event to a webhook, any 2xx response is a successful delivery
+func (s *Arc53WatcherServer) postWebhook(ctx context.Context, webhook *db.Webhook, delivery *db.WebhookDelivery, event *db.Event) (int, error) {
+	body, err := json.Marshal(webhookPayload{
+		WebhookID:  webhook.ID,
+		DeliveryID: delivery.ID,
+		Event:      event,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, webhookTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	ts := time.Now().UnixMilli()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(webhookIDHeader, webhook.ID)
+	req.Header.Set(webhookDeliveryHeader, strconv.FormatUint(delivery.ID, 10))
+	req.Header.Set(webhookEventHeader, event.Type)
+	req.Header.Set(webhookTimestampHeader, strconv.FormatInt(ts, 10))
+	req.Header.Set(webhookSignatureHeader, "sha256="+signWebhook(webhook.Secret, ts, body))
+
+	resp, err := s.webhookClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,292 @@
+package server
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/kylebeee/arc53-watcher-go/db"
+)
+
+const (
+	webhookQuery         = "select id,url,secret,provider_ids,types,last_event_id,created from webhook where id = ?"
+	webhooksQuery        = "select id,url,secret,provider_ids,types,last_event_id,created from webhook order by created asc"
+	eventQuery           = "select id,provider_id,type,subject,round,created from event where id = ?"
+	webhookDeliveryQuery = "select id,webhook_id,event_id,status,attempts,response_code,error,next_attempt,last_attempt,created from webhook_delivery where id = ?"
+	dueDeliveriesQuery   = "select id,webhook_id,event_id,status,attempts,response_code,error,next_attempt,last_attempt,created from webhook_delivery where webhook_id = ? and status = ? and next_attempt <= ? order by event_id asc limit ?"
+	recordAttemptQuery   = "update webhook_delivery set status = ?, attempts = attempts + 1, response_code = ?, error = ?, next_attempt = ?, last_attempt = ? where id = ?"
+)
+
+// msWithin matches a unix millisecond timestamp between from & to
+type msWithin struct {
+	from, to time.Time
+}
+
+func (m msWithin) Match(v driver.Value) bool {
+	ms, ok := v.(int64)
+	return ok && ms >= m.from.UnixMilli() && ms <= m.to.UnixMilli()
+}
+
+// receiver is a webhook receiver that checks every delivery's signature & answers with status
+type receiver struct {
+	*httptest.Server
+	status   int
+	requests atomic.Int64
+	// bad is why the last delivery wasn't what a receiver expects, empty when it was
+	bad atomic.Value
+}
+
+func newReceiver(t *testing.T, secret string, status int) *receiver {
+	t.Helper()
+
+	r := &receiver{status: status}
+	r.bad.Store("")
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		r.requests.Add(1)
+
+		body, _ := io.ReadAll(req.Body)
+		ts, err := strconv.ParseInt(req.Header.Get(webhookTimestampHeader), 10, 64)
+		switch {
+		case err != nil:
+			r.bad.Store("timestamp isn't a number")
+		case time.Since(time.UnixMilli(ts)).Abs() > time.Minute:
+			r.bad.Store(fmt.Sprintf("timestamp %d isn't unix milliseconds", ts))
+		case req.Header.Get(webhookSignatureHeader) != "sha256="+signWebhook(secret, ts, body):
+			r.bad.Store("signature doesn't match the body")
+		case req.Header.Get(webhookIDHeader) != "whk_1" || req.Header.Get(webhookDeliveryHeader) != "7" || req.Header.Get(webhookEventHeader) != db.EventCommunityUpdated:
+			r.bad.Store(fmt.Sprintf("headers %v", req.Header))
+		}
+
+		var payload webhookPayload
+		if json.Unmarshal(body, &payload) != nil || payload.WebhookID != "whk_1" || payload.DeliveryID != 7 || payload.Event == nil || payload.Event.ID != 42 {
+			r.bad.Store(fmt.Sprintf("payload %s", body))
+		}
+
+		w.WriteHeader(r.status)
+	}))
+	t.Cleanup(r.Close)
+
+	return r
+}
+
+func expectDelivery(mock sqlmock.Sqlmock, url string) {
+	mock.ExpectQuery(regexp.QuoteMeta(webhookQuery)).WithArgs("whk_1").
+		WillReturnRows(sqlmock.NewRows(db.WebhookTableKeys()).AddRow("whk_1", url, "secret", "", "", 41, 1000))
+	mock.ExpectQuery(regexp.QuoteMeta(eventQuery)).WithArgs(42).
+		WillReturnRows(sqlmock.NewRows(db.EventTableKeys()).AddRow(42, 1, db.EventCommunityUpdated, "", 10, 1000))
+}
+
+func deliveryRow(rows *sqlmock.Rows, id, eventID uint64, status string, attempts uint64) *sqlmock.Rows {
+	return rows.AddRow(id, "whk_1", eventID, status, attempts, 0, "", 1000, 0, 1000)
+}
+
+func TestDeliverWebhook(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		attempts uint64
+		want     string
+		// delay is the backoff the next attempt is due after
+		delay time.Duration
+	}{
+		{name: "delivered", status: 200, want: db.WebhookDeliveryDelivered},
+		{name: "retried after the first failure", status: 500, want: db.WebhookDeliveryPending, delay: webhookBaseDelay},
+		{name: "backoff doubles", status: 503, attempts: 3, want: db.WebhookDeliveryPending, delay: 8 * webhookBaseDelay},
+		{name: "failed after the last attempt", status: 500, attempts: webhookMaxAttempts - 1, want: db.WebhookDeliveryFailed, delay: webhookMaxDelay},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, mock := mockServer(t)
+			s.webhookClient = &http.Client{}
+			r := newReceiver(t, "secret", tt.status)
+
+			start := time.Now()
+			expectDelivery(mock, r.URL)
+			mock.ExpectExec(regexp.QuoteMeta(recordAttemptQuery)).
+				WithArgs(tt.want, tt.status, sqlmock.AnyArg(), msWithin{start.Add(tt.delay), time.Now().Add(time.Minute + tt.delay)}, msWithin{start, start.Add(time.Minute)}, 7).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+
+			err := s.DeliverWebhook(context.Background(), &db.WebhookDelivery{ID: 7, WebhookID: "whk_1", EventID: 42, Attempts: tt.attempts})
+			if (err != nil) != (tt.status != 200) {
+				t.Fatalf("DeliverWebhook error %v with the receiver answering %d", err, tt.status)
+			}
+
+			if bad := r.bad.Load().(string); bad != "" {
+				t.Fatalf("receiver got a bad delivery: %s", bad)
+			}
+			if r.requests.Load() != 1 {
+				t.Fatalf("receiver got %d requests, want 1", r.requests.Load())
+			}
+			err = mock.ExpectationsWereMet()
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestDrainWebhookStopsAtAFailure(t *testing.T) {
+	s, mock := mockServer(t)
+	s.webhookClient = &http.Client{}
+	r := newReceiver(t, "secret", 500)
+
+	// the first delivery fails, the second waits for the next check instead of the receiver that's down
+	mock.ExpectQuery(regexp.QuoteMeta(dueDeliveriesQuery)).WithArgs("whk_1", db.WebhookDeliveryPending, sqlmock.AnyArg(), webhookBatch).
+		WillReturnRows(deliveryRow(deliveryRow(sqlmock.NewRows(db.WebhookDeliveryTableKeys()), 7, 42, db.WebhookDeliveryPending, 0), 8, 43, db.WebhookDeliveryPending, 0))
+	expectDelivery(mock, r.URL)
+	mock.ExpectExec(regexp.QuoteMeta(recordAttemptQuery)).
+		WithArgs(db.WebhookDeliveryPending, 500, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), 7).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	s.drainWebhook(context.Background(), "whk_1")
+
+	if r.requests.Load() != 1 {
+		t.Fatalf("receiver got %d requests, want 1", r.requests.Load())
+	}
+	err := mock.ExpectationsWereMet()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWebhookQueues(t *testing.T) {
+	q := newWebhookQueues(2)
+
+	if !q.start("whk_1") {
+		t.Fatal("whk_1's queue didn't start with workers free")
+	}
+	if q.start("whk_1") {
+		t.Fatal("whk_1's queue started on a second worker")
+	}
+	if !q.start("whk_2") {
+		t.Fatal("whk_2's queue didn't start with a worker free")
+	}
+	if q.start("whk_3") {
+		t.Fatal("whk_3's queue started with every worker busy")
+	}
+
+	q.done("whk_1")
+	if !q.start("whk_3") {
+		t.Fatal("whk_3's queue didn't start once a worker was done")
+	}
+
+	q.done("whk_2")
+	q.done("whk_3")
+	q.wg.Wait()
+}
+
+func TestQueueWebhookDeliveries(t *testing.T) {
+	s, mock := mockServer(t)
+
+	// whk_1 wants every event after 5, whk_2 provider 2's after 7 & whk_3 is already past them
+	mock.ExpectQuery(regexp.QuoteMeta(webhooksQuery)).WillReturnRows(sqlmock.NewRows(db.WebhookTableKeys()).
+		AddRow("whk_1", "https://example.com/1", "secret", "", "", 5, 1000).
+		AddRow("whk_2", "https://example.com/2", "secret", "2", "", 7, 2000).
+		AddRow("whk_3", "https://example.com/3", "secret", "", "", 9, 3000))
+	mock.ExpectQuery(regexp.QuoteMeta(eventsAfterQuery)).WithArgs(5, webhookBatch).WillReturnRows(sqlmock.NewRows(db.EventTableKeys()).
+		AddRow(6, 2, db.EventCommunityUpdated, "", 10, 1000).
+		AddRow(8, 1, db.EventCommunityUpdated, "", 11, 1000).
+		AddRow(9, 2, db.EventCollectionCreated, "art", 11, 1000))
+
+	now := msWithin{time.Now(), time.Now().Add(time.Minute)}
+	mock.ExpectBegin()
+	mock.ExpectPrepare(regexp.QuoteMeta("insert ignore into webhook_delivery")).ExpectExec().
+		WithArgs(
+			"whk_1", 6, db.WebhookDeliveryPending, now, now,
+			"whk_1", 8, db.WebhookDeliveryPending, now, now,
+			"whk_1", 9, db.WebhookDeliveryPending, now, now,
+			"whk_2", 9, db.WebhookDeliveryPending, now, now,
+		).
+		WillReturnResult(sqlmock.NewResult(4, 4))
+	for _, id := range []string{"whk_1", "whk_2"} {
+		mock.ExpectPrepare(regexp.QuoteMeta("update webhook set last_event_id = ? where id = ? and last_event_id < ?")).ExpectExec().
+			WithArgs(9, id, 9).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	mock.ExpectCommit()
+
+	err := s.queueWebhookDeliveries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWebhookDeliveryLog(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	s, mock := mockServer(t)
+	s.webhookClient = &http.Client{}
+	rcv := newReceiver(t, "secret", 200)
+
+	r := gin.New()
+	r.GET("/webhooks/:id/deliveries", s.handleListWebhookDeliveries())
+	r.POST("/webhooks/:id/deliveries/:deliveryID/redeliver", s.handleRedeliverWebhook())
+
+	// a failed delivery is redelivered by hand & the log shows the attempt
+	failed := deliveryRow(sqlmock.NewRows(db.WebhookDeliveryTableKeys()), 7, 42, db.WebhookDeliveryFailed, webhookMaxAttempts)
+	mock.ExpectQuery(regexp.QuoteMeta(webhookDeliveryQuery)).WithArgs(7).WillReturnRows(failed)
+	expectDelivery(mock, rcv.URL)
+	mock.ExpectExec(regexp.QuoteMeta(recordAttemptQuery)).
+		WithArgs(db.WebhookDeliveryDelivered, 200, "", sqlmock.AnyArg(), sqlmock.AnyArg(), 7).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	attempted := time.Now().UnixMilli()
+	mock.ExpectQuery(regexp.QuoteMeta(webhookDeliveryQuery)).WithArgs(7).WillReturnRows(sqlmock.NewRows(db.WebhookDeliveryTableKeys()).
+		AddRow(7, "whk_1", 42, db.WebhookDeliveryDelivered, webhookMaxAttempts+1, 200, "", attempted, attempted, 1000))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("POST", "/webhooks/whk_1/deliveries/7/redeliver", nil))
+	if w.Code != 200 {
+		t.Fatalf("redeliver status %d: %s", w.Code, w.Body)
+	}
+	if rcv.requests.Load() != 1 {
+		t.Fatalf("receiver got %d requests, want 1", rcv.requests.Load())
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(webhookQuery)).WithArgs("whk_1").
+		WillReturnRows(sqlmock.NewRows(db.WebhookTableKeys()).AddRow("whk_1", rcv.URL, "secret", "", "", 41, 1000))
+	mock.ExpectQuery(regexp.QuoteMeta("from webhook_delivery where webhook_id = ? order by id desc limit ?, ?")).WithArgs("whk_1", 0, 50).
+		WillReturnRows(sqlmock.NewRows(db.WebhookDeliveryTableKeys()).
+			AddRow(7, "whk_1", 42, db.WebhookDeliveryDelivered, webhookMaxAttempts+1, 200, "", attempted, attempted, 1000))
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/webhooks/whk_1/deliveries?page=1&limit=50", nil))
+	if w.Code != 200 {
+		t.Fatalf("deliveries status %d: %s", w.Code, w.Body)
+	}
+
+	var log struct {
+		Deliveries []db.WebhookDelivery `json:"deliveries"`
+	}
+	err := json.Unmarshal(w.Body.Bytes(), &log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(log.Deliveries) != 1 || log.Deliveries[0].Status != db.WebhookDeliveryDelivered || log.Deliveries[0].ResponseCode != 200 || log.Deliveries[0].LastAttempt != attempted {
+		t.Fatalf("delivery log %+v, want the redelivery logged", log.Deliveries)
+	}
+	if !strings.Contains(w.Body.String(), fmt.Sprintf(`"last_attempt":%d`, attempted)) {
+		t.Fatalf("delivery log %s doesn't have the attempt in milliseconds", w.Body)
+	}
+
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
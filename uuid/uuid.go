@@ -20,6 +20,8 @@ type Prefix int
 const (
 	Collection Prefix = iota + 1
 	Property
+	SyncJob
+	Webhook
 )
 
 func (p Prefix) String() string {
@@ -29,11 +31,15 @@ func (p Prefix) String() string {
 var prefixToString = map[Prefix]string{
 	Collection: "col",
 	Property:   "prp",
+	SyncJob:    "job",
+	Webhook:    "whk",
 }
 
 var prefixToID = map[string]Prefix{
 	"col": Collection,
 	"prp": Property,
+	"job": SyncJob,
+	"whk": Webhook,
 }
 
 // UnmarshalYAML checks to see if its type is valid